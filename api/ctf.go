@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"codeheist/game"
+
+	"github.com/gin-gonic/gin"
+)
+
+type registerTeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RequireInstructor restricts event administration to organizers
+func (h *Handler) RequireInstructor(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !h.isInstructor(token) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "instructor token required"})
+		return
+	}
+	c.Next()
+}
+
+// GetEvent handles GET /api/event
+func (h *Handler) GetEvent(c *gin.Context) {
+	info, exists := h.engine.CTFInfo()
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": game.ErrNoCTFEvent.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// ConfigureEvent handles PUT /api/event
+func (h *Handler) ConfigureEvent(c *gin.Context) {
+	var config game.CTFConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.engine.ConfigureCTF(config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	info, _ := h.engine.CTFInfo()
+	c.JSON(http.StatusOK, info)
+}
+
+// StopEvent handles DELETE /api/event
+func (h *Handler) StopEvent(c *gin.Context) {
+	h.engine.StopCTF()
+	c.Status(http.StatusNoContent)
+}
+
+// RegisterTeam handles POST /api/event/teams. Teams register themselves
+// while registration is open; organizers can always register them.
+func (h *Handler) RegisterTeam(c *gin.Context) {
+	var req registerTeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	err := h.engine.RegisterCTFTeam(req.Name, h.isInstructor(token))
+	switch {
+	case errors.Is(err, game.ErrNoCTFEvent):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, game.ErrRegistrationClosed):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusCreated, gin.H{"team": req.Name})
+	}
+}
+
+// GetScoreboard handles GET /api/event/scoreboard
+func (h *Handler) GetScoreboard(c *gin.Context) {
+	if _, exists := h.engine.CTFInfo(); !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": game.ErrNoCTFEvent.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.engine.Scoreboard())
+}
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"codeheist/game"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler exposes the game over plain HTTP/JSON for integrations
+// (LMS plugins, chat bots, graders) that can't speak the WebSocket protocol
+type Handler struct {
+	engine          *game.GameEngine
+	InstructorToken string // Accepted by RequireViewer for reviewing any session
+}
+
+type createSessionRequest struct {
+	// Optional codeheistN login to start from a level unlocked elsewhere
+	User     string `json:"user"`
+	Password string `json:"password"`
+	// Optional player keeping achievements and speedrun bests; the
+	// token is needed once the name has been claimed
+	Player      string `json:"player"`
+	PlayerToken string `json:"player_token"`
+}
+
+type createSessionResponse struct {
+	Token       string `json:"token"`
+	PlayerToken string `json:"player_token,omitempty"`
+	game.SessionInfo
+}
+
+type commandRequest struct {
+	Command string `json:"command" binding:"required"`
+}
+
+type commandResponse struct {
+	*game.CommandResponse
+	Session game.SessionInfo `json:"session"`
+}
+
+func NewHandler(engine *game.GameEngine) *Handler {
+	return &Handler{
+		engine: engine,
+	}
+}
+
+// CreateSession handles POST /api/sessions
+func (h *Handler) CreateSession(c *gin.Context) {
+	var req createSessionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	level := 0
+	if req.User != "" {
+		var ok bool
+		level, ok = h.engine.AuthenticateLevel(req.User, req.Password)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Permission denied"})
+			return
+		}
+	}
+
+	session := h.engine.CreateSessionAtLevel(c.ClientIP(), level)
+	playerToken := ""
+	if req.Player != "" {
+		var err error
+		playerToken, err = h.engine.SignIn(session.ID, req.Player, req.PlayerToken)
+		if err != nil {
+			h.engine.EndSession(session.ID)
+			status := http.StatusBadRequest
+			if errors.Is(err, game.ErrPlayerTaken) {
+				status = http.StatusUnauthorized
+			}
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	info, _ := h.engine.SessionInfo(session.ID)
+
+	c.JSON(http.StatusCreated, createSessionResponse{
+		Token:       session.Token,
+		PlayerToken: playerToken,
+		SessionInfo: info,
+	})
+}
+
+// RequireSession checks the bearer token against the :id session
+func (h *Handler) RequireSession(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if _, ok := h.engine.AuthenticateSession(c.Param("id"), token); !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid session or token"})
+		return
+	}
+	c.Next()
+}
+
+// RequireViewer allows the session's own token or an instructor token,
+// for read-only endpoints used when reviewing a player's work
+func (h *Handler) RequireViewer(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if h.isInstructor(token) {
+		c.Next()
+		return
+	}
+	h.RequireSession(c)
+}
+
+// GetSession handles GET /api/sessions/:id
+func (h *Handler) GetSession(c *gin.Context) {
+	info, exists := h.engine.SessionInfo(c.Param("id"))
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// ExecuteCommand handles POST /api/sessions/:id/commands
+func (h *Handler) ExecuteCommand(c *gin.Context) {
+	var req commandRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessionID := c.Param("id")
+	// Team sessions need a member to credit and teammates to tell, which
+	// only the WebSocket has
+	if info, _ := h.engine.SessionInfo(sessionID); info.Team != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "team sessions take commands over the WebSocket"})
+		return
+	}
+
+	response := h.engine.ExecuteBatchCommand(sessionID, req.Command)
+	info, _ := h.engine.SessionInfo(sessionID)
+
+	c.JSON(http.StatusOK, commandResponse{
+		CommandResponse: response,
+		Session:         info,
+	})
+}
+
+// GetRecording handles GET /api/sessions/:id/recording, exporting the
+// session's terminal history as an asciinema v2 .cast file
+func (h *Handler) GetRecording(c *gin.Context) {
+	sessionID := c.Param("id")
+	recording, exists := h.engine.GetRecording(sessionID)
+	info, _ := h.engine.SessionInfo(sessionID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	cast := recording.Asciicast("CodeHeist - " + info.User)
+	c.Header("Content-Disposition", `attachment; filename="codeheist-`+sessionID+`.cast"`)
+	c.Data(http.StatusOK, "application/x-asciicast", []byte(cast))
+}
+
+func (h *Handler) isInstructor(token string) bool {
+	return h.InstructorToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.InstructorToken)) == 1
+}
@@ -0,0 +1,176 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Achievement is a badge unlocked by playing with style. Rules are
+// evaluated on engine events with the session locked.
+type Achievement struct {
+	ID          string
+	Icon        string
+	Title       string
+	Description string
+	unlocked    func(s *Session, event Event, totalLevels int) bool
+}
+
+// UnlockedAchievement is an achievement a player has earned
+type UnlockedAchievement struct {
+	ID          string    `json:"id"`
+	Icon        string    `json:"icon"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	UnlockedAt  time.Time `json:"unlocked_at"`
+}
+
+// playStats are the per-session counters achievement rules look at
+type playStats struct {
+	levelCommands  int  // Commands run on the current level
+	levelHints     int  // Hints shown on the current level
+	lastCommands   int  // Commands it took to clear the previous level
+	hintFreeStreak int  // Consecutive levels cleared without a hint
+	skippedLevels  bool // Logged in past levels instead of playing them
+}
+
+// levelCleared rolls the current level's counters over
+func (p *playStats) levelCleared() {
+	p.lastCommands = p.levelCommands
+	if p.levelHints == 0 {
+		p.hintFreeStreak++
+	} else {
+		p.hintFreeStreak = 0
+	}
+	p.levelCommands = 0
+	p.levelHints = 0
+}
+
+var achievements = []*Achievement{
+	{
+		ID:          "first_steps",
+		Icon:        "👣",
+		Title:       "First Steps",
+		Description: "Clear your first level",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventLevelCompleted
+		},
+	},
+	{
+		ID:          "one_shot",
+		Icon:        "🎯",
+		Title:       "One Shot",
+		Description: "Clear a level with a single command",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventLevelCompleted && s.stats.lastCommands == 1
+		},
+	},
+	{
+		ID:          "self_reliant",
+		Icon:        "🧠",
+		Title:       "Self-Reliant",
+		Description: "Clear 5 levels in a row without a hint",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventLevelCompleted && s.stats.hintFreeStreak >= 5
+		},
+	},
+	{
+		ID:          "plumber",
+		Icon:        "🔧",
+		Title:       "Plumber",
+		Description: "Use a pipeline",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventCommandRun && len(splitPipeline(event.Message)) > 1
+		},
+	},
+	{
+		ID:          "master",
+		Icon:        "🏆",
+		Title:       "CodeHeist Master",
+		Description: "Clear every level from the start",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventLevelCompleted && event.Level == totalLevels-1 && !s.stats.skippedLevels
+		},
+	},
+	{
+		ID:          "speedrunner",
+		Icon:        "⚡",
+		Title:       "Speedrunner",
+		Description: "Clear every level from the start in under 30 minutes",
+		unlocked: func(s *Session, event Event, totalLevels int) bool {
+			return event.Type == EventLevelCompleted && event.Level == totalLevels-1 &&
+				!s.stats.skippedLevels && event.Time.Sub(s.CreatedAt) < 30*time.Minute
+		},
+	},
+}
+
+// checkAchievements evaluates every locked rule against an event
+func (e *GameEngine) checkAchievements(event Event) {
+	switch event.Type {
+	case EventLevelCompleted, EventCommandRun:
+	default:
+		return
+	}
+	session, exists := e.GetSession(event.SessionID)
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	player := session.player
+	player.mu.Lock()
+	defer player.mu.Unlock()
+
+	for _, achievement := range achievements {
+		if _, done := player.Achievements[achievement.ID]; done || !achievement.unlocked(session, event, len(e.Levels)) {
+			continue
+		}
+		if player.Achievements == nil {
+			player.Achievements = make(map[string]time.Time)
+		}
+		player.Achievements[achievement.ID] = event.Time
+		session.newAchievements = append(session.newAchievements, achievement.unlock(event.Time))
+		session.Recording.add("m", "Achievement: "+achievement.Title)
+		log.Printf("🏅 Session %s unlocked %s", session.ID, achievement.Title)
+	}
+}
+
+// takeAchievements returns what the last command unlocked
+func (e *GameEngine) takeAchievements(s *Session) []UnlockedAchievement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	unlocked := s.newAchievements
+	s.newAchievements = nil
+	return unlocked
+}
+
+func (a *Achievement) unlock(at time.Time) UnlockedAchievement {
+	return UnlockedAchievement{
+		ID:          a.ID,
+		Icon:        a.Icon,
+		Title:       a.Title,
+		Description: a.Description,
+		UnlockedAt:  at,
+	}
+}
+
+// listAchievements renders the `achievements` command output
+func listAchievements(s *Session) commandResult {
+	player := s.player
+	player.mu.Lock()
+	defer player.mu.Unlock()
+
+	var list strings.Builder
+	fmt.Fprintf(&list, "🏅 Achievements (%d/%d)\n\n", len(player.Achievements), len(achievements))
+	for _, achievement := range achievements {
+		if at, done := player.Achievements[achievement.ID]; done {
+			fmt.Fprintf(&list, "  %s %-18s %s (%s)\n", achievement.Icon, achievement.Title,
+				achievement.Description, at.Format("15:04:05"))
+		} else {
+			fmt.Fprintf(&list, "  🔒 %-18s %s\n", achievement.Title, achievement.Description)
+		}
+	}
+	return stdoutResult(strings.TrimRight(list.String(), "\n"))
+}
@@ -0,0 +1,789 @@
+package game
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Magic bytes of the compressed formats
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// gunzipBytes decompresses gzip data, including concatenated members
+func gunzipBytes(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// gzipBytes compresses data as gzip would, recording the file's name
+func gzipBytes(data []byte, name string, node *fileNode) []byte {
+	var out bytes.Buffer
+	writer := gzip.NewWriter(&out)
+	writer.Name = name
+	writer.OS = 3 // Unix
+	if node != nil {
+		writer.ModTime = node.modTime
+	}
+	writer.Write(data)
+	writer.Close()
+	return out.Bytes()
+}
+
+// bunzip2Bytes decompresses bzip2 data
+func bunzip2Bytes(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, bzip2Magic) {
+		return nil, errors.New("not a bzip2 file")
+	}
+	return io.ReadAll(bzip2.NewReader(bytes.NewReader(data)))
+}
+
+// replaceFile writes a compressed or decompressed copy of source to
+// target with source's permissions, then removes source unless keep
+func replaceFile(vfs *VirtualFileSystem, source, target string, data []byte, node *fileNode, keep bool) error {
+	if err := vfs.WriteFile(target, data); err != nil {
+		return err
+	}
+	vfs.Chmod(target, node.mode.Perm())
+	if keep {
+		return nil
+	}
+	return vfs.Remove(source)
+}
+
+// gzipCommand implements gzip, gunzip (-d) and zcat (-dc). Files are
+// replaced by their .gz, or without operands stdin goes to stdout.
+func gzipCommand(vfs *VirtualFileSystem, command string, args []string, stdin *string, tty bool) commandResult {
+	flags, operands, usage := parseFlags(command, args, "cdfkq123456789", map[string]rune{
+		"stdout": 'c', "to-stdout": 'c', "decompress": 'd', "uncompress": 'd', "force": 'f', "keep": 'k', "quiet": 'q'})
+	if usage != nil {
+		return *usage
+	}
+	switch command {
+	case "gunzip":
+		flags['d'] = true
+	case "zcat":
+		flags['d'], flags['c'] = true, true
+	}
+
+	var result commandResult
+	var output []byte
+	if len(operands) == 0 || len(operands) == 1 && operands[0] == "-" {
+		switch {
+		case stdin == nil && flags['d'] && !flags['f']:
+			return errorResult("gzip: compressed data not read from a terminal. Use -f to force decompression.\nFor help, type: gzip -h", 1)
+		case tty && !flags['d'] && !flags['f']:
+			return errorResult("gzip: compressed data not written to a terminal. Use -f to force compression.\nFor help, type: gzip -h", 1)
+		case stdin == nil:
+			return result
+		}
+		data := streamBytes(*stdin)
+		if !flags['d'] {
+			result.stdout = string(gzipBytes(data, "", nil))
+			return result
+		}
+		plain, err := gunzipBytes(data)
+		if err != nil {
+			return errorResult("gzip: stdin: not in gzip format", 1)
+		}
+		result.stdout = streamString(plain)
+		return result
+	}
+	if tty && flags['c'] && !flags['d'] && !flags['f'] {
+		return errorResult("gzip: compressed data not written to a terminal. Use -f to force compression.\nFor help, type: gzip -h", 1)
+	}
+
+	for _, name := range operands {
+		_, node, err := vfs.stat(name)
+		switch {
+		case err != nil:
+			result.fail("gzip: "+name+": "+errorText(err), 1)
+			continue
+		case node.isDir():
+			result.fail("gzip: "+name+" is a directory -- ignored", max(result.exitCode, 2))
+			continue
+		}
+
+		var target string
+		switch {
+		case flags['c']:
+		case !flags['d'] && strings.HasSuffix(name, ".gz"):
+			result.fail("gzip: "+name+" already has .gz suffix -- unchanged", result.exitCode)
+			continue
+		case !flags['d']:
+			target = name + ".gz"
+		case strings.HasSuffix(name, ".gz"):
+			target = strings.TrimSuffix(name, ".gz")
+		case strings.HasSuffix(name, ".tgz"):
+			target = strings.TrimSuffix(name, ".tgz") + ".tar"
+		default:
+			result.fail("gzip: "+name+": unknown suffix -- ignored", max(result.exitCode, 2))
+			continue
+		}
+
+		data, err := vfs.ReadFile(name)
+		if err != nil {
+			result.fail("gzip: "+name+": "+errorText(err), 1)
+			continue
+		}
+		if flags['d'] {
+			plain, err := gunzipBytes(data)
+			if err != nil {
+				message := "invalid compressed data--format violated"
+				if errors.Is(err, gzip.ErrHeader) || len(data) < 2 {
+					message = "not in gzip format"
+				}
+				result.fail("gzip: "+name+": "+message, 1)
+				continue
+			}
+			data = plain
+		} else {
+			data = gzipBytes(data, path.Base(name), node)
+		}
+
+		if flags['c'] {
+			output = append(output, data...)
+			continue
+		}
+		if _, _, err := vfs.lstat(target); err == nil {
+			if !flags['f'] {
+				result.fail("gzip: "+target+" already exists;\tnot overwritten", max(result.exitCode, 2))
+				continue
+			}
+			vfs.Remove(target)
+		}
+		if err := replaceFile(vfs, name, target, data, node, flags['k']); err != nil {
+			result.fail("gzip: "+target+": "+errorText(err), 1)
+		}
+	}
+	result.stdout = streamString(output)
+	return result
+}
+
+// bzip2Command implements bzip2, bunzip2 (-d) and bzcat (-dc). Files are
+// replaced by their .bz2, or without operands stdin goes to stdout.
+func bzip2Command(vfs *VirtualFileSystem, command string, args []string, stdin *string, tty bool) commandResult {
+	flags, operands, usage := parseFlags(command, args, "cdfkqz123456789", map[string]rune{
+		"stdout": 'c', "decompress": 'd', "compress": 'z', "force": 'f', "keep": 'k', "quiet": 'q'})
+	if usage != nil {
+		return *usage
+	}
+	switch command {
+	case "bunzip2":
+		flags['d'] = true
+	case "bzcat":
+		flags['d'], flags['c'] = true, true
+	}
+	compress := !flags['d'] || flags['z']
+	level := 9
+	for digit := '1'; digit <= '9'; digit++ {
+		if flags[digit] {
+			level = int(digit - '0')
+		}
+	}
+	terminalError := errorResult(command+": I won't write compressed data to a terminal.\n"+command+": For help, type: `"+command+" --help'.", 1)
+
+	var result commandResult
+	if len(operands) == 0 || len(operands) == 1 && operands[0] == "-" {
+		switch {
+		case compress && tty && !flags['f']:
+			return terminalError
+		case stdin == nil && !compress:
+			return errorResult(command+": I won't read compressed data from a terminal.\n"+command+": For help, type: `"+command+" --help'.", 1)
+		case stdin == nil:
+			return result
+		case compress:
+			result.stdout = string(bzip2Bytes(streamBytes(*stdin), level))
+			return result
+		}
+		plain, err := bunzip2Bytes([]byte(*stdin))
+		if err != nil {
+			return errorResult(command+": (stdin) is not a bzip2 file.", 2)
+		}
+		result.stdout = streamString(plain)
+		return result
+	}
+	if compress && flags['c'] && tty && !flags['f'] {
+		return terminalError
+	}
+
+	suffixes := [][2]string{{".tbz2", ".tar"}, {".tbz", ".tar"}, {".bz2", ""}, {".bz", ""}}
+	var output []byte
+	for _, name := range operands {
+		_, node, err := vfs.stat(name)
+		if err == nil && node.isDir() {
+			result.fail(command+": Input file "+name+" is a directory.", max(result.exitCode, 1))
+			continue
+		}
+		var data []byte
+		if err == nil {
+			data, err = vfs.ReadFile(name)
+		}
+		if err != nil {
+			result.fail(command+": Can't open input file "+name+": "+errorText(err)+".", max(result.exitCode, 1))
+			continue
+		}
+
+		var target string
+		if compress {
+			compressed := false
+			for _, suffix := range suffixes {
+				if !flags['c'] && strings.HasSuffix(name, suffix[0]) {
+					result.fail(command+": Input file "+name+" already has "+suffix[0]+" suffix.", max(result.exitCode, 1))
+					compressed = true
+					break
+				}
+			}
+			if compressed {
+				continue
+			}
+			data, target = bzip2Bytes(data, level), name+".bz2"
+		} else {
+			target = name + ".out"
+			for _, suffix := range suffixes {
+				if strings.HasSuffix(name, suffix[0]) {
+					target = strings.TrimSuffix(name, suffix[0]) + suffix[1]
+					break
+				}
+			}
+			if target == name+".out" && !flags['c'] {
+				result.fail(command+": Can't guess original name for "+name+" -- using "+target, result.exitCode)
+			}
+			plain, err := bunzip2Bytes(data)
+			if err != nil {
+				result.fail(command+": "+name+" is not a bzip2 file.", 2)
+				continue
+			}
+			data = plain
+		}
+
+		if flags['c'] {
+			output = append(output, data...)
+			continue
+		}
+		if _, _, err := vfs.lstat(target); err == nil {
+			if !flags['f'] {
+				result.fail(command+": Output file "+target+" already exists.", max(result.exitCode, 1))
+				continue
+			}
+			vfs.Remove(target)
+		}
+		if err := replaceFile(vfs, name, target, data, node, flags['k']); err != nil {
+			result.fail(command+": Can't create output file "+target+": "+errorText(err)+".", max(result.exitCode, 1))
+		}
+	}
+	result.stdout = streamString(output)
+	return result
+}
+
+// tarCommand implements tar -c, -x and -t over the VFS, with -f for the
+// archive ("-" or no -f for stdin and stdout), -z for gzip or -j for
+// bzip2, and -C for the directory to work in. Reading detects gzip and
+// bzip2 by itself.
+func tarCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	// The first word's dash is optional: tar xzf archive.tgz
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append([]string{"-" + args[0]}, args[1:]...)
+	}
+	flags, values, operands, usage := parseOptions("tar", args, "cxtvzjp", "fC", map[string]rune{
+		"create": 'c', "extract": 'x', "get": 'x', "list": 't', "verbose": 'v', "gzip": 'z', "gunzip": 'z',
+		"bzip2": 'j', "preserve-permissions": 'p', "file": 'f', "directory": 'C'})
+	if usage != nil {
+		usage.exitCode = 2
+		return *usage
+	}
+
+	fatal := func(message string) commandResult {
+		return errorResult("tar: "+message+"\ntar: Error is not recoverable: exiting now", 2)
+	}
+	archive := values['f']
+	if archive == "" {
+		archive = "-"
+	}
+	dir := "."
+	if value, set := values['C']; set {
+		if _, node, err := vfs.stat(value); err != nil || !node.isDir() {
+			if err == nil {
+				err = errNotDir
+			}
+			return fatal(value + ": Cannot open: " + errorText(err))
+		}
+		dir = value
+	}
+
+	var result commandResult
+	switch {
+	case flags['c'] && !flags['x'] && !flags['t']:
+		if len(operands) == 0 {
+			return errorResult("tar: Cowardly refusing to create an empty archive\nTry 'tar --help' or 'tar --usage' for more information.", 2)
+		}
+		var buffer bytes.Buffer
+		writer := tar.NewWriter(&buffer)
+		var names []string
+		stripped := false
+		for _, operand := range operands {
+			name := operand
+			if strings.HasPrefix(name, "/") && !stripped {
+				result.fail("tar: Removing leading `/' from member names", result.exitCode)
+				stripped = true
+			}
+			tarAdd(vfs, writer, path.Join(dir, operand), strings.TrimLeft(name, "/"), &names, &result)
+		}
+		writer.Close()
+
+		data := buffer.Bytes()
+		switch {
+		case flags['z']:
+			data = gzipBytes(data, "", nil)
+		case flags['j']:
+			data = bzip2Bytes(data, 9)
+		}
+		var listing string
+		if flags['v'] {
+			listing = strings.Join(names, "\n")
+		}
+		if archive == "-" {
+			result.stdout = string(data)
+			if listing != "" {
+				result.fail(listing, result.exitCode)
+			}
+		} else {
+			if err := vfs.WriteFile(archive, data); err != nil {
+				return fatal(archive + ": Cannot open: " + errorText(err))
+			}
+			result.stdout = listing
+		}
+
+	case (flags['x'] || flags['t']) && !(flags['x'] && flags['t']) && !flags['c']:
+		var data []byte
+		if archive == "-" {
+			if stdin != nil {
+				data = []byte(*stdin)
+			}
+		} else {
+			var err error
+			if data, err = vfs.ReadFile(archive); err != nil {
+				return fatal(archive + ": Cannot open: " + errorText(err))
+			}
+		}
+		var err error
+		switch {
+		case bytes.HasPrefix(data, gzipMagic):
+			data, err = gunzipBytes(data)
+		case bytes.HasPrefix(data, bzip2Magic):
+			data, err = bunzip2Bytes(data)
+		}
+		if err != nil {
+			return errorResult("tar: Child returned status 1\ntar: Error is not recoverable: exiting now", 2)
+		}
+
+		var lines []string
+		found := make(map[string]bool)
+		ownerWidth := 19
+		reader := tar.NewReader(bytes.NewReader(data))
+		for {
+			header, err := reader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				if len(found) == 0 && len(lines) == 0 {
+					result.fail("tar: This does not look like a tar archive", 2)
+				} else {
+					result.fail("tar: Unexpected EOF in archive", 2)
+				}
+				break
+			}
+			name := strings.TrimLeft(header.Name, "/")
+			if !tarSelected(name, operands, found) {
+				continue
+			}
+			if flags['v'] && flags['t'] {
+				lines = append(lines, tarListing(header, &ownerWidth))
+			} else if flags['v'] || flags['t'] {
+				lines = append(lines, header.Name)
+			}
+			if flags['x'] {
+				content, _ := io.ReadAll(reader)
+				tarExtract(vfs, header, path.Join(dir, name), content, flags['p'], &result)
+			}
+		}
+		for _, operand := range operands {
+			if !found[strings.TrimSuffix(operand, "/")] {
+				result.fail("tar: "+operand+": Not found in archive", 2)
+			}
+		}
+		result.stdout = strings.Join(lines, "\n")
+
+	default:
+		return errorResult("tar: You must specify one of the '-Acdtrux', '--delete' or '--test-label' options\nTry 'tar --help' or 'tar --usage' for more information.", 2)
+	}
+
+	if result.exitCode != 0 {
+		result.fail("tar: Exiting with failure status due to previous errors", 2)
+	}
+	return result
+}
+
+// tarAdd archives a path as name, and a directory's contents after it
+func tarAdd(vfs *VirtualFileSystem, writer *tar.Writer, source, name string, names *[]string, result *commandResult) {
+	_, node, err := vfs.lstat(source)
+	if err != nil {
+		result.fail("tar: "+name+": Cannot stat: "+errorText(err), 2)
+		return
+	}
+	header := &tar.Header{
+		Name:    name,
+		Mode:    int64(node.mode.Perm()),
+		ModTime: node.modTime,
+		Uname:   node.owner,
+		Gname:   node.group,
+		Format:  tar.FormatGNU,
+	}
+	var content []byte
+	switch {
+	case node.isDir():
+		header.Typeflag = tar.TypeDir
+		header.Name = strings.TrimSuffix(name, "/") + "/"
+	case node.isSymlink():
+		header.Typeflag = tar.TypeSymlink
+		header.Linkname = node.target
+	default:
+		if content, err = vfs.ReadFile(source); err != nil {
+			result.fail("tar: "+name+": Cannot open: "+errorText(err), 2)
+			return
+		}
+		header.Typeflag = tar.TypeReg
+		header.Size = int64(len(content))
+	}
+	writer.WriteHeader(header)
+	writer.Write(content)
+	*names = append(*names, header.Name)
+
+	if node.isDir() {
+		entries, err := vfs.ReadDir(source)
+		if err != nil {
+			result.fail("tar: "+name+": Cannot open: "+errorText(err), 2)
+			return
+		}
+		for _, entry := range entries {
+			tarAdd(vfs, writer, path.Join(source, entry), path.Join(name, entry), names, result)
+		}
+	}
+}
+
+// tarSelected reports whether a member is one of the operands or inside
+// one, recording which operands matched. No operands select everything.
+func tarSelected(name string, operands []string, found map[string]bool) bool {
+	if len(operands) == 0 {
+		return true
+	}
+	name = strings.TrimSuffix(name, "/")
+	for _, operand := range operands {
+		operand = strings.TrimSuffix(operand, "/")
+		if name == operand || strings.HasPrefix(name, operand+"/") {
+			found[operand] = true
+			return true
+		}
+	}
+	return false
+}
+
+// tarListing is a member's line in tar -tv. Like GNU tar, it
+// right-aligns sizes so owner and size fill ownerWidth, which grows to
+// fit the widest seen so far.
+func tarListing(header *tar.Header, ownerWidth *int) string {
+	mode := header.FileInfo().Mode().String()
+	if header.Typeflag == tar.TypeSymlink {
+		mode = "l" + mode[1:]
+	}
+	owner := header.Uname + "/" + header.Gname
+	size := strconv.FormatInt(header.Size, 10)
+	width := len(owner) + len(size)
+	*ownerWidth = max(*ownerWidth, width)
+	line := fmt.Sprintf("%s %s %*s %s %s", mode, owner, *ownerWidth-width+len(size), size,
+		header.ModTime.Format("2006-01-02 15:04"), header.Name)
+	switch header.Typeflag {
+	case tar.TypeSymlink:
+		line += " -> " + header.Linkname
+	case tar.TypeLink:
+		line += " link to " + header.Linkname
+	}
+	return line
+}
+
+// tarExtract creates one member. Permissions lose the group and other
+// write bits, as with a umask of 022, unless -p.
+func tarExtract(vfs *VirtualFileSystem, header *tar.Header, target string, content []byte, preserve bool, result *commandResult) {
+	for _, part := range strings.Split(header.Name, "/") {
+		if part == ".." {
+			result.fail("tar: "+header.Name+": Member name contains '..'", 2)
+			return
+		}
+	}
+	mode := os.FileMode(header.Mode).Perm()
+	if !preserve {
+		mode &^= 0022
+	}
+	if err := mkdirAll(vfs, path.Dir(target)); err != nil {
+		result.fail("tar: "+header.Name+": Cannot open: "+errorText(err), 2)
+		return
+	}
+
+	var err error
+	switch header.Typeflag {
+	case tar.TypeDir:
+		if err = mkdirAll(vfs, target); err == nil {
+			err = vfs.Chmod(target, mode)
+		}
+	case tar.TypeSymlink, tar.TypeLink:
+		if _, node, lerr := vfs.lstat(target); lerr == nil && !node.isDir() {
+			vfs.Remove(target)
+		}
+		if header.Typeflag == tar.TypeSymlink {
+			err = vfs.Symlink(header.Linkname, target)
+		} else {
+			err = vfs.Link(header.Linkname, target)
+		}
+	case tar.TypeReg:
+		if _, node, lerr := vfs.lstat(target); lerr == nil && node.isSymlink() {
+			vfs.Remove(target)
+		}
+		if err = vfs.WriteFile(target, content); err == nil {
+			err = vfs.Chmod(target, mode)
+		}
+	}
+	if err != nil && !errors.Is(err, errNotOwner) {
+		result.fail("tar: "+header.Name+": Cannot open: "+errorText(err), 2)
+	}
+}
+
+// unzipCommand implements unzip: extract an archive (-d into a
+// directory, -o overwriting, -p to stdout) or list it (-l)
+func unzipCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, values, operands, usage := parseOptions("unzip", args, "lopqn", "d", nil)
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return errorResult("UnZip 6.00 of 20 April 2009, by Info-ZIP.\n\nUsage: unzip [-Z] [-opts[modifiers]] file[.zip] [list] [-x xlist] [-d exdir]", 10)
+	}
+
+	name := operands[0]
+	data, err := vfs.ReadFile(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		if data, err = vfs.ReadFile(name + ".zip"); err == nil {
+			name += ".zip"
+		}
+	}
+	if err != nil {
+		return errorResult(fmt.Sprintf("unzip:  cannot find or open %s, %s.zip or %s.ZIP.", operands[0], operands[0], operands[0]), 9)
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return commandResult{
+			stdout: "Archive:  " + name + "\n" +
+				"  End-of-central-directory signature not found.  Either this file is not\n" +
+				"  a zipfile, or it constitutes one disk of a multi-part archive.  In the\n" +
+				"  latter case the central directory and zipfile comment will be found on\n" +
+				"  the last disk(s) of this archive.",
+			stderr:   "unzip:  cannot find zipfile directory in one of " + name + " or\n        " + name + ".zip, and cannot find " + name + ".ZIP, period.",
+			exitCode: 9,
+		}
+	}
+
+	// Members named after the archive select what to extract
+	selected := func(member string) bool {
+		if len(operands) == 1 {
+			return true
+		}
+		for _, pattern := range operands[1:] {
+			if unzipMatch(pattern, member) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result commandResult
+	var lines []string
+	if !flags['p'] && !flags['q'] {
+		lines = append(lines, "Archive:  "+name)
+	}
+	if flags['l'] {
+		lines = append(lines, "  Length      Date    Time    Name", "---------  ---------- -----   ----")
+		var total uint64
+		count := 0
+		for _, member := range archive.File {
+			if !selected(member.Name) {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%9d  %s   %s", member.UncompressedSize64, member.Modified.Format("2006-01-02 15:04"), member.Name))
+			total += member.UncompressedSize64
+			count++
+		}
+		files := "files"
+		if count == 1 {
+			files = "file"
+		}
+		lines = append(lines, "---------                     -------", fmt.Sprintf("%9d                     %d %s", total, count, files))
+		result.stdout = strings.Join(lines, "\n")
+		return result
+	}
+
+	dir := values['d']
+	var output []byte
+	matched := make(map[string]bool)
+	skipped := false
+	for _, member := range archive.File {
+		if !selected(member.Name) {
+			continue
+		}
+		matched[member.Name] = true
+
+		// Like tar, never write outside the extraction directory
+		name := member.Name
+		if !flags['p'] {
+			if strings.HasPrefix(name, "/") {
+				lines = append(lines, "warning:  stripped absolute path spec from "+member.Name)
+				name = strings.TrimLeft(name, "/")
+				result.exitCode = max(result.exitCode, 1)
+			}
+			if slices.Contains(strings.Split(name, "/"), "..") {
+				lines = append(lines, "warning:  skipped "+member.Name+": member name contains \"..\"")
+				result.exitCode = max(result.exitCode, 1)
+				continue
+			}
+		}
+		target := name
+		if dir != "" {
+			target = path.Join(dir, name)
+			if strings.HasSuffix(name, "/") {
+				target += "/"
+			}
+		}
+
+		if member.FileInfo().IsDir() {
+			if flags['p'] {
+				continue
+			}
+			if _, _, err := vfs.stat(target); err != nil {
+				if !flags['q'] {
+					lines = append(lines, "   creating: "+target)
+				}
+				mkdirAll(vfs, target)
+			}
+			continue
+		}
+
+		reader, err := member.Open()
+		var content []byte
+		if err == nil {
+			content, err = io.ReadAll(reader)
+			reader.Close()
+		}
+		if err != nil {
+			lines = append(lines, "  error:  invalid compressed data to inflate "+target)
+			result.exitCode = 2
+			continue
+		}
+		if flags['p'] {
+			output = append(output, content...)
+			continue
+		}
+
+		if _, _, err := vfs.lstat(target); err == nil && !flags['o'] {
+			// Nobody answers the prompt, which counts as [N]one
+			if !flags['n'] && !skipped {
+				result.fail("replace "+target+"? [y]es, [n]o, [A]ll, [N]one, [r]ename:  NULL\n(EOF or read error, treating as \"[N]one\" ...)", 1)
+			}
+			skipped = true
+			continue
+		}
+		action := " extracting"
+		if member.Method == zip.Deflate {
+			action = "  inflating"
+		}
+		if !flags['q'] {
+			lines = append(lines, fmt.Sprintf("%s: %-22s  ", action, target))
+		}
+		err = mkdirAll(vfs, path.Dir(target))
+		if err == nil {
+			err = vfs.WriteFile(target, content)
+		}
+		if err != nil {
+			lines = append(lines, "error:  cannot create "+target+"\n        "+errorText(err))
+			result.exitCode = 2
+			continue
+		}
+		if mode := member.Mode().Perm(); mode != 0 {
+			vfs.Chmod(target, mode)
+		}
+	}
+	for _, pattern := range operands[1:] {
+		found := false
+		for member := range matched {
+			if unzipMatch(pattern, member) {
+				found = true
+			}
+		}
+		if !found {
+			result.fail("caution: filename not matched:  "+pattern, 11)
+		}
+	}
+
+	if flags['p'] {
+		result.stdout = streamString(output)
+	} else {
+		result.stdout = strings.Join(lines, "\n")
+	}
+	return result
+}
+
+// unzipMatch matches a member name against an unzip wildcard, where *
+// and ? also match "/"
+func unzipMatch(pattern, name string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			if end := strings.IndexByte(pattern[i+1:], ']'); end > 0 {
+				set := pattern[i+1 : i+1+end]
+				if set[0] == '!' {
+					set = "^" + set[1:]
+				}
+				expr.WriteString("[" + strings.ReplaceAll(set, `\`, `\\`) + "]")
+				i += end + 1
+				continue
+			}
+			expr.WriteString(`\[`)
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	return err == nil && re.MatchString(name)
+}
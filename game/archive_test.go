@@ -0,0 +1,121 @@
+package game
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// Expected output was recorded from GNU gzip, tar, bzip2 and Info-ZIP
+// unzip. Our bzip2 output differs from the real tool's byte for byte, so
+// it is checked by decompressing, and real.gz and msg.bz2 come from the
+// real tools.
+func TestArchives(t *testing.T) {
+	files := map[string]interface{}{
+		"notes.txt":   "hello world",
+		"data.bin":    map[string]interface{}{"content": "00010203ff7f8081", "encoding": "hex"},
+		"dir/a.txt":   "alpha",
+		"dir/b.txt":   "beta",
+		"bad.gz":      "not gzip data",
+		"real.gz":     map[string]interface{}{"content": "1f8b08000000000000034bafca2c28484d5148aa5428c94855284a4dcc5128c9cfcfe102007b89040e19000000", "encoding": "hex"},
+		"msg.bz2":     map[string]interface{}{"content": "425a6839314159265359cf4588dc000003d180001040003664d430200022986a343d350a6000286ae1c07a26d22d12bb958f8bb9229c284867a2c46e00", "encoding": "hex"},
+		"archive.zip": map[string]interface{}{"content": "504b03041400000000002d796e5800000000000000000000000005000000646f63732f504b03041400000000002d796e5805a6a1e508000000080000000f000000646f63732f726561646d652e74787472656164206d650a504b03041400000008002d796e58b4983b08130000000501000008000000666c61672e7478744bcb494cafaeca2c28484da955481b991c2e00504b010214031400000000002d796e58000000000000000000000000050000000000000000001000ed4100000000646f63732f504b010214031400000000002d796e5805a6a1e508000000080000000f0000000000000000000000a48123000000646f63732f726561646d652e747874504b010214031400000008002d796e58b4983b081300000005010000080000000000000000000000a48158000000666c61672e747874504b05060000000003000300a6000000910000000000", "encoding": "hex"},
+	}
+	runCommandTests(t, files, []commandTest{
+		{setup: []string{"gzip notes.txt"}, name: "gzip", command: "ls notes.txt notes.txt.gz", stdout: "notes.txt.gz", stderr: "ls: cannot access 'notes.txt': No such file or directory", exitCode: 2},
+		{setup: []string{"gzip -k notes.txt"}, name: "gzip -k", command: "ls notes.txt notes.txt.gz", stdout: "notes.txt\nnotes.txt.gz"},
+		{setup: []string{"gzip notes.txt"}, name: "gzip round trip", command: "zcat notes.txt.gz", stdout: "hello world"},
+		{setup: []string{"gzip notes.txt", "gunzip notes.txt.gz"}, name: "gunzip", command: "cat notes.txt", stdout: "hello world"},
+		{name: "gzip -c", command: "gzip -c notes.txt | gunzip", stdout: "hello world"},
+		{name: "gzip binary", command: "gzip -c data.bin | zcat | xxd -p", stdout: "00010203ff7f8081"},
+		{name: "zcat real", command: "zcat real.gz", stdout: "gzipped by the real tool"},
+		{setup: []string{"gzip notes.txt"}, name: "gzip .gz suffix", command: "gzip notes.txt.gz", stderr: "gzip: notes.txt.gz already has .gz suffix -- unchanged"},
+		{setup: []string{"touch notes.txt.gz"}, name: "gzip exists", command: "gzip notes.txt", stderr: "gzip: notes.txt.gz already exists;\tnot overwritten", exitCode: 2},
+		{name: "gunzip not gzip", command: "gunzip bad.gz", stderr: "gzip: bad.gz: not in gzip format", exitCode: 1},
+		{name: "gzip missing", command: "gzip nope", stderr: "gzip: nope: No such file or directory", exitCode: 1},
+		{name: "gzip directory", command: "gzip dir", stderr: "gzip: dir is a directory -- ignored", exitCode: 2},
+		{setup: []string{"bzip2 notes.txt"}, name: "bzip2", command: "ls notes.txt notes.txt.bz2", stdout: "notes.txt.bz2", stderr: "ls: cannot access 'notes.txt': No such file or directory", exitCode: 2},
+		{setup: []string{"bzip2 -k notes.txt"}, name: "bzip2 -k", command: "ls notes.txt notes.txt.bz2", stdout: "notes.txt\nnotes.txt.bz2"},
+		{setup: []string{"bzip2 notes.txt"}, name: "bzip2 round trip", command: "bzcat notes.txt.bz2", stdout: "hello world"},
+		{setup: []string{"bzip2 notes.txt", "bunzip2 notes.txt.bz2"}, name: "bunzip2", command: "cat notes.txt", stdout: "hello world"},
+		{name: "bzip2 -c", command: "bzip2 -c notes.txt | bunzip2", stdout: "hello world"},
+		{name: "bzip2 -9 stdin", command: "cat notes.txt | bzip2 -9 | bzcat", stdout: "hello world"},
+		{name: "bzip2 binary", command: "bzip2 -c data.bin | bzcat | xxd -p", stdout: "00010203ff7f8081"},
+		{name: "bzip2 -d", command: "bzip2 -dc msg.bz2", stdout: "bzipped by the real tool"},
+		{setup: []string{"bunzip2 msg.bz2"}, name: "bunzip2 real", command: "cat msg", stdout: "bzipped by the real tool"},
+		{name: "bzip2 .bz2 suffix", command: "bzip2 msg.bz2", stderr: "bzip2: Input file msg.bz2 already has .bz2 suffix.", exitCode: 1},
+		{setup: []string{"touch notes.txt.bz2"}, name: "bzip2 exists", command: "bzip2 notes.txt", stderr: "bzip2: Output file notes.txt.bz2 already exists.", exitCode: 1},
+		{name: "bzip2 directory", command: "bzip2 dir", stderr: "bzip2: Input file dir is a directory.", exitCode: 1},
+		{name: "bunzip2 directory", command: "bunzip2 dir", stderr: "bunzip2: Input file dir is a directory.", exitCode: 1},
+		{name: "bzip2 missing", command: "bzip2 nope", stderr: "bzip2: Can't open input file nope: No such file or directory.", exitCode: 1},
+		{name: "bunzip2 not bzip2", command: "bunzip2 notes.txt", stderr: "bunzip2: Can't guess original name for notes.txt -- using notes.txt.out\nbunzip2: notes.txt is not a bzip2 file.", exitCode: 2},
+		{setup: []string{"mkdir x", "bzip2 -c notes.txt > x/plain", "bunzip2 x/plain"}, name: "bunzip2 unknown suffix", command: "ls", stdout: "archive.zip\nbad.gz\ndata.bin\ndir\nmsg.bz2\nnotes.txt\nreal.gz\nx"},
+		{setup: []string{"tar -cf d.tar dir"}, name: "tar -t", command: "tar -tf d.tar | sort", stdout: "dir/\ndir/a.txt\ndir/b.txt"},
+		{setup: []string{"tar -cf d.tar dir"}, name: "tar -tv", command: "tar -tvf d.tar dir/a.txt | cut -c 1-10", stdout: "-rw-r--r--"},
+		{setup: []string{"tar -cf d.tar dir", "mkdir out", "tar -xf d.tar -C out"}, name: "tar -x", command: "cat out/dir/a.txt out/dir/b.txt", stdout: "alpha\nbeta"},
+		{setup: []string{"tar -czf d.tgz dir"}, name: "tar -z", command: "tar -tzf d.tgz | sort", stdout: "dir/\ndir/a.txt\ndir/b.txt"},
+		{setup: []string{"tar -cjf d.tbz2 dir"}, name: "tar -j", command: "tar -tf d.tbz2 | sort", stdout: "dir/\ndir/a.txt\ndir/b.txt"},
+		{setup: []string{"tar -cjf d.tbz2 dir", "bunzip2 d.tbz2"}, name: "tar -j bunzip2", command: "tar -tf d.tar | sort", stdout: "dir/\ndir/a.txt\ndir/b.txt"},
+		{setup: []string{"tar -cjf d.tbz2 dir", "mkdir out", "tar -xjf d.tbz2 -C out"}, name: "tar -xj", command: "cat out/dir/b.txt", stdout: "beta"},
+		{name: "tar stdout", command: "tar -cf - dir/a.txt | tar -tf -", stdout: "dir/a.txt"},
+		{setup: []string{"tar -cf d.tar dir", "rm dir/b.txt"}, name: "tar member", command: "tar -xvf d.tar dir/b.txt", stdout: "dir/b.txt"},
+		{setup: []string{"tar -cf d.tar dir"}, name: "tar not found", command: "tar -xf d.tar dir/c.txt", stderr: "tar: dir/c.txt: Not found in archive\ntar: Exiting with failure status due to previous errors", exitCode: 2},
+		{name: "tar missing archive", command: "tar -xf nope.tar", stderr: "tar: nope.tar: Cannot open: No such file or directory\ntar: Error is not recoverable: exiting now", exitCode: 2},
+		{name: "tar missing member", command: "tar -cf x.tar nope", stderr: "tar: nope: Cannot stat: No such file or directory\ntar: Exiting with failure status due to previous errors", exitCode: 2},
+		{name: "tar empty", command: "tar -cf x.tar", stderr: "tar: Cowardly refusing to create an empty archive\nTry 'tar --help' or 'tar --usage' for more information.", exitCode: 2},
+		{name: "tar not tar", command: "tar -tf notes.txt", stderr: "tar: This does not look like a tar archive\ntar: Exiting with failure status due to previous errors", exitCode: 2},
+		{name: "unzip", command: "unzip archive.zip", stdout: "Archive:  archive.zip\n   creating: docs/\n extracting: docs/readme.txt         \n  inflating: flag.txt                "},
+		{setup: []string{"unzip archive.zip"}, name: "unzip files", command: "cat docs/readme.txt", stdout: "read me"},
+		{name: "unzip -l", command: "unzip -l archive.zip", stdout: "Archive:  archive.zip\n  Length      Date    Time    Name\n---------  ---------- -----   ----\n        0  2024-03-14 15:09   docs/\n        8  2024-03-14 15:09   docs/readme.txt\n      261  2024-03-14 15:09   flag.txt\n---------                     -------\n      269                     3 files"},
+		{name: "unzip -p", command: "unzip -p archive.zip docs/readme.txt", stdout: "read me"},
+		{name: "unzip -d", command: "unzip -d out archive.zip", stdout: "Archive:  archive.zip\n   creating: out/docs/\n extracting: out/docs/readme.txt     \n  inflating: out/flag.txt            "},
+		{name: "unzip -q", command: "unzip -q archive.zip"},
+		{setup: []string{"mkdir docs", "touch docs/readme.txt"}, name: "unzip exists", command: "unzip archive.zip", stdout: "Archive:  archive.zip\n  inflating: flag.txt                ", stderr: "replace docs/readme.txt? [y]es, [n]o, [A]ll, [N]one, [r]ename:  NULL\n(EOF or read error, treating as \"[N]one\" ...)", exitCode: 1},
+		{setup: []string{"mkdir docs", "touch docs/readme.txt"}, name: "unzip -n", command: "unzip -n archive.zip", stdout: "Archive:  archive.zip\n  inflating: flag.txt                "},
+		{setup: []string{"mkdir docs", "touch docs/readme.txt"}, name: "unzip -o", command: "unzip -o archive.zip", stdout: "Archive:  archive.zip\n extracting: docs/readme.txt         \n  inflating: flag.txt                "},
+		{name: "unzip pattern", command: "unzip archive.zip '*.txt'", stdout: "Archive:  archive.zip\n extracting: docs/readme.txt         \n  inflating: flag.txt                "},
+		{name: "unzip no match", command: "unzip archive.zip nomatch", stdout: "Archive:  archive.zip", stderr: "caution: filename not matched:  nomatch", exitCode: 11},
+		{name: "unzip missing", command: "unzip nope", stderr: "unzip:  cannot find or open nope, nope.zip or nope.ZIP.", exitCode: 9},
+	})
+}
+
+// Members that would land outside the extraction directory are skipped,
+// where GNU tar and Info-ZIP unzip strip the "../" and extract them
+func TestArchiveUnsafeMembers(t *testing.T) {
+	var zipped bytes.Buffer
+	zw := zip.NewWriter(&zipped)
+	for _, name := range []string{"ok.txt", "../evil.txt", "/abs.txt", "a/../../up.txt"} {
+		w, _ := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		w.Write([]byte(name + "\n"))
+	}
+	zw.Close()
+
+	var tarred bytes.Buffer
+	tw := tar.NewWriter(&tarred)
+	for _, name := range []string{"ok.txt", "../evil.txt"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(name) + 1), Typeflag: tar.TypeReg})
+		tw.Write([]byte(name + "\n"))
+	}
+	tw.Close()
+
+	files := map[string]interface{}{
+		"evil.zip": map[string]interface{}{"content": hex.EncodeToString(zipped.Bytes()), "encoding": "hex"},
+		"evil.tar": map[string]interface{}{"content": hex.EncodeToString(tarred.Bytes()), "encoding": "hex"},
+	}
+	setup := []string{"mkdir w"}
+	runCommandTests(t, files, []commandTest{
+		{setup: setup, name: "unzip", command: "unzip -d w evil.zip", exitCode: 1, stdout: "Archive:  evil.zip\n" +
+			" extracting: w/ok.txt                \n" +
+			"warning:  skipped ../evil.txt: member name contains \"..\"\n" +
+			"warning:  stripped absolute path spec from /abs.txt\n" +
+			" extracting: w/abs.txt               \n" +
+			"warning:  skipped a/../../up.txt: member name contains \"..\""},
+		{setup: []string{"mkdir w", "unzip -d w evil.zip"}, name: "unzip files", command: "find /home -name '*.txt' | sort", stdout: "/home/codeheist0/w/abs.txt\n/home/codeheist0/w/ok.txt"},
+		{setup: setup, name: "unzip -p", command: "unzip -p evil.zip ../evil.txt", stdout: "../evil.txt"},
+		{setup: setup, name: "tar", command: "tar -xvf evil.tar -C w", stdout: "ok.txt\n../evil.txt",
+			stderr: "tar: ../evil.txt: Member name contains '..'\ntar: Exiting with failure status due to previous errors", exitCode: 2},
+		{setup: []string{"mkdir w", "tar -xf evil.tar -C w"}, name: "tar files", command: "find /home -name '*.txt'", stdout: "/home/codeheist0/w/ok.txt"},
+	})
+}
@@ -0,0 +1,335 @@
+package game
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// isBinary reports whether content is data rather than text, as grep
+// and cat judge it: it holds a NUL byte or isn't valid UTF-8
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// garble renders binary content the way it looks dumped to a terminal:
+// control bytes and invalid UTF-8 become replacement characters
+func garble(content string) string {
+	var out strings.Builder
+	for len(content) > 0 {
+		r, size := utf8.DecodeRuneInString(content)
+		switch {
+		case r == utf8.RuneError && size <= 1, r < 0x20 && r != '\n' && r != '\t', r == 0x7f:
+			out.WriteRune(utf8.RuneError)
+		default:
+			out.WriteString(content[:size])
+		}
+		content = content[size:]
+	}
+	return out.String()
+}
+
+// stringsCommand implements strings: print each run of at least -n
+// printable characters, one per line
+func stringsCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	// -8 is short for -n 8
+	for i, arg := range args {
+		if len(arg) > 1 && arg[0] == '-' && strings.Trim(arg[1:], "0123456789") == "" {
+			args = append(append(append([]string{}, args[:i]...), "-n", arg[1:]), args[i+1:]...)
+			break
+		}
+	}
+	flags, values, operands, usage := parseOptions("strings", args, "af", "nt", map[string]rune{
+		"all": 'a', "print-file-name": 'f', "bytes": 'n', "radix": 't'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 && stdin == nil {
+		return errorResult("strings: missing filename", 1)
+	}
+
+	minimum := 4
+	if value, set := values['n']; set {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return errorResult("strings: invalid minimum string length "+value, 1)
+		}
+		minimum = n
+	}
+	offsetFormat := ""
+	if radix, set := values['t']; set {
+		verbs := map[string]string{"d": "%7d ", "o": "%7o ", "x": "%7x "}
+		if offsetFormat = verbs[radix]; offsetFormat == "" {
+			return errorResult("strings: invalid radix", 1)
+		}
+	}
+
+	var result commandResult
+	var output []string
+	for _, input := range readInputs(vfs, operands, stdin, "strings: '%s': %s", &result) {
+		name := input.name
+		if name == "" {
+			name = "{standard input}"
+		}
+		content := input.content
+		start := -1
+		for i := 0; i <= len(content); i++ {
+			if i < len(content) && (content[i] >= 0x20 && content[i] < 0x7f || content[i] == '\t') {
+				if start < 0 {
+					start = i
+				}
+				continue
+			}
+			if start >= 0 && i-start >= minimum {
+				line := content[start:i]
+				if offsetFormat != "" {
+					line = fmt.Sprintf(offsetFormat, start) + line
+				}
+				if flags['f'] {
+					line = name + ": " + line
+				}
+				output = append(output, line)
+			}
+			start = -1
+		}
+	}
+	result.stdout = strings.Join(output, "\n")
+	return result
+}
+
+// fileCommand implements file, naming each file's type from its magic
+// bytes
+func fileCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, operands, usage := parseFlags("file", args, "bL", map[string]rune{"brief": 'b', "dereference": 'L'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return errorResult("Usage: file [-bL] <file> ...\nTry 'file --help' for more information.", 1)
+	}
+
+	width := 0
+	for _, name := range operands {
+		width = max(width, len(name)+1)
+	}
+
+	var lines []string
+	for _, name := range operands {
+		var kind string
+		if name == "-" && stdin != nil {
+			kind = fileType([]byte(*stdin))
+			name = "/dev/stdin"
+		} else {
+			kind = vfs.describe(name, flags['L'])
+		}
+		if flags['b'] {
+			lines = append(lines, kind)
+		} else {
+			lines = append(lines, fmt.Sprintf("%-*s %s", width, name+":", kind))
+		}
+	}
+	return stdoutResult(strings.Join(lines, "\n"))
+}
+
+// describe is file's report on one path
+func (vfs *VirtualFileSystem) describe(name string, follow bool) string {
+	resolve := vfs.lstat
+	if follow {
+		resolve = vfs.stat
+	}
+	_, node, err := resolve(name)
+	switch {
+	case err != nil:
+		return "cannot open `" + name + "' (" + errorText(err) + ")"
+	case node.isSymlink():
+		return "symbolic link to " + node.target
+	case node.isDir():
+		return "directory"
+	case !vfs.readable(node):
+		return "regular file, no read permission"
+	}
+	return fileType(node.content)
+}
+
+// fileType identifies content by its magic bytes, falling back to
+// describing it as text or data
+func fileType(content []byte) string {
+	switch {
+	case len(content) == 0:
+		return "empty"
+	case bytes.HasPrefix(content, []byte("\x7fELF")):
+		return elfType(content)
+	case bytes.HasPrefix(content, []byte("\x89PNG\r\n\x1a\n")):
+		return pngType(content)
+	case bytes.HasPrefix(content, []byte("GIF87a")), bytes.HasPrefix(content, []byte("GIF89a")):
+		if len(content) < 10 {
+			return "GIF image data, version " + string(content[3:6])
+		}
+		return fmt.Sprintf("GIF image data, version %s, %d x %d", content[3:6],
+			binary.LittleEndian.Uint16(content[6:]), binary.LittleEndian.Uint16(content[8:]))
+	case bytes.HasPrefix(content, []byte("\xff\xd8\xff")):
+		return "JPEG image data"
+	case bytes.HasPrefix(content, gzipMagic):
+		return gzipType(content)
+	case bytes.HasPrefix(content, bzip2Magic) && len(content) > 3 && content[3] >= '1' && content[3] <= '9':
+		return fmt.Sprintf("bzip2 compressed data, block size = %c00k", content[3])
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")) && len(content) >= 10:
+		method := map[uint16]string{0: "store", 8: "deflate"}[binary.LittleEndian.Uint16(content[8:])]
+		version := binary.LittleEndian.Uint16(content[4:])
+		return fmt.Sprintf("Zip archive data, at least v%d.%d to extract, compression method=%s", version/10, version%10, method)
+	case len(content) >= 512 && string(content[257:265]) == "ustar  \x00":
+		return "POSIX tar archive (GNU)"
+	case len(content) >= 512 && string(content[257:263]) == "ustar\x00":
+		return "POSIX tar archive"
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		version, _, _ := bytes.Cut(content[5:], []byte("\n"))
+		return "PDF document, version " + strings.TrimSpace(string(version))
+	}
+
+	text := textType(content)
+	if text == "" {
+		return "data"
+	}
+	if bytes.HasPrefix(content, []byte("#!")) {
+		return scriptType(content) + ", " + text + " executable"
+	}
+	return text
+}
+
+// textType describes text content, or returns "" for data
+func textType(content []byte) string {
+	if !utf8.Valid(content) {
+		return ""
+	}
+	ascii := true
+	for _, c := range content {
+		if c < 0x20 && !strings.ContainsRune("\t\n\r\f\b\a\x1b", rune(c)) || c == 0x7f {
+			return ""
+		}
+		ascii = ascii && c < 0x80
+	}
+
+	kind := "Unicode text, UTF-8 text"
+	if ascii {
+		kind = "ASCII text"
+	}
+	longest := 0
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		longest = max(longest, len(line))
+	}
+	if longest > 300 {
+		kind += fmt.Sprintf(", with very long lines (%d)", longest)
+	}
+	if bytes.Contains(content, []byte("\r\n")) {
+		kind += ", with CRLF line terminators"
+	}
+	return kind
+}
+
+// scriptType names the interpreter of a #! script
+func scriptType(content []byte) string {
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return "a script"
+	}
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = fields[1]
+	}
+	switch strings.TrimRight(interpreter, "0123456789.") {
+	case "bash":
+		return "Bourne-Again shell script"
+	case "sh", "dash":
+		return "POSIX shell script"
+	case "python":
+		return "Python script"
+	case "perl":
+		return "Perl script"
+	case "ruby":
+		return "Ruby script"
+	case "node":
+		return "Node.js script"
+	}
+	return "a " + strings.Join(fields, " ") + " script"
+}
+
+// elfType describes an ELF header
+func elfType(content []byte) string {
+	if len(content) < 20 {
+		return "ELF (truncated)"
+	}
+	class := map[byte]string{1: "32-bit", 2: "64-bit"}[content[4]]
+	var order binary.ByteOrder = binary.LittleEndian
+	endian := "LSB"
+	if content[5] == 2 {
+		order, endian = binary.BigEndian, "MSB"
+	}
+	kind := map[uint16]string{1: "relocatable", 2: "executable", 3: "shared object", 4: "core file"}[order.Uint16(content[16:])]
+	machine := map[uint16]string{3: "Intel 80386", 0x28: "ARM", 0x3e: "x86-64", 0xb7: "ARM aarch64", 0xf3: "UCB RISC-V"}[order.Uint16(content[18:])]
+
+	description := "ELF " + class + " " + endian + " " + kind
+	if machine != "" {
+		description += ", " + machine
+	}
+	return description + ", version 1 (SYSV)"
+}
+
+// pngType describes a PNG from its IHDR chunk
+func pngType(content []byte) string {
+	if len(content) < 29 {
+		return "PNG image data"
+	}
+	width := binary.BigEndian.Uint32(content[16:])
+	height := binary.BigEndian.Uint32(content[20:])
+	depth := content[24]
+	color := map[byte]string{
+		0: fmt.Sprintf("%d-bit grayscale", depth),
+		2: fmt.Sprintf("%d-bit/color RGB", depth),
+		3: fmt.Sprintf("%d-bit colormap", depth),
+		4: fmt.Sprintf("%d-bit gray+alpha", depth),
+		6: fmt.Sprintf("%d-bit/color RGBA", depth),
+	}[content[25]]
+	interlace := "non-interlaced"
+	if content[28] == 1 {
+		interlace = "interlaced"
+	}
+	return fmt.Sprintf("PNG image data, %d x %d, %s, %s", width, height, color, interlace)
+}
+
+// gzipType describes a gzip header: the original name and time, and
+// the size of the data it holds
+func gzipType(content []byte) string {
+	if len(content) < 18 {
+		return "gzip compressed data"
+	}
+	description := "gzip compressed data"
+	flags := content[3]
+	rest := content[10:]
+	if flags&0x04 != 0 && len(rest) >= 2 { // FEXTRA
+		rest = rest[min(2+int(binary.LittleEndian.Uint16(rest)), len(rest)):]
+	}
+	if flags&0x08 != 0 { // FNAME
+		if name, _, found := bytes.Cut(rest, []byte{0}); found {
+			description += ", was \"" + string(name) + "\""
+		}
+	}
+	if modified := binary.LittleEndian.Uint32(content[4:]); modified != 0 {
+		description += ", last modified: " + time.Unix(int64(modified), 0).UTC().Format("Mon Jan _2 15:04:05 2006")
+	}
+	switch content[8] {
+	case 2:
+		description += ", max compression"
+	case 4:
+		description += ", max speed"
+	}
+	if content[9] == 3 {
+		description += ", from Unix"
+	}
+	return description + fmt.Sprintf(", original size modulo 2^32 %d", binary.LittleEndian.Uint32(content[len(content)-4:]))
+}
@@ -0,0 +1,348 @@
+package game
+
+import "sort"
+
+// The standard library only decompresses bzip2, so compression lives
+// here: run-length encoding, the Burrows-Wheeler transform, move-to-front
+// and Huffman coding, as in the bzip2 file format. With a single Huffman
+// table the output is a little larger than the real tool's, but any
+// bunzip2 decompresses it.
+
+// Block framing magics, written as two 24-bit halves
+const (
+	bzip2BlockMagicHigh  = 0x314159
+	bzip2BlockMagicLow   = 0x265359
+	bzip2StreamMagicHigh = 0x177245
+	bzip2StreamMagicLow  = 0x385090
+	bzip2MaxCodeLength   = 17 // Decoders accept up to 20
+	bzip2GroupSize       = 50 // Symbols per Huffman table selector
+)
+
+var bzip2CRCTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// bzip2Bytes compresses data with blocks of level * 100k bytes
+func bzip2Bytes(data []byte, level int) []byte {
+	w := &bitWriter{}
+	w.out = append(w.out, 'B', 'Z', 'h', byte('0'+level))
+
+	var combined uint32
+	for len(data) > 0 {
+		block, used := bzip2RunLengths(data, level*100000-19)
+		crc := bzip2CRC(data[:used])
+		combined = (combined<<1 | combined>>31) ^ crc
+		bzip2WriteBlock(w, block, crc)
+		data = data[used:]
+	}
+
+	w.write(24, bzip2StreamMagicHigh)
+	w.write(24, bzip2StreamMagicLow)
+	w.write(32, uint64(combined))
+	return w.flush()
+}
+
+func bzip2CRC(data []byte) uint32 {
+	crc := ^uint32(0)
+	for _, c := range data {
+		crc = crc<<8 ^ bzip2CRCTable[byte(crc>>24)^c]
+	}
+	return ^crc
+}
+
+// bzip2RunLengths shortens runs of 4 to 255 equal bytes to the first
+// four and a count, stopping before the result outgrows limit. It
+// returns the block and how much of data it covers.
+func bzip2RunLengths(data []byte, limit int) ([]byte, int) {
+	var block []byte
+	i := 0
+	for i < len(data) {
+		run := 1
+		for run < 255 && i+run < len(data) && data[i+run] == data[i] {
+			run++
+		}
+		size := run
+		if run >= 4 {
+			size = 5
+		}
+		if len(block)+size > limit {
+			break
+		}
+		if run >= 4 {
+			block = append(block, data[i], data[i], data[i], data[i], byte(run-4))
+		} else {
+			for range run {
+				block = append(block, data[i])
+			}
+		}
+		i += run
+	}
+	return block, i
+}
+
+// burrowsWheeler sorts the block's rotations by prefix doubling and
+// returns their last bytes and where the unrotated block ended up
+func burrowsWheeler(block []byte) ([]byte, int) {
+	n := len(block)
+	rotations := make([]int, n)
+	rank := make([]int, n)
+	for i := range block {
+		rotations[i] = i
+		rank[i] = int(block[i])
+	}
+	next := make([]int, n)
+	for k := 1; ; k *= 2 {
+		key := func(i int) (int, int) {
+			return rank[i], rank[(i+k)%n]
+		}
+		sort.Slice(rotations, func(a, b int) bool {
+			a1, a2 := key(rotations[a])
+			b1, b2 := key(rotations[b])
+			return a1 < b1 || a1 == b1 && a2 < b2
+		})
+		next[rotations[0]] = 0
+		for i := 1; i < n; i++ {
+			next[rotations[i]] = next[rotations[i-1]]
+			a1, a2 := key(rotations[i-1])
+			b1, b2 := key(rotations[i])
+			if a1 != b1 || a2 != b2 {
+				next[rotations[i]]++
+			}
+		}
+		copy(rank, next)
+		// Equal rotations of a periodic block never separate
+		if rank[rotations[n-1]] == n-1 || k >= n {
+			break
+		}
+	}
+
+	last := make([]byte, n)
+	origin := 0
+	for i, start := range rotations {
+		last[i] = block[(start+n-1)%n]
+		if start == 0 {
+			origin = i
+		}
+	}
+	return last, origin
+}
+
+// bzip2WriteBlock encodes one run-length encoded block
+func bzip2WriteBlock(w *bitWriter, block []byte, crc uint32) {
+	last, origin := burrowsWheeler(block)
+
+	// Bytes are renumbered over the ones the block uses
+	var used [256]bool
+	for _, c := range block {
+		used[c] = true
+	}
+	var order [256]byte
+	var recent []byte
+	for c := range used {
+		if used[c] {
+			order[c] = byte(len(recent))
+			recent = append(recent, byte(len(recent)))
+		}
+	}
+
+	// Move-to-front, with runs of zeros written in bijective base 2
+	// as RUNA (0) and RUNB (1); other positions shift up by one
+	var symbols []int
+	zeros := 0
+	flushZeros := func() {
+		for zeros > 0 {
+			if zeros&1 == 1 {
+				symbols = append(symbols, 0)
+				zeros = (zeros - 1) / 2
+			} else {
+				symbols = append(symbols, 1)
+				zeros = (zeros - 2) / 2
+			}
+		}
+	}
+	for _, c := range last {
+		seq := order[c]
+		j := 0
+		for recent[j] != seq {
+			j++
+		}
+		if j == 0 {
+			zeros++
+			continue
+		}
+		flushZeros()
+		copy(recent[1:j+1], recent[:j])
+		recent[0] = seq
+		symbols = append(symbols, j+1)
+	}
+	flushZeros()
+	alphabet := len(recent) + 2
+	symbols = append(symbols, alphabet-1) // End of block
+
+	freq := make([]int, alphabet)
+	for _, symbol := range symbols {
+		freq[symbol]++
+	}
+	lengths := huffmanLengths(freq, bzip2MaxCodeLength)
+	codes := canonicalCodes(lengths)
+
+	w.write(24, bzip2BlockMagicHigh)
+	w.write(24, bzip2BlockMagicLow)
+	w.write(32, uint64(crc))
+	w.write(1, 0) // Not randomised
+	w.write(24, uint64(origin))
+
+	var ranges uint64
+	for i := range 16 {
+		for j := range 16 {
+			if used[i*16+j] {
+				ranges |= 1 << (15 - i)
+			}
+		}
+	}
+	w.write(16, ranges)
+	for i := range 16 {
+		if ranges&(1<<(15-i)) == 0 {
+			continue
+		}
+		var bits uint64
+		for j := range 16 {
+			if used[i*16+j] {
+				bits |= 1 << (15 - j)
+			}
+		}
+		w.write(16, bits)
+	}
+
+	// The format needs at least two tables; both are the same, so every
+	// selector picks the first
+	const tables = 2
+	selectors := (len(symbols) + bzip2GroupSize - 1) / bzip2GroupSize
+	w.write(3, tables)
+	w.write(15, uint64(selectors))
+	for range selectors {
+		w.write(1, 0)
+	}
+	for range tables {
+		current := lengths[0]
+		w.write(5, uint64(current))
+		for _, length := range lengths {
+			for ; current < length; current++ {
+				w.write(2, 2)
+			}
+			for ; current > length; current-- {
+				w.write(2, 3)
+			}
+			w.write(1, 0)
+		}
+	}
+
+	for _, symbol := range symbols {
+		w.write(uint(lengths[symbol]), uint64(codes[symbol]))
+	}
+}
+
+// huffmanLengths builds code lengths for every symbol, unused ones too,
+// flattening the frequencies until no code is longer than maxLength
+func huffmanLengths(freq []int, maxLength int) []int {
+	weights := make([]int, len(freq))
+	for i, f := range freq {
+		weights[i] = max(f, 1)
+	}
+	for {
+		lengths := huffmanTree(weights)
+		longest := 0
+		for _, length := range lengths {
+			longest = max(longest, length)
+		}
+		if longest <= maxLength {
+			return lengths
+		}
+		for i := range weights {
+			weights[i] = 1 + weights[i]/2
+		}
+	}
+}
+
+// huffmanTree merges the two lightest nodes until one is left and
+// returns each leaf's depth
+func huffmanTree(weights []int) []int {
+	type node struct{ weight, parent int }
+	nodes := make([]node, len(weights), 2*len(weights))
+	for i, weight := range weights {
+		nodes[i] = node{weight, -1}
+	}
+	active := make([]int, len(weights))
+	for i := range active {
+		active[i] = i
+	}
+	for len(active) > 1 {
+		sort.Slice(active, func(a, b int) bool { return nodes[active[a]].weight < nodes[active[b]].weight })
+		parent := len(nodes)
+		nodes = append(nodes, node{nodes[active[0]].weight + nodes[active[1]].weight, -1})
+		nodes[active[0]].parent = parent
+		nodes[active[1]].parent = parent
+		active = append(active[2:], parent)
+	}
+
+	lengths := make([]int, len(weights))
+	for i := range weights {
+		for n := i; nodes[n].parent >= 0; n = nodes[n].parent {
+			lengths[i]++
+		}
+	}
+	return lengths
+}
+
+// canonicalCodes numbers codes by length, then by symbol
+func canonicalCodes(lengths []int) []uint32 {
+	codes := make([]uint32, len(lengths))
+	var code uint32
+	for length := 1; length <= bzip2MaxCodeLength; length++ {
+		for symbol, l := range lengths {
+			if l == length {
+				codes[symbol] = code
+				code++
+			}
+		}
+		code <<= 1
+	}
+	return codes
+}
+
+// bitWriter packs values most significant bit first
+type bitWriter struct {
+	out   []byte
+	bits  uint64
+	nbits uint
+}
+
+func (w *bitWriter) write(n uint, value uint64) {
+	w.bits = w.bits<<n | value&(1<<n-1)
+	w.nbits += n
+	for w.nbits >= 8 {
+		w.nbits -= 8
+		w.out = append(w.out, byte(w.bits>>w.nbits))
+	}
+	w.bits &= 1<<w.nbits - 1
+}
+
+// flush pads the last byte with zero bits
+func (w *bitWriter) flush() []byte {
+	if w.nbits > 0 {
+		w.write(8-w.nbits, 0)
+	}
+	return w.out
+}
@@ -0,0 +1,36 @@
+package game
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// TestBzip2Bytes round-trips through the standard library's decoder;
+// archive_test.go covers the commands
+func TestBzip2Bytes(t *testing.T) {
+	random := make([]byte, 20000)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("the quick brown fox jumps over the lazy dog\n"), 5000)
+
+	tests := []struct {
+		name  string
+		data  []byte
+		level int
+	}{
+		{"empty", nil, 9},
+		{"one byte", []byte("a"), 9},
+		{"periodic", bytes.Repeat([]byte("ab"), 1000), 9},
+		{"long run", bytes.Repeat([]byte{0}, 1000), 9},
+		{"runs of every length", bytes.Repeat([]byte("abbcccddddeeeeeffffffggggggg"), 50), 9},
+		{"random", random, 9},
+		{"several blocks", text, 1},
+	}
+	for _, tt := range tests {
+		compressed := bzip2Bytes(tt.data, tt.level)
+		got, err := bunzip2Bytes(compressed)
+		if err != nil || !bytes.Equal(got, tt.data) {
+			t.Errorf("%s: round trip gave %d bytes, error %v, want %d bytes", tt.name, len(got), err, len(tt.data))
+		}
+	}
+}
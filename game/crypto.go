@@ -0,0 +1,684 @@
+package game
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// base64Command implements base64: encode, or with -d decode, files or
+// stdin
+func base64Command(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions("base64", args, "di", "w", map[string]rune{
+		"decode": 'd', "ignore-garbage": 'i', "wrap": 'w'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) > 1 {
+		return errorResult("base64: extra operand '"+operands[1]+"'\nTry 'base64 --help' for more information.", 1)
+	}
+	wrap := 76
+	if value, set := values['w']; set {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return errorResult("base64: invalid wrap size: '"+value+"'", 1)
+		}
+		wrap = n
+	}
+
+	var result commandResult
+	data := readBytes(vfs, operands, stdin, "base64: %s: %s", &result)
+	if result.exitCode != 0 {
+		return result
+	}
+	if !flags['d'] {
+		result.stdout = wrapColumns(base64.StdEncoding.EncodeToString(data), wrap)
+		return result
+	}
+
+	decoded, err := decodeBase64(data, flags['i'])
+	result.stdout = streamString(decoded)
+	if err != nil {
+		result.fail("base64: invalid input", 1)
+	}
+	return result
+}
+
+// decodeBase64 decodes as GNU base64 -d does: line breaks are skipped,
+// anything else outside the alphabet is an error unless ignoreGarbage,
+// and the data before an error is still returned
+func decodeBase64(data []byte, ignoreGarbage bool) ([]byte, error) {
+	var clean []byte
+	for _, c := range data {
+		switch {
+		case c == '\n' || c == '\r':
+		case ignoreGarbage && !strings.ContainsRune("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/=", rune(c)):
+		default:
+			clean = append(clean, c)
+		}
+	}
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(clean)))
+	n, err := base64.StdEncoding.Decode(decoded, clean)
+	var corrupt base64.CorruptInputError
+	if errors.As(err, &corrupt) {
+		// Decode stops at the bad quantum; keep the whole ones before it
+		// and, like GNU, the bytes its leading characters complete
+		whole := int(corrupt) / 4 * 4
+		n, _ = base64.StdEncoding.Decode(decoded, clean[:whole])
+		partial, _ := base64.RawStdEncoding.Decode(decoded[n:], clean[whole:corrupt])
+		n += partial
+	}
+	return decoded[:n], err
+}
+
+// wrapColumns breaks a base64 string into lines of width characters,
+// or leaves it whole for width 0
+func wrapColumns(text string, width int) string {
+	if width == 0 {
+		return text
+	}
+	return strings.Join(wrapLines(text, width), "\n")
+}
+
+// hashAlgorithms are the digests behind the *sum commands and openssl
+// dgst, by name
+var hashAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// hashSumCommand implements md5sum, sha1sum, sha256sum and sha512sum:
+// print each input's digest, or with -c verify a list of them
+func hashSumCommand(vfs *VirtualFileSystem, command string, args []string, stdin *string) commandResult {
+	flags, operands, usage := parseFlags(command, args, "bctw", map[string]rune{
+		"binary": 'b', "check": 'c', "text": 't', "warn": 'w'})
+	if usage != nil {
+		return *usage
+	}
+	newHash := hashAlgorithms[strings.TrimSuffix(command, "sum")]
+	if flags['c'] {
+		return checkSums(vfs, command, newHash, operands, stdin, flags)
+	}
+
+	var result commandResult
+	var lines []string
+	for _, input := range readInputs(vfs, operands, stdin, command+": %s: %s", &result) {
+		name := input.name
+		if name == "" {
+			name = "-"
+		}
+		lines = append(lines, digest(newHash, streamBytes(input.content))+"  "+name)
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// checkSums is the -c mode of the *sum commands
+func checkSums(vfs *VirtualFileSystem, command string, newHash func() hash.Hash, operands []string, stdin *string, flags map[rune]bool) commandResult {
+	var result commandResult
+	var lines []string
+	for _, input := range readInputs(vfs, operands, stdin, command+": %s: %s", &result) {
+		listName := input.name
+		if listName == "" {
+			listName = "standard input"
+		}
+		checked, mismatched, unreadable, malformed := 0, 0, 0, 0
+		for _, line := range textLines(input.content) {
+			sum, name, found := strings.Cut(line, " ")
+			name = strings.TrimPrefix(strings.TrimPrefix(name, " "), "*")
+			if !found || name == "" || len(sum) != newHash().Size()*2 || strings.Trim(strings.ToLower(sum), "0123456789abcdef") != "" {
+				malformed++
+				continue
+			}
+			checked++
+			content, err := vfs.ReadFile(name)
+			switch {
+			case err != nil:
+				unreadable++
+				result.fail(command+": "+name+": "+errorText(err), 1)
+				lines = append(lines, name+": FAILED open or read")
+			case digest(newHash, streamBytes(string(content))) != strings.ToLower(sum):
+				mismatched++
+				lines = append(lines, name+": FAILED")
+			default:
+				lines = append(lines, name+": OK")
+			}
+		}
+
+		if checked == 0 {
+			result.fail(command+": "+listName+": no properly formatted checksum lines found", 1)
+			continue
+		}
+		if malformed > 0 && flags['w'] {
+			result.fail(fmt.Sprintf("%s: WARNING: %d %s improperly formatted", command, malformed, plural(malformed, "line is", "lines are")), result.exitCode)
+		}
+		if unreadable > 0 {
+			result.fail(fmt.Sprintf("%s: WARNING: %d listed %s could not be read", command, unreadable, plural(unreadable, "file", "files")), 1)
+		}
+		if mismatched > 0 {
+			result.fail(fmt.Sprintf("%s: WARNING: %d computed %s did NOT match", command, mismatched, plural(mismatched, "checksum", "checksums")), 1)
+		}
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// plural picks the singular or plural form for n
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// digest is data's hash in lowercase hex
+func digest(newHash func() hash.Hash, data []byte) string {
+	h := newHash()
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// xorCommand implements xor, a CTF helper: XOR the input with a
+// repeating key given as text (-k) or hex (-x), or with -b try every
+// single-byte key and list those that give printable text
+func xorCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions("xor", args, "b", "kx", map[string]rune{
+		"brute-force": 'b', "key": 'k', "hex-key": 'x'})
+	if usage != nil {
+		return *usage
+	}
+
+	var key []byte
+	textKey, hasText := values['k']
+	hexKey, hasHex := values['x']
+	switch {
+	case flags['b'] && (hasText || hasHex), hasText && hasHex:
+		return errorResult("xor: use only one of -k, -x and -b", 1)
+	case hasText:
+		key = []byte(textKey)
+	case hasHex:
+		var err error
+		if key, err = hex.DecodeString(strings.TrimPrefix(hexKey, "0x")); err != nil {
+			return errorResult("xor: invalid hex key '"+hexKey+"'", 1)
+		}
+	case !flags['b']:
+		return errorResult("xor: a key is required: -k <text>, -x <hex> or -b to try every byte", 1)
+	}
+	if len(key) == 0 && !flags['b'] {
+		return errorResult("xor: the key is empty", 1)
+	}
+
+	// Unlike the byte dumps, xor leaves text's implied final newline out,
+	// so text XORed twice with the same key comes back unchanged
+	var result commandResult
+	var data []byte
+	for _, input := range readInputs(vfs, operands, stdin, "xor: %s: %s", &result) {
+		data = append(data, input.content...)
+	}
+	if result.exitCode != 0 {
+		return result
+	}
+	if !flags['b'] {
+		result.stdout = string(xorBytes(data, key))
+		return result
+	}
+
+	var candidates []string
+	for k := 1; k < 256; k++ {
+		plain := xorBytes(data, []byte{byte(k)})
+		if printableText(plain) {
+			candidates = append(candidates, fmt.Sprintf("0x%02x: %s", k, strings.ReplaceAll(string(plain), "\n", "\\n")))
+		}
+	}
+	if len(candidates) == 0 {
+		return errorResult("xor: no single-byte key gives printable text", 1)
+	}
+	result.stdout = strings.Join(candidates, "\n")
+	return result
+}
+
+// xorBytes XORs data with key repeated to its length
+func xorBytes(data, key []byte) []byte {
+	out := make([]byte, len(data))
+	for i, c := range data {
+		out[i] = c ^ key[i%len(key)]
+	}
+	return out
+}
+
+// printableText reports whether data is all printable ASCII, tabs and
+// newlines
+func printableText(data []byte) bool {
+	for _, c := range data {
+		if (c < 0x20 || c >= 0x7f) && c != '\n' && c != '\t' {
+			return false
+		}
+	}
+	return len(data) > 0
+}
+
+// opensslCommand implements the parts of openssl a puzzle needs: enc,
+// base64, the digests and s_client
+func opensslCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	if len(args) == 0 {
+		return errorResult("openssl: interactive mode is not supported; try openssl enc, dgst, base64 or s_client", 1)
+	}
+	subcommand, args := args[0], args[1:]
+	switch {
+	case subcommand == "enc":
+		return opensslEnc(vfs, "", args, stdin)
+	case subcommand == "base64":
+		return opensslEnc(vfs, "base64", args, stdin)
+	case subcommand == "dgst":
+		return opensslDigest(vfs, "", args, stdin)
+	case subcommand == "s_client":
+		return sClientCommand(vfs, args, stdin)
+	case hashAlgorithms[subcommand] != nil:
+		return opensslDigest(vfs, subcommand, args, stdin)
+	case aesCiphers[subcommand].keySize > 0:
+		return opensslEnc(vfs, subcommand, args, stdin)
+	}
+	return errorResult("Invalid command '"+subcommand+"'; type \"help\" for a list.", 1)
+}
+
+// aesCipher is one of the AES modes openssl enc offers
+type aesCipher struct {
+	keySize int
+	mode    string // "cbc", "ecb" or "ctr"
+}
+
+var aesCiphers = map[string]aesCipher{
+	"aes-128-cbc": {16, "cbc"}, "aes-192-cbc": {24, "cbc"}, "aes-256-cbc": {32, "cbc"},
+	"aes-128-ecb": {16, "ecb"}, "aes-192-ecb": {24, "ecb"}, "aes-256-ecb": {32, "ecb"},
+	"aes-128-ctr": {16, "ctr"}, "aes-192-ctr": {24, "ctr"}, "aes-256-ctr": {32, "ctr"},
+	"aes128": {16, "cbc"}, "aes192": {24, "cbc"}, "aes256": {32, "cbc"},
+}
+
+// opensslEnc implements openssl enc. Output is byte-compatible with the
+// real tool: "Salted__", the salt, then the ciphertext, with the key and
+// IV from PBKDF2 (-pbkdf2, -iter) or the legacy EVP_BytesToKey.
+func opensslEnc(vfs *VirtualFileSystem, cipherName string, args []string, stdin *string) commandResult {
+	decrypt, encode, oneLine, usePBKDF2, salted, printKey, printOnly := false, cipherName == "base64", false, false, true, false, false
+	iterations := 0
+	var inFile, outFile, password, digestName, saltHex, keyHex, ivHex string
+	havePassword := false
+	for i := 0; i < len(args); i++ {
+		option := strings.TrimPrefix(args[i], "-")
+		if !strings.HasPrefix(args[i], "-") {
+			return errorResult("enc: Extra option: \""+args[i]+"\"\nenc: Use -help for summary.", 1)
+		}
+		value := func() (string, bool) {
+			if i+1 >= len(args) {
+				return "", false
+			}
+			i++
+			return args[i], true
+		}
+		var ok = true
+		switch option {
+		case "d":
+			decrypt = true
+		case "e":
+			decrypt = false
+		case "a", "base64":
+			encode = true
+		case "A":
+			oneLine = true
+		case "pbkdf2":
+			usePBKDF2 = true
+		case "salt":
+			salted = true
+		case "nosalt":
+			salted = false
+		case "p":
+			printKey = true
+		case "P":
+			printKey, printOnly = true, true
+		case "in":
+			inFile, ok = value()
+		case "out":
+			outFile, ok = value()
+		case "k":
+			password, ok = value()
+			havePassword = true
+		case "pass":
+			var spec string
+			if spec, ok = value(); ok {
+				var err error
+				if password, err = passwordArg(vfs, spec); err != nil {
+					return errorResult(err.Error(), 1)
+				}
+				havePassword = true
+			}
+		case "md":
+			digestName, ok = value()
+		case "S":
+			saltHex, ok = value()
+		case "K":
+			keyHex, ok = value()
+		case "iv":
+			ivHex, ok = value()
+		case "iter":
+			var count string
+			if count, ok = value(); ok {
+				n, err := strconv.Atoi(count)
+				if err != nil || n < 1 {
+					return errorResult("enc: Non-positive number \""+count+"\" for option -iter\nenc: Use -help for summary.", 1)
+				}
+				iterations, usePBKDF2 = n, true
+			}
+		case "cipher":
+			cipherName, ok = value()
+		default:
+			if _, known := aesCiphers[option]; !known {
+				return errorResult("enc: Unknown cipher: "+option+"\nenc: Use -help for summary.", 1)
+			}
+			cipherName = option
+		}
+		if !ok {
+			return errorResult("enc: Option -"+option+" needs a value\nenc: Use -help for summary.", 1)
+		}
+	}
+
+	var result commandResult
+	var input []byte
+	if inFile != "" {
+		content, err := vfs.ReadFile(inFile)
+		if err != nil {
+			return errorResult("Can't open \""+inFile+"\" for reading, "+errorText(err), 1)
+		}
+		input = streamBytes(string(content))
+	} else if stdin != nil {
+		input = streamBytes(*stdin)
+	}
+
+	var output []byte
+	if cipherName == "base64" || cipherName == "" {
+		if decrypt && encode {
+			var err error
+			if output, err = decodeBase64(input, false); err != nil {
+				output = nil
+			}
+		} else if encode {
+			output = encodeOpenSSLBase64(input, oneLine)
+		} else {
+			output = input
+		}
+		return opensslOutput(vfs, outFile, output, result)
+	}
+
+	spec, known := aesCiphers[cipherName]
+	if !known {
+		return errorResult("enc: Unknown cipher: "+cipherName+"\nenc: Use -help for summary.", 1)
+	}
+	newHash := sha256.New
+	if digestName != "" {
+		if newHash = hashAlgorithms[digestName]; newHash == nil {
+			return errorResult("enc: Unknown option or message digest: "+digestName+"\nenc: Use -help for summary.", 1)
+		}
+	}
+	if usePBKDF2 && iterations == 0 {
+		iterations = 10000
+	}
+
+	if decrypt && encode {
+		var err error
+		if input, err = decodeBase64(input, false); err != nil {
+			return errorResult("error reading input file", 1)
+		}
+	}
+
+	var key, iv, salt []byte
+	header := false
+	hexValue := func(value string, size int) []byte {
+		decoded, short := hexParameter(value, size)
+		if short {
+			result.fail("hex string is too short, padding with zero bytes to length", result.exitCode)
+		}
+		return decoded
+	}
+	if keyHex != "" {
+		key = hexValue(keyHex, spec.keySize)
+		if spec.mode != "ecb" {
+			if ivHex == "" {
+				return errorResult("iv undefined", 1)
+			}
+			iv = hexValue(ivHex, aes.BlockSize)
+		}
+	} else {
+		if !havePassword {
+			return errorResult("enter "+strings.ToUpper(cipherName)+" "+map[bool]string{true: "decryption", false: "encryption"}[decrypt]+" password:\nbad password read", 1)
+		}
+		if !usePBKDF2 {
+			result.fail("*** WARNING : deprecated key derivation used.\nUsing -iter or -pbkdf2 would be better.", 0)
+		}
+		switch {
+		case decrypt && salted && saltHex == "":
+			if len(input) < 16 {
+				result.fail("error reading input file", 1)
+				return result
+			}
+			if string(input[:8]) != "Salted__" {
+				result.fail("bad magic number", 1)
+				return result
+			}
+			salt, input = input[8:16], input[16:]
+		case salted && saltHex != "":
+			salt = hexValue(saltHex, 8)
+		case salted:
+			// Only a generated salt is written out; with -S the
+			// decrypting side is expected to pass it again
+			salt, header = make([]byte, 8), true
+			rand.Read(salt)
+		}
+		material := deriveKey(newHash, password, salt, iterations, spec.keySize+aes.BlockSize)
+		key, iv = material[:spec.keySize], material[spec.keySize:]
+		if ivHex != "" {
+			iv = hexValue(ivHex, aes.BlockSize)
+		}
+	}
+
+	if printKey {
+		var lines []string
+		if salt != nil {
+			lines = append(lines, "salt="+strings.ToUpper(hex.EncodeToString(salt)))
+		}
+		lines = append(lines, "key="+strings.ToUpper(hex.EncodeToString(key)))
+		if spec.mode != "ecb" {
+			lines = append(lines, "iv ="+strings.ToUpper(hex.EncodeToString(iv)))
+		}
+		result.stdout = strings.Join(lines, "\n")
+		if printOnly {
+			return result
+		}
+		result.stdout += "\n"
+	}
+
+	block, _ := aes.NewCipher(key)
+	if decrypt {
+		plain, err := aesDecrypt(block, spec.mode, iv, input)
+		if err != nil {
+			result.fail("bad decrypt", 1)
+		}
+		return opensslOutput(vfs, outFile, plain, result)
+	}
+
+	output = aesEncrypt(block, spec.mode, iv, input)
+	if header {
+		output = append(append([]byte("Salted__"), salt...), output...)
+	}
+	if encode {
+		output = encodeOpenSSLBase64(output, oneLine)
+	}
+	return opensslOutput(vfs, outFile, output, result)
+}
+
+// opensslOutput writes openssl's output to -out, or to stdout after
+// anything already printed there
+func opensslOutput(vfs *VirtualFileSystem, outFile string, output []byte, result commandResult) commandResult {
+	if outFile != "" {
+		if err := vfs.WriteFile(outFile, output); err != nil {
+			result.fail("Can't open \""+outFile+"\" for writing, "+errorText(err), 1)
+		}
+		result.stdout = strings.TrimSuffix(result.stdout, "\n")
+		return result
+	}
+	if result.stdout != "" && !isBinary(output) {
+		result.stdout += streamString(output)
+		return result
+	}
+	result.stdout = streamString(append([]byte(result.stdout), output...))
+	return result
+}
+
+// passwordArg reads a -pass argument: pass:text, file:path or env:VAR
+func passwordArg(vfs *VirtualFileSystem, spec string) (string, error) {
+	source, value, _ := strings.Cut(spec, ":")
+	switch source {
+	case "pass":
+		return value, nil
+	case "file":
+		content, err := vfs.ReadFile(value)
+		if err != nil {
+			return "", fmt.Errorf("Can't open file %s", value)
+		}
+		line, _, _ := strings.Cut(string(content), "\n")
+		return line, nil
+	}
+	return "", fmt.Errorf("Invalid password argument \"%s\"\nError getting password", spec)
+}
+
+// hexParameter decodes a hex -K, -iv or -S value, padding it with zeros
+// to size bytes as openssl does, and reports whether it was short
+func hexParameter(value string, size int) ([]byte, bool) {
+	decoded, _ := hex.DecodeString(value + strings.Repeat("0", len(value)%2))
+	out := make([]byte, size)
+	copy(out, decoded)
+	return out, len(decoded) < size
+}
+
+// deriveKey produces size bytes of key and IV from a password: PBKDF2
+// when iterations is set, or else OpenSSL's EVP_BytesToKey with one round
+func deriveKey(newHash func() hash.Hash, password string, salt []byte, iterations, size int) []byte {
+	if iterations > 0 {
+		key, _ := pbkdf2.Key(newHash, password, salt, iterations, size)
+		return key
+	}
+	var material, previous []byte
+	for len(material) < size {
+		h := newHash()
+		h.Write(previous)
+		h.Write([]byte(password))
+		h.Write(salt)
+		previous = h.Sum(nil)
+		material = append(material, previous...)
+	}
+	return material[:size]
+}
+
+// aesEncrypt encrypts with PKCS#7 padding for the block modes
+func aesEncrypt(block cipher.Block, mode string, iv, plain []byte) []byte {
+	if mode == "ctr" {
+		out := make([]byte, len(plain))
+		cipher.NewCTR(block, iv).XORKeyStream(out, plain)
+		return out
+	}
+	padding := aes.BlockSize - len(plain)%aes.BlockSize
+	padded := append(append([]byte{}, plain...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+	out := make([]byte, len(padded))
+	if mode == "cbc" {
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, padded)
+		return out
+	}
+	for i := 0; i < len(padded); i += aes.BlockSize {
+		block.Encrypt(out[i:], padded[i:])
+	}
+	return out
+}
+
+// aesDecrypt reverses aesEncrypt. A wrong key shows up as bad padding;
+// what was decrypted is returned alongside the error, as openssl prints
+// it too.
+func aesDecrypt(block cipher.Block, mode string, iv, data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	if mode == "ctr" {
+		cipher.NewCTR(block, iv).XORKeyStream(out, data)
+		return out, nil
+	}
+	whole := len(data) / aes.BlockSize * aes.BlockSize
+	if whole == 0 {
+		return nil, errors.New("wrong final block length")
+	}
+	if mode == "cbc" {
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(out[:whole], data[:whole])
+	} else {
+		for i := 0; i < whole; i += aes.BlockSize {
+			block.Decrypt(out[i:], data[i:])
+		}
+	}
+	if whole != len(data) {
+		return out[:whole-aes.BlockSize], errors.New("wrong final block length")
+	}
+	padding := int(out[whole-1])
+	if padding == 0 || padding > aes.BlockSize || !bytes.Equal(out[whole-padding:whole], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return out[:whole-aes.BlockSize], errors.New("bad decrypt")
+	}
+	return out[:whole-padding], nil
+}
+
+// encodeOpenSSLBase64 is openssl's -a output: 64 columns, or one line
+// with -A, newline-terminated either way
+func encodeOpenSSLBase64(data []byte, oneLine bool) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	if !oneLine {
+		encoded = wrapColumns(encoded, 64)
+	}
+	return []byte(encoded + "\n")
+}
+
+// opensslDigest implements openssl dgst and its md5, sha1 and sha256
+// shorthands
+func opensslDigest(vfs *VirtualFileSystem, algorithm string, args []string, stdin *string) commandResult {
+	var operands []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "-") && hashAlgorithms[strings.TrimPrefix(arg, "-")] != nil:
+			algorithm = strings.TrimPrefix(arg, "-")
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			return errorResult("dgst: Unknown option or message digest: "+strings.TrimPrefix(arg, "-")+"\ndgst: Use -help for summary.", 1)
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+	label := map[string]string{"md5": "MD5", "sha1": "SHA1", "sha256": "SHA2-256", "sha512": "SHA2-512"}[algorithm]
+
+	var result commandResult
+	var lines []string
+	for _, input := range readInputs(vfs, operands, stdin, "%s: %s", &result) {
+		name := input.name
+		if name == "" {
+			name = "stdin"
+		}
+		lines = append(lines, label+"("+name+")= "+digest(hashAlgorithms[algorithm], streamBytes(input.content)))
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
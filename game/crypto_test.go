@@ -0,0 +1,70 @@
+package game
+
+import "testing"
+
+// Expected output was recorded from GNU coreutils and OpenSSL 3.0 on the
+// same files, minus OpenSSL's library error stack. Salts are fixed with
+// -S, or read from msg.enc, so the ciphertexts are reproducible.
+func TestCrypto(t *testing.T) {
+	files := map[string]interface{}{
+		"msg.txt":  "attack at dawn",
+		"b64.txt":  "ZmxhZ3tiYXNlNjR9Cg==",
+		"long.txt": "The quick brown fox jumps over the lazy dog. The quick brown fox jumps over the lazy dog.",
+		"sums.md5": "d41d8cd98f00b204e9800998ecf8427e  empty.txt\n00000000000000000000000000000000  msg.txt",
+		"pass.txt": "hunter2",
+		"msg.enc":  map[string]interface{}{"content": "53616c7465645f5f0001020304050607dff59a4540e3851ca9b69699877cd408", "encoding": "hex"},
+	}
+	setup := []string{
+		"touch empty.txt",
+		"md5sum msg.txt > good.md5",
+	}
+	runCommandTests(t, files, []commandTest{
+		{setup: setup, name: "base64", command: "base64 msg.txt", stdout: "YXR0YWNrIGF0IGRhd24K"},
+		{setup: setup, name: "base64 -w", command: "base64 -w 20 long.txt", stdout: "VGhlIHF1aWNrIGJyb3du\nIGZveCBqdW1wcyBvdmVy\nIHRoZSBsYXp5IGRvZy4g\nVGhlIHF1aWNrIGJyb3du\nIGZveCBqdW1wcyBvdmVy\nIHRoZSBsYXp5IGRvZy4K"},
+		{setup: setup, name: "base64 -w 0", command: "base64 -w 0 long.txt", stdout: "VGhlIHF1aWNrIGJyb3duIGZveCBqdW1wcyBvdmVyIHRoZSBsYXp5IGRvZy4gVGhlIHF1aWNrIGJyb3duIGZveCBqdW1wcyBvdmVyIHRoZSBsYXp5IGRvZy4K"},
+		{setup: setup, name: "base64 -d", command: "base64 -d b64.txt", stdout: "flag{base64}"},
+		{setup: setup, name: "base64 stdin", command: "cat msg.txt | base64", stdout: "YXR0YWNrIGF0IGRhd24K"},
+		{setup: setup, name: "base64 invalid", command: "base64 -d msg.txt", stdout: "j\xdbZr", stderr: "base64: invalid input", exitCode: 1},
+		{setup: setup, name: "md5sum", command: "md5sum msg.txt empty.txt", stdout: "02f9de7e6fa68c59732c066a5a5c76c2  msg.txt\nd41d8cd98f00b204e9800998ecf8427e  empty.txt"},
+		{setup: setup, name: "sha1sum", command: "sha1sum msg.txt", stdout: "d8e21fbaaeaefd1acb6923432bf50a2943850508  msg.txt"},
+		{setup: setup, name: "sha256sum", command: "sha256sum msg.txt", stdout: "4e8803396cacc79c25865cf06f9572380e0e081332332905c74a5a63e43d30eb  msg.txt"},
+		{setup: setup, name: "sha512sum stdin", command: "cat msg.txt | sha512sum", stdout: "b9387563a52267f53845d3459746b0a70de936f41c271f16a38de57f29c89cabcf3d9ea54495c75fe70a522ea2f6641111c3d8df6344ba4301e4040142102ae6  -"},
+		{setup: setup, name: "md5sum -c ok", command: "md5sum -c good.md5", stdout: "msg.txt: OK"},
+		{setup: setup, name: "md5sum -c bad", command: "md5sum -c sums.md5", stdout: "empty.txt: OK\nmsg.txt: FAILED", stderr: "md5sum: WARNING: 1 computed checksum did NOT match", exitCode: 1},
+		{setup: setup, name: "md5sum missing", command: "md5sum nope", stderr: "md5sum: nope: No such file or directory", exitCode: 1},
+		{setup: setup, name: "openssl dgst", command: "openssl dgst -sha256 msg.txt", stdout: "SHA2-256(msg.txt)= 4e8803396cacc79c25865cf06f9572380e0e081332332905c74a5a63e43d30eb"},
+		{setup: setup, name: "openssl md5", command: "openssl md5 msg.txt", stdout: "MD5(msg.txt)= 02f9de7e6fa68c59732c066a5a5c76c2"},
+		{setup: setup, name: "openssl base64", command: "openssl base64 -in long.txt", stdout: "VGhlIHF1aWNrIGJyb3duIGZveCBqdW1wcyBvdmVyIHRoZSBsYXp5IGRvZy4gVGhl\nIHF1aWNrIGJyb3duIGZveCBqdW1wcyBvdmVyIHRoZSBsYXp5IGRvZy4K"},
+		{setup: setup, name: "openssl base64 -d", command: "openssl base64 -d -in b64.txt", stdout: "flag{base64}"},
+		{setup: setup, name: "enc pbkdf2", command: "openssl enc -aes-256-cbc -pbkdf2 -pass pass:hunter2 -S 0102030405060708 -in msg.txt | xxd -p", stdout: "27ad41fcce9ed98b15ee9a74899afa51"},
+		{setup: setup, name: "enc -a", command: "openssl enc -aes-128-cbc -pbkdf2 -iter 1000 -k secret -S 0001020304050607 -a -in long.txt", stdout: "NpYr/tFCcM+HHzi2dxaiuYfI9YqBrqDD5t+6lApByYjr5M/8JgO4U4Ma8yH4b5+f\nIHUZuaHYhVQcnHB89RkiYm+AIJbw0v4ROs6/dxS98cusBNycjY6PudswsoBsXZ/S"},
+		{setup: setup, name: "enc legacy md5", command: "openssl enc -aes-256-cbc -md md5 -k secret -S 0001020304050607 -in msg.txt | base64", stdout: "ewZQjFzLYXwp8mGZZhk+Mg==", stderr: "*** WARNING : deprecated key derivation used.\nUsing -iter or -pbkdf2 would be better."},
+		{setup: setup, name: "enc -K -iv", command: "openssl enc -aes-128-cbc -K 000102030405060708090a0b0c0d0e0f -iv 0f0e0d0c0b0a09080706050403020100 -in msg.txt | xxd -p", stdout: "ed00cd3de5e1365e45158d997003181a"},
+		{setup: setup, name: "enc ecb", command: "openssl enc -aes-128-ecb -nosalt -K 000102030405060708090a0b0c0d0e0f -in msg.txt | xxd -p", stdout: "fd57809175f8f361a32afbec83eae023"},
+		{setup: setup, name: "enc ctr", command: "openssl aes-256-ctr -K 000102030405060708090a0b0c0d0e0f000102030405060708090a0b0c0d0e0f -iv 00000000000000000000000000000001 -in msg.txt | xxd -p", stdout: "83f6d455d8c87554cf39804a694d8b"},
+		{setup: setup, name: "enc -P", command: "openssl enc -aes-256-cbc -pbkdf2 -k secret -S 0001020304050607 -P", stdout: "salt=0001020304050607\nkey=5405E260909794EEFA989175E5695C5A082FD19968AED316DEE1688769B94ADF\niv =8DCDC66E9CCD154E5A6165ABC88AE8A5"},
+		{setup: setup, name: "dec", command: "openssl enc -d -aes-256-cbc -pbkdf2 -pass file:pass.txt -in msg.enc", stdout: "attack at dawn"},
+		{setup: setup, name: "dec -p", command: "openssl enc -d -aes-256-cbc -pbkdf2 -pass pass:hunter2 -p -in msg.enc", stdout: "salt=0001020304050607\nkey=633F4734EFB0F0D63D6FBEAB764122FBEC0F34FDE1DD1DD76925B6D1C22821E8\niv =FDF5D20B200BC08BE218DEA2C175D3F6\nattack at dawn"},
+		{setup: setup, name: "dec wrong password", command: "openssl enc -d -aes-256-cbc -pbkdf2 -pass pass:wrong -in msg.enc | wc -c", stdout: "0", stderr: "bad decrypt"},
+		{setup: setup, name: "unknown cipher", command: "openssl enc -rot13 -in msg.txt", stderr: "enc: Unknown cipher: rot13\nenc: Use -help for summary.", exitCode: 1},
+	})
+}
+
+func TestXor(t *testing.T) {
+	files := map[string]interface{}{
+		"msg.txt":  "attack at dawn",
+		"flag.txt": "flag{xor}",
+	}
+	setup := []string{"xor -k key msg.txt > msg.xor", "xor -x 42 flag.txt > flag.xor"}
+	runCommandTests(t, files, []commandTest{
+		{setup: setup, name: "text key", command: "xor -k at msg.txt | xxd -p", stdout: "00001515021f411515540515161a"},
+		{setup: setup, name: "round trip", command: "xor -k key msg.xor", stdout: "attack at dawn"},
+		{setup: setup, name: "hex key", command: "xor -x 0x2020 msg.txt | tr '\\000' _", stdout: "ATTACK_AT_DAWN"},
+		{setup: setup, name: "stdin", command: "cat msg.xor | xor --key key", stdout: "attack at dawn"},
+		{setup: setup, name: "brute force", command: "xor -b flag.xor | grep flag", stdout: "0x42: flag{xor}"},
+		{setup: setup, name: "no key", command: "xor msg.txt", stderr: "xor: a key is required: -k <text>, -x <hex> or -b to try every byte", exitCode: 1},
+		{setup: setup, name: "two keys", command: "xor -k a -x 61 msg.txt", stderr: "xor: use only one of -k, -x and -b", exitCode: 1},
+		{setup: setup, name: "bad hex key", command: "xor -x zz msg.txt", stderr: "xor: invalid hex key 'zz'", exitCode: 1},
+		{setup: setup, name: "missing file", command: "xor -k key nope", stderr: "xor: nope: No such file or directory", exitCode: 1},
+	})
+}
@@ -0,0 +1,554 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Points a challenge is worth in a CTF event without dynamic scoring
+const challengePoints = 100
+
+var (
+	ErrNoCTFEvent         = errors.New("no CTF event is configured")
+	ErrRegistrationClosed = errors.New("team registration is closed for this event")
+	ErrInvalidEventWindow = errors.New("event must end after it starts")
+	ErrNoEventChallenges  = errors.New("event needs at least one level pack")
+	ErrUnknownEventPack   = errors.New("unknown level pack")
+)
+
+// CTFConfig describes a Jeopardy-style CTF event: every level of the
+// enabled packs becomes a challenge that registered teams can attempt in
+// any order, submitting flags with `submit`
+type CTFConfig struct {
+	Name             string         `json:"name"`
+	StartsAt         time.Time      `json:"starts_at"` // Zero means already open
+	EndsAt           time.Time      `json:"ends_at"`   // Zero means no end
+	Packs            []string       `json:"packs"`
+	OpenRegistration bool           `json:"open_registration"` // Teams may register themselves
+	Teams            []string       `json:"teams"`             // Registered up front
+	Scoring          *ScoringConfig `json:"scoring,omitempty"` // Dynamic values; fixed points when nil
+}
+
+// CTFEvent is a running CTF event and its scores
+type CTFEvent struct {
+	Config     CTFConfig
+	Challenges []*Challenge
+	teams      map[string]*CTFTeam // By lower-case name
+	mu         sync.Mutex
+}
+
+type Challenge struct {
+	ID          string `json:"id"` // "<pack>/<level id>"
+	Pack        string `json:"pack"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Points      int    `json:"points"` // Current value
+	Solves      int    `json:"solves"`
+	FirstBlood  string `json:"first_blood,omitempty"` // Team that solved it first
+	level       *Level
+}
+
+type CTFTeam struct {
+	Name         string
+	Score        int
+	Solves       map[string]time.Time // Solve time by challenge ID
+	LastSolve    time.Time
+	RegisteredAt time.Time
+}
+
+// CTFInfo is the public view of the event for API clients
+type CTFInfo struct {
+	Name             string       `json:"name"`
+	State            string       `json:"state"` // "upcoming", "running" or "ended"
+	StartsAt         *time.Time   `json:"starts_at,omitempty"`
+	EndsAt           *time.Time   `json:"ends_at,omitempty"`
+	Packs            []string     `json:"packs"`
+	OpenRegistration bool         `json:"open_registration"`
+	Teams            int          `json:"teams"`
+	Challenges       []*Challenge `json:"challenges"`
+}
+
+type ScoreboardEntry struct {
+	Rank      int        `json:"rank"`
+	Team      string     `json:"team"`
+	Score     int        `json:"score"`
+	Solves    int        `json:"solves"`
+	LastSolve *time.Time `json:"last_solve,omitempty"`
+}
+
+// ConfigureCTF switches team play into CTF event mode, replacing any
+// event already configured
+func (e *GameEngine) ConfigureCTF(config CTFConfig) error {
+	if config.Name == "" {
+		config.Name = "CodeHeist CTF"
+	}
+	if !config.StartsAt.IsZero() && !config.EndsAt.IsZero() && !config.EndsAt.After(config.StartsAt) {
+		return ErrInvalidEventWindow
+	}
+	if len(config.Packs) == 0 {
+		return ErrNoEventChallenges
+	}
+	points := challengePoints
+	if config.Scoring != nil {
+		scoring := config.Scoring.withDefaults()
+		if err := scoring.validate(); err != nil {
+			return err
+		}
+		config.Scoring = &scoring
+		points = *scoring.Initial
+	}
+
+	event := &CTFEvent{
+		Config: config,
+		teams:  make(map[string]*CTFTeam),
+	}
+	for _, name := range config.Packs {
+		pack, exists := e.Packs[name]
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrUnknownEventPack, name)
+		}
+		for _, level := range pack.Levels {
+			event.Challenges = append(event.Challenges, &Challenge{
+				ID:          fmt.Sprintf("%s/%d", pack.Name, level.ID),
+				Pack:        pack.Name,
+				Title:       level.Title,
+				Description: level.Description,
+				Points:      points,
+				level:       level,
+			})
+		}
+	}
+	for _, name := range config.Teams {
+		if !teamNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: %q", ErrInvalidTeamName, name)
+		}
+		event.register(name)
+	}
+
+	e.ctfMu.Lock()
+	e.ctf = event
+	e.ctfMu.Unlock()
+
+	log.Printf("🚩 CTF event %s configured with %d challenges", config.Name, len(event.Challenges))
+	return nil
+}
+
+// LoadCTFConfig configures the event from a JSON file
+func (e *GameEngine) LoadCTFConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var config CTFConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return e.ConfigureCTF(config)
+}
+
+// StopCTF returns team play to the normal level progression
+func (e *GameEngine) StopCTF() {
+	e.ctfMu.Lock()
+	defer e.ctfMu.Unlock()
+	if e.ctf != nil {
+		log.Printf("🚩 CTF event %s stopped", e.ctf.Config.Name)
+	}
+	e.ctf = nil
+}
+
+func (e *GameEngine) currentCTF() *CTFEvent {
+	e.ctfMu.RLock()
+	defer e.ctfMu.RUnlock()
+	return e.ctf
+}
+
+// RegisterCTFTeam signs a team up for the event. Organizers may
+// register teams even when self-registration is closed.
+func (e *GameEngine) RegisterCTFTeam(teamName string, byOrganizer bool) error {
+	event := e.currentCTF()
+	if event == nil {
+		return ErrNoCTFEvent
+	}
+	if !teamNamePattern.MatchString(teamName) {
+		return ErrInvalidTeamName
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	if _, exists := event.teams[strings.ToLower(teamName)]; exists {
+		return nil
+	}
+	if !event.Config.OpenRegistration && !byOrganizer {
+		return ErrRegistrationClosed
+	}
+	event.register(teamName)
+	log.Printf("🚩 Team %s registered for %s", teamName, event.Config.Name)
+	return nil
+}
+
+// register adds a team; callers hold ev.mu or own the event
+func (ev *CTFEvent) register(teamName string) {
+	ev.teams[strings.ToLower(teamName)] = &CTFTeam{
+		Name:         teamName,
+		Solves:       make(map[string]time.Time),
+		RegisteredAt: time.Now(),
+	}
+}
+
+// CTFInfo describes the configured event
+func (e *GameEngine) CTFInfo() (CTFInfo, bool) {
+	event := e.currentCTF()
+	if event == nil {
+		return CTFInfo{}, false
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	info := CTFInfo{
+		Name:             event.Config.Name,
+		State:            event.state(time.Now()),
+		Packs:            event.Config.Packs,
+		OpenRegistration: event.Config.OpenRegistration,
+		Teams:            len(event.teams),
+	}
+	if !event.Config.StartsAt.IsZero() {
+		info.StartsAt = &event.Config.StartsAt
+	}
+	if !event.Config.EndsAt.IsZero() {
+		info.EndsAt = &event.Config.EndsAt
+	}
+	for _, challenge := range event.Challenges {
+		copied := *challenge
+		info.Challenges = append(info.Challenges, &copied)
+	}
+	return info, true
+}
+
+// Scoreboard ranks registered teams by score, ties going to whoever got
+// there first
+func (e *GameEngine) Scoreboard() []ScoreboardEntry {
+	event := e.currentCTF()
+	if event == nil {
+		return nil
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+	return event.scoreboard()
+}
+
+// scoreboard ranks the teams; callers hold ev.mu
+func (ev *CTFEvent) scoreboard() []ScoreboardEntry {
+	teams := make([]*CTFTeam, 0, len(ev.teams))
+	for _, team := range ev.teams {
+		teams = append(teams, team)
+	}
+	sort.Slice(teams, func(i, j int) bool {
+		a, b := teams[i], teams[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.LastSolve.Equal(b.LastSolve) {
+			return a.LastSolve.Before(b.LastSolve)
+		}
+		return a.Name < b.Name
+	})
+
+	entries := make([]ScoreboardEntry, 0, len(teams))
+	for i, team := range teams {
+		entry := ScoreboardEntry{
+			Rank:   i + 1,
+			Team:   team.Name,
+			Score:  team.Score,
+			Solves: len(team.Solves),
+		}
+		if !team.LastSolve.IsZero() {
+			lastSolve := team.LastSolve
+			entry.LastSolve = &lastSolve
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// CTFSessions returns the sessions of registered teams that are playing
+func (e *GameEngine) CTFSessions() []string {
+	event := e.currentCTF()
+	if event == nil {
+		return nil
+	}
+
+	event.mu.Lock()
+	var names []string
+	for _, team := range event.teams {
+		names = append(names, team.Name)
+	}
+	event.mu.Unlock()
+
+	var sessionIDs []string
+	for _, name := range names {
+		if team, exists := e.GetTeam(name); exists {
+			sessionIDs = append(sessionIDs, team.SessionID)
+		}
+	}
+	return sessionIDs
+}
+
+func (ev *CTFEvent) state(now time.Time) string {
+	switch {
+	case !ev.Config.StartsAt.IsZero() && now.Before(ev.Config.StartsAt):
+		return "upcoming"
+	case !ev.Config.EndsAt.IsZero() && !now.Before(ev.Config.EndsAt):
+		return "ended"
+	}
+	return "running"
+}
+
+// challenge finds a challenge by ID, or by level number alone when a
+// single pack is enabled
+func (ev *CTFEvent) challenge(id string) *Challenge {
+	for _, challenge := range ev.Challenges {
+		if challenge.ID == id {
+			return challenge
+		}
+	}
+	if len(ev.Config.Packs) == 1 && id != "" {
+		return ev.challenge(ev.Config.Packs[0] + "/" + id)
+	}
+	return nil
+}
+
+// ctfFor returns the event and team a session plays for, if any
+func (e *GameEngine) ctfFor(s *Session) (*CTFEvent, *CTFTeam) {
+	if s.TeamName == "" {
+		return nil, nil
+	}
+	event := e.currentCTF()
+	if event == nil {
+		return nil, nil
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+	team, exists := event.teams[strings.ToLower(s.TeamName)]
+	if !exists {
+		return nil, nil
+	}
+	return event, team
+}
+
+// ctfGate rejects play outside the event window
+func (e *GameEngine) ctfGate(s *Session, command string) string {
+	event, _ := e.ctfFor(s)
+	if event == nil {
+		return ""
+	}
+	return event.gate(command)
+}
+
+// gate explains why a command can't run now, or returns "" when it can.
+// The scoreboard stays open before and after the event.
+func (ev *CTFEvent) gate(command string) string {
+	if strings.TrimSpace(command) == "scoreboard" {
+		return ""
+	}
+
+	switch ev.state(time.Now()) {
+	case "upcoming":
+		return fmt.Sprintf("event: %s starts at %s", ev.Config.Name, ev.Config.StartsAt.Format(time.RFC1123))
+	case "ended":
+		return fmt.Sprintf("event: %s has ended, see `scoreboard` for the results", ev.Config.Name)
+	}
+	return ""
+}
+
+// executeCTFCommand runs a command against the session's selected
+// challenge. Finding a flag doesn't complete anything; teams `submit` it.
+func (e *GameEngine) executeCTFCommand(s *Session, event *CTFEvent, command string, tty bool) *CommandResponse {
+	// Checked here too, so no path into a challenge skips the window
+	if message := event.gate(command); message != "" {
+		s.login = nil
+		return &CommandResponse{Stderr: message, ExitCode: 1}
+	}
+
+	challenge := event.challenge(s.Challenge)
+	if challenge == nil {
+		// First command of the event, or the event changed underneath us
+		challenge = event.Challenges[0]
+		s.Challenge = challenge.ID
+		e.loadLevelFiles(s, challenge.level)
+	}
+
+	result, _ := e.processCommand(command, s, challenge.level, tty)
+	return &CommandResponse{
+		Stdout:   result.stdout,
+		Stderr:   result.stderr,
+		ExitCode: result.exitCode,
+	}
+}
+
+// ctfStatus renders the `status` command output for a CTF session
+func ctfStatus(s *Session, event *CTFEvent, team *CTFTeam) string {
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	challenge := event.challenge(s.Challenge)
+	if challenge == nil {
+		challenge = event.Challenges[0]
+	}
+	return fmt.Sprintf(`
+Challenge: %s
+User: %s
+Challenge Title: %s
+Description: %s
+Progress: %d/%d challenges solved
+%s`,
+		challenge.ID,
+		s.User,
+		challenge.Title,
+		challenge.Description,
+		len(team.Solves),
+		len(event.Challenges),
+		timeLeft(s))
+}
+
+// listChallenges renders the `challenges` command output
+func (e *GameEngine) listChallenges(s *Session) commandResult {
+	event, team := e.ctfFor(s)
+	if event == nil {
+		return errorResult("challenges: no CTF event running for this session", 1)
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	var list strings.Builder
+	fmt.Fprintf(&list, "🚩 %s - %d challenges\n\n", event.Config.Name, len(event.Challenges))
+	for _, challenge := range event.Challenges {
+		mark := " "
+		if _, solved := team.Solves[challenge.ID]; solved {
+			mark = "✓"
+		}
+		current := " "
+		if challenge.ID == s.Challenge {
+			current = "*"
+		}
+		fmt.Fprintf(&list, "%s[%s] %-12s %4d pts %3d solves  %s\n", current, mark, challenge.ID, challenge.Points, challenge.Solves, challenge.Title)
+	}
+	list.WriteString("\nUse 'challenge <id>' to switch and 'submit <flag>' to score.")
+	return stdoutResult(list.String())
+}
+
+// selectChallenge switches the session to another challenge's filesystem
+func (e *GameEngine) selectChallenge(s *Session, args []string) commandResult {
+	event, _ := e.ctfFor(s)
+	if event == nil {
+		return errorResult("challenge: no CTF event running for this session", 1)
+	}
+	if len(args) == 0 {
+		return errorResult("challenge: missing challenge id (see 'challenges')", 1)
+	}
+
+	challenge := event.challenge(args[0])
+	if challenge == nil {
+		return errorResult("challenge: "+args[0]+": no such challenge", 1)
+	}
+
+	s.Challenge = challenge.ID
+	e.loadLevelFiles(s, challenge.level)
+	return stdoutResult(fmt.Sprintf("🚩 %s: %s\n%s", challenge.ID, challenge.Title, challenge.level.WelcomeMsg))
+}
+
+// submitFlag checks a flag against the selected challenge and scores it
+func (e *GameEngine) submitFlag(s *Session, args []string) commandResult {
+	event, team := e.ctfFor(s)
+	if event == nil {
+		return errorResult("submit: no CTF event running for this session", 1)
+	}
+	if len(args) == 0 {
+		return errorResult("submit: missing flag", 1)
+	}
+
+	challenge := event.challenge(s.Challenge)
+	if challenge == nil {
+		return errorResult("submit: select a challenge first (see 'challenges')", 1)
+	}
+	if args[0] != challenge.level.Solution {
+		return errorResult("submit: incorrect flag for "+challenge.ID, 1)
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	if _, solved := team.Solves[challenge.ID]; solved {
+		return errorResult("submit: "+team.Name+" already solved "+challenge.ID, 1)
+	}
+
+	now := time.Now()
+	team.Solves[challenge.ID] = now
+	team.LastSolve = now
+	challenge.Solves++
+	firstBlood := challenge.FirstBlood == ""
+	if firstBlood {
+		challenge.FirstBlood = team.Name
+	}
+	event.rescore()
+
+	message := fmt.Sprintf("%s solved %s (now worth %d)", team.Name, challenge.ID, challenge.Points)
+	log.Printf("🚩 %s", message)
+	s.Recording.add("m", "Solved "+challenge.ID)
+	s.events = append(s.events, Event{
+		Type:      EventFlagSolved,
+		SessionID: s.ID,
+		Group:     team.Name,
+		Message:   message,
+		Time:      now,
+	})
+
+	output := fmt.Sprintf("🚩 Correct! %s is now worth %d points. %s has %d points.",
+		challenge.ID, challenge.Points, team.Name, team.Score)
+	if firstBlood {
+		bloodMessage := fmt.Sprintf("First blood! %s was the first to solve %s", team.Name, challenge.ID)
+		if event.Config.Scoring != nil && event.Config.Scoring.FirstBlood > 0 {
+			bloodMessage += fmt.Sprintf(" (+%d bonus)", event.Config.Scoring.FirstBlood)
+		}
+		output += "\n🩸 " + bloodMessage
+		s.events = append(s.events, Event{
+			Type:      EventFirstBlood,
+			SessionID: s.ID,
+			Group:     team.Name,
+			Message:   bloodMessage,
+			Time:      now,
+		})
+	}
+	return stdoutResult(output)
+}
+
+// scoreboardTable renders the `scoreboard` command output
+func (e *GameEngine) scoreboardTable() commandResult {
+	event := e.currentCTF()
+	if event == nil {
+		return errorResult("scoreboard: no CTF event running", 1)
+	}
+
+	event.mu.Lock()
+	defer event.mu.Unlock()
+
+	var table strings.Builder
+	fmt.Fprintf(&table, "🏆 %s (%s)\n\n", event.Config.Name, event.state(time.Now()))
+	fmt.Fprintf(&table, "  %-4s %-20s %6s %6s\n", "#", "Team", "Score", "Solves")
+	for _, entry := range event.scoreboard() {
+		fmt.Fprintf(&table, "  %-4d %-20s %6d %6d\n", entry.Rank, entry.Team, entry.Score, entry.Solves)
+	}
+	return stdoutResult(strings.TrimRight(table.String(), "\n"))
+}
@@ -1,8 +1,10 @@
 package game
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -12,62 +14,212 @@ import (
 
 type GameEngine struct {
 	Sessions sync.Map
+	Teams    sync.Map // *Team by lower-case name
+	Races    sync.Map // *Race by lower-case name
+	Players  sync.Map // Named *Player by lower-case name
 	Levels   map[int]*Level
+	Packs    map[string]*LevelPack // Level sets a CTF event can enable
+
+	ctf   *CTFEvent // Running CTF event, nil outside event mode
+	ctfMu sync.RWMutex
+
+	subscribers []func(Event)
+	subMu       sync.RWMutex
 }
 
 type Session struct {
 	ID           string
+	Token        string // Secret for resuming the session from other frontends
 	CurrentLevel int
 	VirtualFS    *VirtualFileSystem
 	User         string
 	CreatedAt    time.Time
 	IPAddress    string
 	LastActivity time.Time
-	CurrentInput string
-	mu           sync.Mutex // Add mutex for CurrentInput safety
+	LastExitCode int        // Exit status of the last command, exposed as $?
+	Cols         int        // Terminal width reported by the client
+	Rows         int        // Terminal height reported by the client
+	Pager        *Pager     // Open less/more pager, nil when at the shell
+	Recording    *Recording // Timestamped terminal frames for replay
+	TeamName     string     // Set when the session is shared by a team
+	RaceName     string     // Set when the session takes part in a race
+	Challenge    string     // Selected CTF challenge in event mode
+	Speedrun     *Speedrun  // Run in progress, nil when not speedrunning
+
+	stats           playStats
+	player          *Player                 // Keeps achievements and speedrun bests
+	pristine        *vfsSnapshot            // Filesystem as the level started
+	snapshots       map[string]*vfsSnapshot // Saved with `snapshot save`
+	timer           *levelTimer             // Clock of a level with a time limit
+	failedLevel     *Level                  // Level whose time ran out with ExpiryFail
+	events          []Event                 // Published once the command finishes
+	newAchievements []UnlockedAchievement   // Unlocked by the running command
+	hops            []sshHop                // Shells left by ssh, the latest last
+	login           *sshLogin               // An ssh waiting for its password
+	mu              sync.Mutex              // Guards session state shared between frontends
 }
 
 type Level struct {
-	ID          int
-	Title       string
-	Description string
-	Filesystem  map[string]interface{}
-	Solution    string
-	Hint        string
-	WelcomeMsg  string
+	ID          int                    `json:"id"`
+	Title       string                 `json:"title"`
+	Description string                 `json:"description"`
+	Filesystem  map[string]interface{} `json:"filesystem"`
+	Solution    string                 `json:"solution"`
+	Hint        string                 `json:"hint"`
+	WelcomeMsg  string                 `json:"welcome"`
+	TimeLimit   int                    `json:"time_limit,omitempty"` // Seconds to clear the level, 0 for no limit
+	Expiry      string                 `json:"expiry,omitempty"`     // ExpiryReset (default) or ExpiryFail
+	Objective   *FileObjective         `json:"objective,omitempty"`  // Filesystem state that also clears the level
+	Processes   []LevelProcess         `json:"processes,omitempty"`  // Running when the level starts, besides the shell
+	Services    []LevelService         `json:"services,omitempty"`   // Listening on the level's ports, besides ssh
+	Hostname    string                 `json:"hostname,omitempty"`   // The player's machine, defaultHostname if empty
+	Hosts       []LevelHost            `json:"hosts,omitempty"`      // Other machines on the level's network
 }
 
 type CommandResponse struct {
-	Output         string
-	LevelCompleted bool
-	NewLevel       int
+	Stdout         string `json:"stdout"`
+	Stderr         string `json:"stderr"`
+	ExitCode       int    `json:"exit_code"`
+	LevelCompleted bool   `json:"level_completed"`
+	NewLevel       int    `json:"new_level,omitempty"`
+	Paging         bool   `json:"paging,omitempty"`   // A pager is open and waiting for keystrokes
+	Password       bool   `json:"password,omitempty"` // ssh is asking for a password; don't echo the next line
+
+	Achievements []UnlockedAchievement `json:"achievements,omitempty"` // Unlocked by this command
+}
+
+// commandResult holds the streams produced by a single command
+type commandResult struct {
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// stdoutResult is a successful command that only writes to stdout
+func stdoutResult(output string) commandResult {
+	return commandResult{stdout: output}
+}
+
+// errorResult is a failed command that writes a message to stderr
+func errorResult(message string, exitCode int) commandResult {
+	return commandResult{stderr: message, exitCode: exitCode}
+}
+
+// fail adds an error line for commands that keep going after one operand fails
+func (r *commandResult) fail(message string, exitCode int) {
+	if r.stderr != "" {
+		r.stderr += "\n"
+	}
+	r.stderr += message
+	r.exitCode = exitCode
 }
 
 func NewEngine() *GameEngine {
 	engine := &GameEngine{
 		Levels: initializeLevels(),
 	}
+	engine.Packs = map[string]*LevelPack{
+		builtinPack: builtinLevelPack(engine.Levels),
+	}
+	engine.Subscribe(engine.trackRace)
+	engine.Subscribe(engine.checkAchievements)
 	return engine
 }
 
 func (e *GameEngine) CreateSession(ip string) *Session {
+	return e.CreateSessionAtLevel(ip, 0)
+}
+
+// CreateSessionAtLevel starts a session directly at a level, used when a
+// player logs in with a password found on another frontend
+func (e *GameEngine) CreateSessionAtLevel(ip string, level int) *Session {
 	session := &Session{
 		ID:           uuid.New().String(),
-		CurrentLevel: 0,
-		VirtualFS:    NewVirtualFS(),
-		User:         "codeheist0",
+		Token:        newSessionToken(),
+		CurrentLevel: level,
+		VirtualFS:    NewVirtualFS(fmt.Sprintf("codeheist%d", level)),
+		User:         fmt.Sprintf("codeheist%d", level),
 		CreatedAt:    time.Now(),
 		IPAddress:    ip,
 		LastActivity: time.Now(),
+		Cols:         DefaultTerminalCols,
+		Rows:         DefaultTerminalRows,
+		Recording:    newRecording(DefaultTerminalCols, DefaultTerminalRows),
+		stats:        playStats{skippedLevels: level > 0},
+		player:       &Player{},
 	}
 
-	e.initializeLevelFilesystem(session, 0)
+	e.initializeLevelFilesystem(session, level)
 	e.Sessions.Store(session.ID, session)
 
 	log.Printf("🆕 New session created: %s for %s", session.ID, ip)
 	return session
 }
 
+// LevelPassword returns the password that unlocks a level: the solution
+// of the previous level, or the user name itself for level 0 like bandit
+func (e *GameEngine) LevelPassword(level int) string {
+	if level == 0 {
+		return "codeheist0"
+	}
+	previous, exists := e.Levels[level-1]
+	if !exists {
+		return ""
+	}
+	return previous.Solution
+}
+
+// AuthenticateLevel checks a codeheistN login and returns the level it unlocks
+func (e *GameEngine) AuthenticateLevel(user, password string) (int, bool) {
+	var level int
+	if _, err := fmt.Sscanf(user, "codeheist%d", &level); err != nil {
+		return 0, false
+	}
+	if fmt.Sprintf("codeheist%d", level) != user || level < 0 || level >= len(e.Levels) {
+		return 0, false
+	}
+
+	expected := e.LevelPassword(level)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+		return 0, false
+	}
+	return level, true
+}
+
+// Login moves an existing session to the level unlocked by a codeheistN password
+func (e *GameEngine) Login(sessionID, user, password string) bool {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return false
+	}
+
+	level, ok := e.AuthenticateLevel(user, password)
+	if !ok {
+		return false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	// Racers must clear every level themselves
+	if session.RaceName != "" {
+		return false
+	}
+
+	// Jumping levels ends a speedrun, its splits would be meaningless
+	if session.Speedrun != nil {
+		session.Speedrun = nil
+		session.Recording.add("m", "Speedrun abandoned")
+		log.Printf("⏱️ Session %s abandoned its speedrun by logging in", sessionID)
+	}
+
+	session.CurrentLevel = level
+	session.stats = playStats{skippedLevels: session.stats.skippedLevels || level > 0}
+	e.initializeLevelFilesystem(session, level)
+	log.Printf("🔑 Session %s logged in as %s", sessionID, user)
+	return true
+}
+
 func (e *GameEngine) GetSession(sessionID string) (*Session, bool) {
 	session, exists := e.Sessions.Load(sessionID)
 	if !exists {
@@ -76,65 +228,298 @@ func (e *GameEngine) GetSession(sessionID string) (*Session, bool) {
 	return session.(*Session), true
 }
 
+// EndSession stops a session's clock and forgets it
+func (e *GameEngine) EndSession(sessionID string) {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return
+	}
+	session.mu.Lock()
+	e.stopLevelTimer(session)
+	session.mu.Unlock()
+	e.Sessions.Delete(sessionID)
+}
+
 func (e *GameEngine) ExecuteCommand(sessionID, command string) *CommandResponse {
+	return e.execute(sessionID, command, true)
+}
+
+// ExecuteBatchCommand runs a command without a terminal attached, for
+// API clients and `ssh host cmd`: pagers print the whole file and ls
+// prints one name per line
+func (e *GameEngine) ExecuteBatchCommand(sessionID, command string) *CommandResponse {
+	return e.execute(sessionID, command, false)
+}
+
+func (e *GameEngine) execute(sessionID, command string, tty bool) *CommandResponse {
 	session, exists := e.Sessions.Load(sessionID)
 	if !exists {
-		return &CommandResponse{Output: "Session not found"}
+		return &CommandResponse{Stderr: "Session not found", ExitCode: 1}
 	}
 
 	s := session.(*Session)
+	s.mu.Lock()
+
 	s.LastActivity = time.Now()
 
-	// Handle special commands
-	switch strings.TrimSpace(command) {
-	case "clear":
-		return &CommandResponse{Output: "\033[H\033[2J"}
-	case "help":
-		return e.showHelp()
-	case "levels":
-		return e.showLevels()
-	case "whoami":
-		return &CommandResponse{Output: s.User}
-	case "pwd":
-		return &CommandResponse{Output: "/home/" + s.User}
+	var response *CommandResponse
+	if s.login != nil {
+		// A line typed at an ssh password prompt isn't a command
+		response = e.executeCommand(s, command, tty)
+	} else if message := e.raceGate(s, command); message != "" {
+		response = &CommandResponse{Stderr: message, ExitCode: 1}
+	} else if message := e.ctfGate(s, command); message != "" {
+		response = &CommandResponse{Stderr: message, ExitCode: 1}
+	} else {
+		s.stats.levelCommands++
+		s.events = append(s.events, Event{
+			Type:      EventCommandRun,
+			SessionID: s.ID,
+			Message:   command,
+			Time:      time.Now(),
+		})
+		response = e.executeCommand(s, command, tty)
+	}
+	response.Paging = s.Pager != nil
+	response.Password = s.login != nil
+	s.LastExitCode = response.ExitCode
+
+	events := s.events
+	s.events = nil
+	s.mu.Unlock()
+
+	e.emit(events...)
+	response.Achievements = e.takeAchievements(s)
+	return response
+}
+
+func (e *GameEngine) executeCommand(s *Session, command string, tty bool) *CommandResponse {
+	// At an ssh password prompt even "help" is a password
+	if s.login == nil {
+		if response := e.specialCommand(s, command); response != nil {
+			return response
+		}
+	}
+	if s.failedLevel != nil {
+		s.login = nil
+		return &CommandResponse{Stderr: fmt.Sprintf("Level %d failed: time ran out. Type 'retry' to try again.", s.failedLevel.ID), ExitCode: 1}
+	}
+
+	// CTF events replace the level progression with challenges
+	if event, _ := e.ctfFor(s); event != nil {
+		return e.executeCTFCommand(s, event, command, tty)
 	}
 
 	// CHECK: Jika level tidak ada, berarti game completed!
 	if s.CurrentLevel >= len(e.Levels) {
 		return &CommandResponse{
-			Output: "\n🎉 CONGRATULATIONS! You've completed all levels!\n" +
+			Stdout: "\n🎉 CONGRATULATIONS! You've completed all levels!\n" +
 				"🏆 You are now a CodeHeist Master!\n\n" +
 				"Thank you for playing! 🚀",
 		}
 	}
 
 	level := e.Levels[s.CurrentLevel]
-	output, levelCompleted := e.processCommand(command, s, level)
+	result, levelCompleted := e.processCommand(command, s, level, tty)
 
 	if levelCompleted {
+		result.stdout += "\n\n" + levelCompletionMessage(level.ID)
 		oldLevel := s.CurrentLevel
 		s.CurrentLevel++
 
 		// CHECK: Jika masih ada level berikutnya, initialize filesystem
 		if s.CurrentLevel < len(e.Levels) {
 			e.initializeLevelFilesystem(s, s.CurrentLevel)
+		} else {
+			e.stopLevelTimer(s)
+		}
+		if split := e.recordSplit(s, oldLevel); split != "" {
+			result.stdout += "\n" + split
 		}
 
-		log.Printf("🎉 Session %s completed level %d", sessionID, oldLevel)
+		log.Printf("🎉 Session %s completed level %d", s.ID, oldLevel)
+		s.Recording.add("m", fmt.Sprintf("Level %d completed", oldLevel))
+		s.stats.levelCleared()
+		s.events = append(s.events, Event{
+			Type:      EventLevelCompleted,
+			SessionID: s.ID,
+			Level:     oldLevel,
+			Time:      time.Now(),
+		})
 
 		return &CommandResponse{
-			Output:         output,
+			Stdout:         result.stdout,
+			Stderr:         result.stderr,
+			ExitCode:       result.exitCode,
 			LevelCompleted: true,
 			NewLevel:       s.CurrentLevel,
 		}
 	}
 
 	return &CommandResponse{
-		Output:         output,
+		Stdout:         result.stdout,
+		Stderr:         result.stderr,
+		ExitCode:       result.exitCode,
 		LevelCompleted: false,
 	}
 }
 
+// specialCommand handles the commands that act on the session rather
+// than the level, or returns nil
+func (e *GameEngine) specialCommand(s *Session, command string) *CommandResponse {
+	switch strings.TrimSpace(command) {
+	case "clear":
+		return &CommandResponse{Stdout: "\033[H\033[2J"}
+	case "help":
+		return e.showHelp()
+	case "levels":
+		return e.showLevels()
+	case "race":
+		result := e.raceStatus(s)
+		return &CommandResponse{Stdout: result.stdout, Stderr: result.stderr, ExitCode: result.exitCode}
+	case "achievements":
+		return &CommandResponse{Stdout: listAchievements(s).stdout}
+	case "retry":
+		return e.retryLevel(s)
+	}
+
+	if fields := strings.Fields(command); len(fields) > 0 && fields[0] == "speedrun" {
+		return e.speedrunCommand(s, fields[1:])
+	}
+	return nil
+}
+
+// expandSpecialParams substitutes $? (the last exit status), $$ (the
+// shell's PID) and $! (the last background job's), leaving
+// single-quoted and backslash-escaped text untouched like a real shell
+func expandSpecialParams(input string, params map[byte]string) string {
+	var result strings.Builder
+	inSingleQuotes, inDoubleQuotes := false, false
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == '\\' && !inSingleQuotes && i+1 < len(input):
+			// Keep the escape for the tokenizer, but never expand "\$?"
+			result.WriteByte(c)
+			result.WriteByte(input[i+1])
+			i++
+			continue
+		case c == '\'' && !inDoubleQuotes:
+			inSingleQuotes = !inSingleQuotes
+		case c == '"' && !inSingleQuotes:
+			inDoubleQuotes = !inDoubleQuotes
+		}
+		if c == '$' && !inSingleQuotes && i+1 < len(input) {
+			if value, special := params[input[i+1]]; special {
+				result.WriteString(value)
+				i++
+				continue
+			}
+		}
+		result.WriteByte(c)
+	}
+
+	return result.String()
+}
+
+// listCommand implements ls with -a and -1, printing columns sized to the terminal
+func listCommand(session *Session, args []string, tty bool) commandResult {
+	showAll := false
+	onePerLine := !tty
+	var paths []string
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && len(arg) > 1 {
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'a':
+					showAll = true
+				case '1':
+					onePerLine = true
+				case 'C':
+					onePerLine = false
+				default:
+					return errorResult(fmt.Sprintf("ls: invalid option -- '%c'", flag), 2)
+				}
+			}
+		} else {
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	format := func(names []string) string {
+		if onePerLine {
+			return strings.Join(names, "\n")
+		}
+		return formatColumns(names, session.Cols)
+	}
+
+	// Files named on the command line come first, then each directory
+	var result commandResult
+	var files, dirs, sections []string
+	for _, path := range paths {
+		_, node, err := session.VirtualFS.stat(path)
+		switch {
+		case err != nil:
+			result.fail("ls: cannot access '"+path+"': "+errorText(err), 2)
+		case node.isDir():
+			dirs = append(dirs, path)
+		default:
+			files = append(files, path)
+		}
+	}
+	if len(files) > 0 {
+		sections = append(sections, format(files))
+	}
+	for _, dir := range dirs {
+		names, err := session.VirtualFS.ListNames(dir, showAll)
+		if err != nil {
+			result.fail("ls: cannot open directory '"+dir+"': "+errorText(err), 2)
+			continue
+		}
+		if len(paths) > 1 {
+			sections = append(sections, dir+":\n"+format(names))
+		} else {
+			sections = append(sections, format(names))
+		}
+	}
+
+	result.stdout = strings.Join(sections, "\n\n")
+	return result
+}
+
+// pagerCommand opens less or more on a file, printing it directly if it fits the screen
+func pagerCommand(session *Session, name string, args []string, stdin *string, tty bool) commandResult {
+	if len(args) == 0 && stdin != nil {
+		args = []string{"-"}
+	}
+	if len(args) == 0 {
+		if name == "less" {
+			return errorResult("Missing filename (\"less --help\" for help)", 1)
+		}
+		return errorResult("more: bad usage", 1)
+	}
+
+	var result commandResult
+	inputs := readInputs(session.VirtualFS, args[:1], stdin, name+": %s: %s", &result)
+	if len(inputs) == 0 {
+		return result
+	}
+	content := inputs[0].content
+
+	pager := newPager(name, content, session.Cols, session.Rows)
+	if !tty || pager.fitsScreen() {
+		return stdoutResult(content)
+	}
+
+	session.Pager = pager
+	return stdoutResult(pager.open())
+}
+
 // parseCommandWithQuotes handles command parsing with proper quote support
 func parseCommandWithQuotes(input string) []string {
 	var args []string
@@ -164,6 +549,14 @@ func parseCommandWithQuotes(input string) []string {
 				args = append(args, current.String())
 				current.Reset()
 			}
+		case c == '\\' && !inQuotes && i+1 < len(input):
+			// An unquoted backslash escapes the next character, as in \;
+			i++
+			current.WriteByte(input[i])
+		case c == '\\' && inQuotes && quoteChar == '"' && i+1 < len(input) && (input[i+1] == '$' || input[i+1] == '\\'):
+			// Within double quotes only \$ and \\ are escapes
+			i++
+			current.WriteByte(input[i])
 		default:
 			current.WriteByte(c)
 		}
@@ -177,184 +570,588 @@ func parseCommandWithQuotes(input string) []string {
 	return args
 }
 
-func (e *GameEngine) processCommand(cmd string, session *Session, level *Level) (string, bool) {
-	cmd = strings.TrimSpace(cmd)
+// splitPipeline splits a command line on each unquoted | (but not ||)
+func splitPipeline(input string) []string {
+	var stages []string
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '|':
+			if i+1 < len(input) && input[i+1] == '|' {
+				i++
+				continue
+			}
+			stages = append(stages, input[start:i])
+			start = i + 1
+		}
+	}
+	return append(stages, input[start:])
+}
+
+func (e *GameEngine) processCommand(cmd string, session *Session, level *Level, tty bool) (commandResult, bool) {
+	if session.login != nil {
+		return e.enterPassword(session, cmd)
+	}
+
+	lastJob := ""
+	if pid := session.VirtualFS.procs.lastJob; pid != 0 {
+		lastJob = strconv.Itoa(pid)
+	}
+	cmd = strings.TrimSpace(expandSpecialParams(cmd, map[byte]string{
+		'?': strconv.Itoa(session.LastExitCode), '$': strconv.Itoa(shellPID), '!': lastJob}))
+
+	// Background jobs that finished are reported after the command, as
+	// bash does before its next prompt
+	notices := session.VirtualFS.reapJobs()
+	result, completed := e.runLine(cmd, session, level, tty)
+	if len(notices) > 0 {
+		result.stdout = strings.Join(append([]string{result.stdout}, notices...), "\n")
+		result.stdout = strings.TrimPrefix(result.stdout, "\n")
+	}
+	return result, completed
+}
+
+// runLine runs an expanded command line: a pipeline, or with a
+// trailing & a background job
+func (e *GameEngine) runLine(cmd string, session *Session, level *Level, tty bool) (commandResult, bool) {
+	if line, background := splitBackground(cmd); background {
+		return e.startJob(line, session, level)
+	}
+
+	stages := splitPipeline(cmd)
+	if len(stages) == 1 && strings.TrimSpace(stages[0]) == "" {
+		return commandResult{exitCode: session.LastExitCode}, false
+	}
+
+	// Variables to store command result and completion status
+	var result commandResult
+	completed := false
+	vfs, changes := session.VirtualFS, session.VirtualFS.changes
+
+	// Each stage of a pipeline reads the previous stage's stdout; only the
+	// last one writes to the terminal
+	var stdin *string
+	var stderr []string
+	for i, stage := range stages {
+		// Use the new quotes-aware parser instead of strings.Fields
+		stage, redirect, ok := splitRedirections(stage)
+		if !ok {
+			return errorResult("syntax error near unexpected token `newline'", 2), false
+		}
+		parts := parseCommandWithQuotes(stage)
+		if len(parts) == 0 && redirect.stdout != "" {
+			// A bare > file empties the file
+			result = commandResult{}
+			redirect.apply(session.VirtualFS, &result)
+			stdin = &result.stdout
+			continue
+		}
+		if len(parts) == 0 {
+			return errorResult("syntax error near unexpected token `|'", 2), false
+		}
+		last := i == len(stages)-1
+		result, completed = e.runCommand(parts[0], parts[1:], stdin, session, level, tty && last && redirect.stdout == "")
+		redirect.apply(session.VirtualFS, &result)
+		if result.stderr != "" {
+			stderr = append(stderr, result.stderr)
+		}
+		output := result.stdout
+		stdin = &output
+	}
+	result.stderr = strings.Join(stderr, "\n")
+
+	// FINAL CHECK: Only complete if we haven't already marked as completed
+	// AND stdout exactly matches the solution (to prevent false positives)
+	if !completed {
+		cleanOutput := strings.TrimSpace(result.stdout)
+		if cleanOutput == level.Solution {
+			completed = true
+		}
+	}
+
+	// Levels with a file objective clear once a command leaves the
+	// filesystem in the requested state
+	if !completed && level.Objective != nil && session.VirtualFS == vfs && vfs.changes != changes && level.Objective.met(vfs) {
+		completed = true
+		if result.stdout != "" {
+			result.stdout += "\n"
+		}
+		result.stdout += "🔓 Objective complete! The password is " + level.Solution
+	}
+
+	return result, completed
+}
+
+// redirections are where one pipeline stage's output goes: files for
+// > and 2> ("/dev/null" discards), or stderr merged into stdout by 2>&1
+type redirections struct {
+	stdout, stderr       string
+	appendOut, appendErr bool
+	merge                bool
+}
+
+// splitRedirections strips a stage's unquoted redirections (> file,
+// >> file, 2> file, &> file, 2>&1), returning the rest of the stage. It
+// fails when one is missing its file.
+func splitRedirections(stage string) (string, redirections, bool) {
+	var redirect redirections
+	var words strings.Builder
+	var quote byte
+	for i := 0; i < len(stage); i++ {
+		c := stage[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\\' && i+1 < len(stage):
+			words.WriteByte(c)
+			i++
+			c = stage[i]
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '>':
+			// A 1, 2 or & starting the word picks the stream
+			stream := byte('1')
+			text := words.String()
+			if n := len(text); n > 0 && strings.IndexByte("12&", text[n-1]) >= 0 && (n == 1 || text[n-2] == ' ') {
+				stream = text[n-1]
+				words.Reset()
+				words.WriteString(text[:n-1])
+			}
+			appending := strings.HasPrefix(stage[i+1:], ">")
+			if appending {
+				i++
+			}
+			if stream == '2' && strings.HasPrefix(stage[i+1:], "&1") {
+				redirect.merge = true
+				i += 2
+				continue
+			}
 
-	// Use the new quotes-aware parser instead of strings.Fields
-	parts := parseCommandWithQuotes(cmd)
+			// The file is the next word
+			start := i + 1
+			for start < len(stage) && stage[start] == ' ' {
+				start++
+			}
+			end := start
+			var q byte
+			for ; end < len(stage) && (q != 0 || stage[end] != ' ' && stage[end] != '>'); end++ {
+				switch {
+				case q != 0 && stage[end] == q:
+					q = 0
+				case q == 0 && (stage[end] == '\'' || stage[end] == '"'):
+					q = stage[end]
+				}
+			}
+			target := strings.Join(parseCommandWithQuotes(stage[start:end]), "")
+			if target == "" {
+				return "", redirect, false
+			}
+			switch stream {
+			case '2':
+				redirect.stderr, redirect.appendErr = target, appending
+			case '&':
+				redirect.stdout, redirect.appendOut = target, appending
+				redirect.stderr, redirect.appendErr = target, true
+			default:
+				redirect.stdout, redirect.appendOut = target, appending
+			}
+			words.WriteByte(' ')
+			i = end - 1
+			continue
+		}
+		words.WriteByte(c)
+	}
+	return words.String(), redirect, true
+}
 
-	if len(parts) == 0 {
-		return "", false
+// apply sends a stage's output where its redirections say
+func (r redirections) apply(vfs *VirtualFileSystem, result *commandResult) {
+	if r.merge && result.stderr != "" {
+		result.stdout = strings.TrimPrefix(result.stdout+"\n"+result.stderr, "\n")
+		result.stderr = ""
 	}
+	var failed []string
+	if r.stdout != "" {
+		if err := writeRedirect(vfs, r.stdout, result.stdout, r.appendOut); err != nil {
+			failed = append(failed, r.stdout+": "+errorText(err))
+		}
+		result.stdout = ""
+	}
+	if r.stderr != "" && result.stderr != "" {
+		if err := writeRedirect(vfs, r.stderr, result.stderr, r.appendErr); err != nil {
+			failed = append(failed, r.stderr+": "+errorText(err))
+		}
+		result.stderr = ""
+	}
+	for _, message := range failed {
+		result.fail(message, 1)
+	}
+}
 
-	command := parts[0]
-	args := parts[1:]
+// writeRedirect writes output to a file, or after its content with >>
+func writeRedirect(vfs *VirtualFileSystem, name, output string, appending bool) error {
+	if name == "/dev/null" {
+		return nil
+	}
+	if appending {
+		if existing, err := vfs.ReadFile(name); err == nil && len(existing) > 0 {
+			output = string(streamBytes(string(existing))) + output
+		}
+	}
+	return vfs.WriteFile(name, []byte(output))
+}
 
-	// Variables to store command output and completion status
-	var output string
+// runCommand runs one command of a pipeline. stdin is the previous
+// stage's output, nil when reading from the terminal.
+func (e *GameEngine) runCommand(command string, args []string, stdin *string, session *Session, level *Level, tty bool) (commandResult, bool) {
+	var result commandResult
 	completed := false
 
 	switch command {
 	case "ls":
-		// Handle ls flags like -a
-		if len(args) == 0 {
-			output = session.VirtualFS.ListFiles(".")
-		} else if len(args) == 1 && args[0] == "-a" {
-			output = session.VirtualFS.ListAllFiles(".")
-		} else {
-			output = session.VirtualFS.ListFiles(args[0])
-		}
+		result = listCommand(session, args, tty)
 
 	case "cat":
-		if len(args) == 0 {
-			output = "cat: missing filename"
+		if len(args) == 0 && stdin == nil {
+			result = errorResult("cat: missing filename", 1)
 			break
 		}
 
-		filename := args[0]
-		content, exists := session.VirtualFS.ReadFile(filename)
-		if !exists {
-			output = "cat: " + filename + ": No such file or directory"
-			break
+		// Binary files pass through byte for byte; on the terminal they
+		// come out garbled, as they would in a real one
+		var contents []string
+		binary := false
+		for _, input := range readInputs(session.VirtualFS, args, stdin, "cat: %s: %s", &result) {
+			binary = isBinary([]byte(input.content))
+			if !binary {
+				input.content = terminated(input.content)
+			} else if tty {
+				name := input.name
+				if name == "" {
+					name = "-"
+				}
+				input.content = garble(input.content)
+				result.fail("cat: "+name+": binary file; try strings, xxd or file to inspect it", result.exitCode)
+			}
+			contents = append(contents, input.content)
+		}
+		result.stdout = strings.Join(contents, "")
+		if !binary {
+			result.stdout = strings.TrimSuffix(result.stdout, "\n")
 		}
-		output = content
-		completed = (content == level.Solution)
+		completed = (result.stdout == level.Solution)
+
+	case "head", "tail":
+		result = headTailCommand(session.VirtualFS, command, args, stdin)
+
+	case "wc":
+		result = wcCommand(session.VirtualFS, args, stdin)
+
+	case "sort":
+		result = sortCommand(session.VirtualFS, args, stdin)
+
+	case "uniq":
+		result = uniqCommand(session.VirtualFS, args, stdin)
+
+	case "cut":
+		result = cutCommand(session.VirtualFS, args, stdin)
+
+	case "tr":
+		result = trCommand(args, stdin)
+
+	case "pwd":
+		result = stdoutResult(session.VirtualFS.Getwd())
+
+	case "whoami":
+		result = stdoutResult(session.VirtualFS.user)
+
+	case "hostname":
+		result = hostnameCommand(session.VirtualFS, args)
+
+	case "ssh":
+		result, completed = e.sshCommand(session, level, args)
+
+	case "scp":
+		result, completed = e.scpCommand(session, args)
+
+	case "exit", "logout":
+		result = logoutCommand(session, command)
 
 	case "cd":
-		// Simple cd implementation
-		if len(args) == 0 || args[0] == "~" {
-			output = ""
-		} else {
-			output = "cd: " + args[0] + ": No such directory"
+		target := "~"
+		if len(args) > 0 {
+			target = args[0]
+		}
+		if err := session.VirtualFS.Chdir(target); err != nil {
+			result = errorResult("cd: "+target+": "+errorText(err), 1)
 		}
 
+	case "touch":
+		result = touchCommand(session.VirtualFS, args)
+
+	case "mkdir":
+		result = mkdirCommand(session.VirtualFS, args)
+
+	case "rm":
+		result = rmCommand(session.VirtualFS, args)
+
+	case "rmdir":
+		result = rmdirCommand(session.VirtualFS, args)
+
+	case "cp":
+		result = cpCommand(session.VirtualFS, args)
+
+	case "mv":
+		result = mvCommand(session.VirtualFS, args)
+
+	case "ln":
+		result = lnCommand(session.VirtualFS, args)
+
+	case "less", "more":
+		result = pagerCommand(session, command, args, stdin, tty)
+
 	case "find":
-		output = session.VirtualFS.FindFiles(args)
+		result = findCommand(session.VirtualFS, args, func(argv []string) commandResult {
+			output, _ := e.runCommand(argv[0], argv[1:], nil, session, level, false)
+			return output
+		})
 
 	case "hint":
-		output = "💡 Hint: " + level.Hint
+		session.stats.levelHints++
+		result = stdoutResult("💡 Hint: " + level.Hint)
 
 	case "status":
-		output = e.getStatus(session)
+		result = stdoutResult(e.getStatus(session))
+
+	case "team":
+		result = e.teamStatus(session)
+
+	case "reset":
+		result = e.resetLevel(session)
+
+	case "snapshot":
+		result = e.snapshotCommand(session, args)
+
+	case "challenges":
+		result = e.listChallenges(session)
+
+	case "challenge":
+		result = e.selectChallenge(session, args)
+
+	case "submit":
+		result = e.submitFlag(session, args)
+
+	case "scoreboard":
+		result = e.scoreboardTable()
 
 	// --- NEW COMMANDS FOR CHALLENGING LEVELS ---
 	case "chmod":
 		if len(args) < 2 {
-			output = "chmod: missing operand"
+			result = errorResult("chmod: missing operand", 1)
 			break
 		}
-		// Simulate permission change for level 4
-		if args[1] == "secret.txt" {
-			output = "Permissions changed for secret.txt"
-			// After chmod, the file becomes readable with cat
-		} else {
-			output = "chmod: cannot access '" + args[1] + "': No such file or directory"
-		}
+		result = chmodCommand(session.VirtualFS, args[0], args[1:])
 
 	case "grep":
-		if len(args) < 2 {
-			output = "grep: missing pattern or filename"
-			break
-		}
-		output = session.VirtualFS.GrepFile(args[0], args[1])
-		// Check if grep output contains the EXACT solution
-		if strings.Contains(output, level.Solution) {
-			// Extract just the solution line for cleaner completion
-			lines := strings.Split(output, "\n")
-			for _, line := range lines {
-				if strings.Contains(line, level.Solution) {
-					output = strings.TrimSpace(line)
-					completed = true
-					break
-				}
-			}
-		}
+		result = grepCommand(session.VirtualFS, args, stdin)
 
 	case "strings":
-		if len(args) == 0 {
-			output = "strings: missing filename"
-			break
+		result = stringsCommand(session.VirtualFS, args, stdin)
+		// Finding the password among the extracted strings completes the level
+		for _, line := range strings.Split(result.stdout, "\n") {
+			completed = completed || line == level.Solution
 		}
-		output = session.VirtualFS.StringsCommand(args[0])
-		// For strings command, check if output contains the exact solution
-		if strings.Contains(output, level.Solution) {
-			// Extract just the solution part
-			start := strings.Index(output, level.Solution)
-			if start != -1 {
-				end := start + len(level.Solution)
-				output = output[start:end]
-				completed = true
-			}
+
+	case "file":
+		result = fileCommand(session.VirtualFS, args, stdin)
+
+	case "xxd":
+		result = xxdCommand(session.VirtualFS, args, stdin)
+
+	case "hexdump", "hd":
+		if command == "hd" {
+			args = append([]string{"-C"}, args...)
 		}
+		result = hexdumpCommand(session.VirtualFS, args, stdin)
+
+	case "od":
+		result = odCommand(session.VirtualFS, args, stdin)
+
+	case "gzip", "gunzip", "zcat":
+		result = gzipCommand(session.VirtualFS, command, args, stdin, tty)
+
+	case "bzip2", "bunzip2", "bzcat":
+		result = bzip2Command(session.VirtualFS, command, args, stdin, tty)
+
+	case "tar":
+		result = tarCommand(session.VirtualFS, args, stdin)
+
+	case "unzip":
+		result = unzipCommand(session.VirtualFS, args)
+
+	case "ps":
+		result = psCommand(session.VirtualFS, args)
+
+	case "pgrep", "pkill":
+		result = pgrepCommand(session.VirtualFS, command, args)
+
+	case "kill":
+		result = killCommand(session.VirtualFS, args)
+
+	case "top":
+		result = topCommand(session.VirtualFS, args)
+
+	case "jobs":
+		result = jobsCommand(session.VirtualFS, args)
+
+	case "fg", "bg":
+		result = fgBgCommand(session.VirtualFS, command, args)
+
+	case "sleep":
+		result = sleepCommand(args)
 
 	case "echo":
 		if len(args) == 0 {
-			output = ""
+			result = stdoutResult("")
 			break
 		}
 		// Handle environment variables for level 8
 		if len(args) == 1 && args[0] == "$SECRET_KEY" {
-			output = "bandit9{EnvVariableMaster}"
+			result = stdoutResult("bandit9{EnvVariableMaster}")
 			completed = true
 		} else {
-			output = strings.Join(args, " ")
+			result = stdoutResult(strings.Join(args, " "))
 		}
 
 	case "base64":
-		if len(args) < 2 {
-			output = "base64: missing option or filename"
-			break
-		}
-		if args[0] == "-d" {
-			output = session.VirtualFS.Base64Decode(args[1])
-			// Check if decoded content matches solution exactly
-			completed = (strings.TrimSpace(output) == level.Solution)
-		} else {
-			output = "base64: invalid option -- '" + args[0] + "'"
-		}
+		result = base64Command(session.VirtualFS, args, stdin)
+		// Level 9 is solved by decoding the password
+		completed = strings.TrimSpace(result.stdout) == level.Solution
 
-	default:
-		output = "command not found: " + command
-	}
+	case "md5sum", "sha1sum", "sha256sum", "sha512sum":
+		result = hashSumCommand(session.VirtualFS, command, args, stdin)
 
-	// FINAL CHECK: Only complete if we haven't already marked as completed
-	// AND output exactly matches the solution (to prevent false positives)
-	if !completed {
-		cleanOutput := strings.TrimSpace(output)
-		if cleanOutput == level.Solution {
-			completed = true
+	case "xor":
+		result = xorCommand(session.VirtualFS, args, stdin)
+
+	case "openssl":
+		result = opensslCommand(session.VirtualFS, args, stdin)
+		if len(args) > 0 && args[0] == "s_client" {
+			completed = completed || replied(result, level)
 		}
-	}
 
-	if completed {
-		return output + "\n\n" + levelCompletionMessage(level.ID), true
+	case "nc", "ncat", "netcat":
+		result = ncCommand(session.VirtualFS, command, args, stdin)
+		completed = completed || replied(result, level)
+
+	case "telnet":
+		result = telnetCommand(session.VirtualFS, args, stdin)
+		completed = completed || replied(result, level)
+
+	case "curl":
+		result = curlCommand(session.VirtualFS, args)
+		completed = completed || replied(result, level)
+
+	case "nmap":
+		result = nmapCommand(session.VirtualFS, args)
+
+	case "ss":
+		result = ssCommand(session.VirtualFS, args)
+
+	default:
+		result = errorResult("command not found: "+command, 127)
 	}
 
-	return output, false
+	return result, completed
 }
 
 func (e *GameEngine) showHelp() *CommandResponse {
 	helpText := `Available commands:
   ls [dir]        - List directory contents
   ls -a          - List all files including hidden
+  ls -1          - List one file per line
   cat <file>      - Display file contents  
+  head/tail [-n N] <file> - Show the first or last lines
+  wc [-lwc] <file> - Count lines, words and bytes
+  sort [-rnu] [-k N] <file> - Sort lines
+  uniq [-cdu]     - Collapse repeated adjacent lines
+  cut -d X -f N   - Select fields or characters from each line
+  tr <set1> <set2> - Translate or delete characters
+  cmd1 | cmd2     - Pipe one command's output into another
+  cmd > file      - Write a command's output to a file (>> appends)
+  cmd 2>/dev/null - Discard a command's errors (2>&1 merges them into its output)
+  less <file>     - Page through a file (q to quit)
+  more <file>     - Page through a file forwards
   cd [dir]        - Change directory
-  find [pattern]  - Find files
-  grep <pattern> <file> - Search for text in files
-  strings <file>  - Extract text from binary files
+  touch <file>    - Create a file or update its timestamp
+  mkdir [-p] <dir> - Create directories
+  rm [-rf] <file> - Remove files or directories
+  rmdir [-p] <dir> - Remove empty directories
+  cp [-r] <src> <dst> - Copy files and directories
+  mv <src> <dst>  - Move or rename files
+  ln [-s] <target> <link> - Create hard or symbolic links
+  find [dir] [-name P] [-type f|d|l] [-size N] [-user U] [-perm M] [-exec cmd {} \;] - Search a directory tree
+  grep [-invcrlo] <pattern> [file...] - Search for a regex in files
+  strings [-n N] <file> - Extract printable text from binary files
+  file <file>     - Identify a file's type from its contents
+  xxd [-r] [-p] <file> - Hex dump a file, or turn a dump back into bytes
+  hexdump -C <file> - Hex and ASCII dump of a file
+  od [-c] [-t x1] <file> - Octal, hex or character dump of a file
+  gzip/gunzip/zcat <file> - Compress or decompress gzip files
+  bzip2/bunzip2/bzcat <file> - Compress or decompress bzip2 files
+  tar -c|-x|-t [-z|-j] -f <archive> [files] - Create, extract or list tar archives
+  unzip [-l] [-d dir] <file.zip> - Extract or list zip archives
+  ps aux / ps -ef  - List running processes (cat /proc/<pid>/cmdline for details)
+  pgrep/pkill [-f] <pattern> - Find or signal processes by name
+  kill [-SIGNAL] <pid|%job> - Send a signal to a process (kill -l lists them)
+  top             - Show the busiest processes
+  cmd &           - Run a command in the background (jobs, fg, bg)
   chmod <mode> <file> - Change file permissions
-  echo <text>     - Display text or variables
-  base64 -d <file> - Decode base64 encoded file
+  echo <text>     - Display text or variables ($? = last exit status)
+  base64 [-d] [-w N] <file> - Encode or decode base64
+  md5sum/sha1sum/sha256sum [-c] <file> - Print or check file checksums
+  xor -k KEY | -x HEX | -b <file> - XOR data with a repeating key, or try every single-byte key
+  openssl enc -d -aes-256-cbc -pbkdf2 -k PASS -in <file> - Decrypt (or encrypt) with openssl
+  tr 'A-Za-z' 'N-ZA-Mn-za-m' - ROT13 a message
+  nmap [-p PORTS] <host> - Scan a machine for open ports (ss -ltn lists this one's)
+  echo PASS | nc <host> <port> - Send text to a service and print its reply
+  openssl s_client -connect <host>:<port> - Talk to a service over TLS (or ncat --ssl)
+  curl [-k] [-d DATA] <url> - Fetch a page from a web service
   pwd            - Print working directory
   whoami         - Show current user
+  hostname       - Show which machine you're on
+  ssh [-i KEY] [-p PORT] user@host [cmd] - Log in to another machine (exit to come back)
+  scp [-r] [-i KEY] <file> user@host:<path> - Copy files to or from another machine
   hint           - Get hint for current level
   status         - Show game status
+  team           - Show team members and score
+  race           - Show race standings
+  challenges     - List CTF event challenges
+  challenge <id> - Switch to a CTF challenge
+  submit <flag>  - Submit a flag for the current challenge
+  scoreboard     - Show the CTF event scoreboard
+  achievements   - List achievements and badges
+  speedrun [start|stop] - Show splits, or start a timed run from level 0
+  retry          - Restart a level failed by its time limit
+  reset          - Restore the level's files to how they started
+  snapshot save|restore <name> - Save or restore the filesystem state
   levels         - List all levels
   clear          - Clear terminal
   help           - Show this help message
 
 Use these commands to find passwords and complete levels!`
-	return &CommandResponse{Output: helpText}
+	return &CommandResponse{Stdout: helpText}
 }
 
 func (e *GameEngine) showLevels() *CommandResponse {
@@ -364,10 +1161,15 @@ func (e *GameEngine) showLevels() *CommandResponse {
 		levels = append(levels,
 			fmt.Sprintf("Level %d: %s", level.ID, level.Title))
 	}
-	return &CommandResponse{Output: strings.Join(levels, "\n")}
+	return &CommandResponse{Stdout: strings.Join(levels, "\n")}
 }
 
 func (e *GameEngine) getStatus(session *Session) string {
+	// CTF sessions play challenges, not the level progression
+	if event, team := e.ctfFor(session); event != nil {
+		return ctfStatus(session, event, team)
+	}
+
 	level := e.Levels[session.CurrentLevel]
 	return fmt.Sprintf(`
 Current Level: %d
@@ -375,13 +1177,14 @@ User: %s
 Level Title: %s
 Description: %s
 Progress: %d/%d levels completed
-	`,
+%s`,
 		session.CurrentLevel,
 		session.User,
 		level.Title,
 		level.Description,
 		session.CurrentLevel,
-		len(e.Levels)-1)
+		len(e.Levels)-1,
+		timeLeft(session))
 }
 
 func (e *GameEngine) initializeLevelFilesystem(session *Session, level int) {
@@ -391,16 +1194,32 @@ func (e *GameEngine) initializeLevelFilesystem(session *Session, level int) {
 		return
 	}
 
-	levelConfig := e.Levels[level]
-	session.VirtualFS = NewVirtualFS()
-	session.User = fmt.Sprintf("codeheist%d", level)
+	e.loadLevelFiles(session, e.Levels[level])
+	log.Printf("🔄 Initialized filesystem for level %d", level)
+}
+
+// loadLevelFiles replaces the session's filesystem with a level's files
+// and starts the level's clock
+func (e *GameEngine) loadLevelFiles(session *Session, level *Level) {
+	session.User = fmt.Sprintf("codeheist%d", level.ID)
+	session.VirtualFS = NewVirtualFS(session.User)
 
 	// Populate filesystem for this level
-	for filename, content := range levelConfig.Filesystem {
-		session.VirtualFS.WriteFile(filename, content.(string))
+	for filename, value := range level.Filesystem {
+		node, err := levelFileNode(value)
+		if err != nil {
+			log.Printf("⚠️ Level %d: skipping %s: %v", level.ID, filename, err)
+			continue
+		}
+		session.VirtualFS.install(session.VirtualFS.homePath(filename), node)
 	}
-
-	log.Printf("🔄 Initialized filesystem for level %d", level)
+	session.VirtualFS.seedProcesses(level.ID, level.Processes)
+	session.VirtualFS.services = append(session.VirtualFS.services, level.Services...)
+	session.VirtualFS.connect(level)
+	session.hops, session.login = nil, nil
+	session.pristine = session.VirtualFS.Snapshot()
+	session.snapshots = nil
+	e.startLevelTimer(session, level)
 }
 
 func (e *GameEngine) CleanupSessions() {
@@ -409,12 +1228,22 @@ func (e *GameEngine) CleanupSessions() {
 		now := time.Now()
 		e.Sessions.Range(func(key, value interface{}) bool {
 			session := value.(*Session)
-			if now.Sub(session.LastActivity) > 2*time.Hour {
+			session.mu.Lock()
+			idle := now.Sub(session.LastActivity)
+			if idle > 2*time.Hour {
+				e.stopLevelTimer(session)
+			}
+			session.mu.Unlock()
+			if idle > 2*time.Hour {
 				e.Sessions.Delete(key)
+				if session.TeamName != "" {
+					e.Teams.Delete(strings.ToLower(session.TeamName))
+				}
 				log.Printf("🧹 Cleaned up expired session: %s", session.ID)
 			}
 			return true
 		})
+		e.cleanupRaces()
 	}
 }
 
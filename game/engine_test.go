@@ -0,0 +1,122 @@
+package game
+
+import (
+	"io"
+	"log"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	log.SetOutput(io.Discard)
+	os.Exit(m.Run())
+}
+
+// commandTest runs setup commands on a fresh session, then checks the
+// result of one command. Setup results are ignored, so a failing setup
+// command can leave $? behind.
+type commandTest struct {
+	name     string
+	setup    []string
+	command  string
+	stdout   string
+	stderr   string
+	exitCode int
+}
+
+// newTestSession starts a session on a single level holding files
+func newTestSession(t *testing.T, files map[string]interface{}) (*GameEngine, *Session) {
+	t.Helper()
+	e := NewEngine()
+	e.Levels = map[int]*Level{
+		0: {ID: 0, Title: "Test", Filesystem: files, Solution: "test{never printed}"},
+	}
+	return e, e.CreateSession("127.0.0.1")
+}
+
+func runCommandTests(t *testing.T, files map[string]interface{}, tests []commandTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, s := newTestSession(t, files)
+			for _, command := range tt.setup {
+				e.ExecuteBatchCommand(s.ID, command)
+			}
+			r := e.ExecuteBatchCommand(s.ID, tt.command)
+			if r.Stdout != tt.stdout || r.Stderr != tt.stderr || r.ExitCode != tt.exitCode {
+				t.Errorf("%s\n got stdout %q stderr %q exit %d\nwant stdout %q stderr %q exit %d",
+					tt.command, r.Stdout, r.Stderr, r.ExitCode, tt.stdout, tt.stderr, tt.exitCode)
+			}
+		})
+	}
+}
+
+func TestShell(t *testing.T) {
+	files := map[string]interface{}{
+		"notes.txt":       "alpha\nbeta\ngamma",
+		"file with space": "spaced",
+		"-":               "dash",
+	}
+	runCommandTests(t, files, []commandTest{
+		{name: "cat", command: "cat notes.txt", stdout: "alpha\nbeta\ngamma"},
+		{name: "double quotes", command: `cat "file with space"`, stdout: "spaced"},
+		{name: "single quotes", command: `cat 'file with space'`, stdout: "spaced"},
+		{name: "backslash space", command: `cat file\ with\ space`, stdout: "spaced"},
+		{name: "dash file", command: "cat ./-", stdout: "dash"},
+		{name: "missing file", command: "cat nope", stderr: "cat: nope: No such file or directory", exitCode: 1},
+		{name: "unknown command", command: "frobnicate", stderr: "command not found: frobnicate", exitCode: 127},
+		{name: "exit status", setup: []string{"cat nope"}, command: "echo $?", stdout: "1"},
+		{name: "command not found status", setup: []string{"frobnicate"}, command: "echo $?", stdout: "127"},
+		{name: "status in double quotes", setup: []string{"cat nope"}, command: `echo "it's $?"`, stdout: "it's 1"},
+		{name: "status in single quotes", command: `echo '$?'`, stdout: "$?"},
+		{name: "escaped status", command: `echo \$? "\$?"`, stdout: "$? $?"},
+		{name: "pipeline", command: "cat notes.txt | grep a | wc -l", stdout: "3"},
+		{name: "quoted pipe", command: `echo "a|b"`, stdout: "a|b"},
+		{name: "redirect", setup: []string{"echo hi > out"}, command: "cat out", stdout: "hi"},
+		{name: "append", setup: []string{"echo one > out", "echo two >> out"}, command: "cat out", stdout: "one\ntwo"},
+		{name: "stderr redirect", setup: []string{"cat nope 2> err"}, command: "cat err", stdout: "cat: nope: No such file or directory"},
+		{name: "stderr to stdout", command: "cat nope 2>&1 | wc -l", stdout: "1"},
+		{name: "pipeline status", command: "cat nope | wc -l", stdout: "0", stderr: "cat: nope: No such file or directory"},
+	})
+}
+
+func TestExpandSpecialParams(t *testing.T) {
+	params := map[byte]string{'?': "2", '$': "1337", '!': ""}
+	tests := []struct {
+		input, want string
+	}{
+		{"echo $?", "echo 2"},
+		{"echo $$ $!", "echo 1337 "},
+		{"echo '$?'", "echo '$?'"},
+		{`echo "$?"`, `echo "2"`},
+		{`echo "it's $?"`, `echo "it's 2"`},
+		{`echo '"' $?`, `echo '"' 2`},
+		{`echo \$?`, `echo \$?`},
+		{`echo "\$?"`, `echo "\$?"`},
+		{`echo $x`, `echo $x`},
+	}
+	for _, tt := range tests {
+		if got := expandSpecialParams(tt.input, params); got != tt.want {
+			t.Errorf("expandSpecialParams(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestSplitPipeline(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"ls", []string{"ls"}},
+		{"ls | wc -l", []string{"ls ", " wc -l"}},
+		{"a || b", []string{"a || b"}},
+		{`echo "x|y" | cat`, []string{`echo "x|y" `, " cat"}},
+		{`echo 'x|y'`, []string{`echo 'x|y'`}},
+	}
+	for _, tt := range tests {
+		if got := splitPipeline(tt.input); !slices.Equal(got, tt.want) {
+			t.Errorf("splitPipeline(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package game
+
+import "time"
+
+// Engine event types
+const (
+	EventLevelCompleted = "level_completed"
+	EventCommandRun     = "command_run" // Message is the command line
+	EventTimerWarning   = "timer_warning"
+	EventLevelExpired   = "level_expired"
+	EventRaceCountdown  = "race_countdown" // Time is when input unlocks
+	EventRaceProgress   = "race_progress"
+	EventRaceFinished   = "race_finished"
+	EventFlagSolved     = "flag_solved" // Group is the team
+	EventFirstBlood     = "first_blood" // Group is the team
+)
+
+// Event is something that happened in the game, published to subscribers
+// after the session lock is released so they may call back into the engine
+type Event struct {
+	Type      string
+	SessionID string
+	Level     int
+	Group     string // Race or team the event belongs to, if any
+	Message   string // Summary to show other players
+	Time      time.Time
+}
+
+// Subscribe registers a function called synchronously for every event
+func (e *GameEngine) Subscribe(fn func(Event)) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+	e.subscribers = append(e.subscribers, fn)
+}
+
+func (e *GameEngine) emit(events ...Event) {
+	e.subMu.RLock()
+	subscribers := e.subscribers
+	e.subMu.RUnlock()
+
+	for _, event := range events {
+		for _, fn := range subscribers {
+			fn(event)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// parseFlags splits single-letter options from operands, getopt style:
+// flags may be combined (-rf), "--" ends the options and a lone "-" is an
+// operand. Long options map onto their letters.
+func parseFlags(command string, args []string, allowed string, long map[string]rune) (map[rune]bool, []string, *commandResult) {
+	flags, _, operands, usage := parseOptions(command, args, allowed, "", long)
+	return flags, operands, usage
+}
+
+// parseOptions is parseFlags for commands whose options take values
+// (-n 5, -n5, --lines=5): the letters in valued take one, returned in
+// values. A repeated option keeps its last value.
+func parseOptions(command string, args []string, allowed, valued string, long map[string]rune) (map[rune]bool, map[rune]string, []string, *commandResult) {
+	flags, lists, operands, usage := parseOptionValues(command, args, allowed, valued, long)
+	if usage != nil {
+		return nil, nil, nil, usage
+	}
+	values := make(map[rune]string)
+	for flag, list := range lists {
+		values[flag] = list[len(list)-1]
+	}
+	return flags, values, operands, nil
+}
+
+// parseOptionValues is parseOptions keeping every value of a repeated
+// option, as grep -e a -e b needs
+func parseOptionValues(command string, args []string, allowed, valued string, long map[string]rune) (map[rune]bool, map[rune][]string, []string, *commandResult) {
+	flags := make(map[rune]bool)
+	values := make(map[rune][]string)
+	var operands []string
+	usage := func(format string, arg interface{}) *commandResult {
+		result := errorResult(fmt.Sprintf("%s: "+format+"\nTry '%s --help' for more information.", command, arg, command), 1)
+		return &result
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return flags, values, append(operands, args[i+1:]...), nil
+		case strings.HasPrefix(arg, "--"):
+			name, value, hasValue := strings.Cut(arg[2:], "=")
+			flag, known := long[name]
+			if !known {
+				return nil, nil, nil, usage("unrecognized option '%s'", arg)
+			}
+			flags[flag] = true
+			if strings.ContainsRune(valued, flag) {
+				if !hasValue {
+					if i+1 == len(args) {
+						return nil, nil, nil, usage("option '%s' requires an argument", "--"+name)
+					}
+					i++
+					value = args[i]
+				}
+				values[flag] = append(values[flag], value)
+			}
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for j, flag := range arg[1:] {
+				if !strings.ContainsRune(allowed+valued, flag) {
+					return nil, nil, nil, usage("invalid option -- '%c'", flag)
+				}
+				flags[flag] = true
+				if !strings.ContainsRune(valued, flag) {
+					continue
+				}
+				// The value is the rest of the word, or the next argument
+				if rest := arg[j+2:]; rest != "" {
+					values[flag] = append(values[flag], rest)
+				} else if i+1 < len(args) {
+					i++
+					values[flag] = append(values[flag], args[i])
+				} else {
+					return nil, nil, nil, usage("option requires an argument -- '%c'", flag)
+				}
+				break
+			}
+		default:
+			operands = append(operands, arg)
+		}
+	}
+	return flags, values, operands, nil
+}
+
+// missingOperand is the usage error for a command run without files
+func missingOperand(command, what string) commandResult {
+	return errorResult(fmt.Sprintf("%s: missing %s\nTry '%s --help' for more information.", command, what, command), 1)
+}
+
+// touchCommand implements touch [-c] <file>...
+func touchCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("touch", args, "acm", map[string]rune{"no-create": 'c'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return missingOperand("touch", "file operand")
+	}
+
+	var result commandResult
+	for _, name := range operands {
+		err := vfs.Touch(name, !flags['c'])
+		if err != nil && !(flags['c'] && errors.Is(err, fs.ErrNotExist)) {
+			result.fail("touch: cannot touch '"+name+"': "+errorText(err), 1)
+		}
+	}
+	return result
+}
+
+// mkdirCommand implements mkdir [-p] <dir>...
+func mkdirCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("mkdir", args, "p", map[string]rune{"parents": 'p'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return missingOperand("mkdir", "operand")
+	}
+
+	var result commandResult
+	for _, name := range operands {
+		var err error
+		if flags['p'] {
+			err = mkdirAll(vfs, name)
+		} else {
+			err = vfs.Mkdir(name)
+		}
+		if err != nil {
+			result.fail("mkdir: cannot create directory '"+name+"': "+errorText(err), 1)
+		}
+	}
+	return result
+}
+
+// mkdirAll creates a directory and any missing parents, like mkdir -p
+func mkdirAll(vfs *VirtualFileSystem, name string) error {
+	prefix := ""
+	if strings.HasPrefix(name, "/") {
+		prefix = "/"
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == "" {
+			continue
+		}
+		prefix = path.Join(prefix, part)
+		if _, node, err := vfs.stat(prefix); err == nil {
+			if !node.isDir() {
+				return errNotDir
+			}
+			continue
+		}
+		if err := vfs.Mkdir(prefix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rmCommand implements rm [-r] [-f] [-d] <file>...
+func rmCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("rm", args, "rRfdi", map[string]rune{"recursive": 'r', "force": 'f', "dir": 'd'})
+	if usage != nil {
+		return *usage
+	}
+	recursive, force := flags['r'] || flags['R'], flags['f']
+	if len(operands) == 0 {
+		if force {
+			return commandResult{}
+		}
+		return missingOperand("rm", "operand")
+	}
+
+	var result commandResult
+	for _, name := range operands {
+		if base := path.Base(name); base == "." || base == ".." {
+			result.fail("rm: refusing to remove '.' or '..' directory: skipping '"+name+"'", 1)
+			continue
+		}
+		if recursive && vfs.abs(name) == "/" {
+			result.fail("rm: it is dangerous to operate recursively on '/'\nrm: use --no-preserve-root to override this failsafe", 1)
+			continue
+		}
+
+		_, node, err := vfs.lstat(name)
+		switch {
+		case err != nil:
+			if !force || !errors.Is(err, fs.ErrNotExist) {
+				result.fail("rm: cannot remove '"+name+"': "+errorText(err), 1)
+			}
+			continue
+		case node.isDir() && recursive:
+			removeTree(vfs, name, &result)
+			continue
+		case node.isDir() && !flags['d']:
+			result.fail("rm: cannot remove '"+name+"': Is a directory", 1)
+			continue
+		}
+		if err := vfs.Remove(name); err != nil {
+			result.fail("rm: cannot remove '"+name+"': "+errorText(err), 1)
+		}
+	}
+	return result
+}
+
+// removeTree deletes a directory depth-first like rm -r, reporting each
+// entry that can't be removed
+func removeTree(vfs *VirtualFileSystem, name string, result *commandResult) {
+	entries, err := vfs.ReadDir(name)
+	if err != nil {
+		result.fail("rm: cannot remove '"+name+"': "+errorText(err), 1)
+		return
+	}
+	for _, entry := range entries {
+		child := path.Join(name, entry)
+		if _, node, err := vfs.lstat(child); err == nil && node.isDir() {
+			removeTree(vfs, child, result)
+			continue
+		}
+		if err := vfs.Remove(child); err != nil {
+			result.fail("rm: cannot remove '"+child+"': "+errorText(err), 1)
+		}
+	}
+	// Entries that failed were reported already
+	if err := vfs.Remove(name); err != nil && !errors.Is(err, errNotEmpty) {
+		result.fail("rm: cannot remove '"+name+"': "+errorText(err), 1)
+	}
+}
+
+// rmdirCommand implements rmdir [-p] <dir>...
+func rmdirCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("rmdir", args, "p", map[string]rune{"parents": 'p'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return missingOperand("rmdir", "operand")
+	}
+
+	var result commandResult
+	for _, name := range operands {
+		for dir := path.Clean(name); ; dir = path.Dir(dir) {
+			if err := removeDir(vfs, dir); err != nil {
+				result.fail("rmdir: failed to remove '"+dir+"': "+errorText(err), 1)
+				break
+			}
+			if !flags['p'] || path.Dir(dir) == "." || path.Dir(dir) == "/" {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// removeDir removes an empty directory, refusing files and symlinks
+func removeDir(vfs *VirtualFileSystem, name string) error {
+	_, node, err := vfs.lstat(name)
+	if err != nil {
+		return err
+	}
+	if !node.isDir() {
+		return errNotDir
+	}
+	return vfs.Remove(name)
+}
+
+// targets pairs each source with where cp, mv or ln puts it: inside the
+// destination when it's a directory, otherwise the destination itself
+func targets(vfs *VirtualFileSystem, command string, operands []string) ([][2]string, *commandResult) {
+	if len(operands) == 0 {
+		usage := missingOperand(command, "file operand")
+		return nil, &usage
+	}
+	if len(operands) == 1 {
+		usage := missingOperand(command, "destination file operand after '"+operands[0]+"'")
+		return nil, &usage
+	}
+
+	sources, dest := operands[:len(operands)-1], operands[len(operands)-1]
+	_, node, err := vfs.stat(dest)
+	intoDir := err == nil && node.isDir()
+	if len(sources) > 1 && !intoDir {
+		usage := errorResult(command+": target '"+dest+"' is not a directory", 1)
+		return nil, &usage
+	}
+
+	var pairs [][2]string
+	for _, source := range sources {
+		target := dest
+		if intoDir {
+			target = path.Join(dest, path.Base(source))
+		}
+		pairs = append(pairs, [2]string{source, target})
+	}
+	return pairs, nil
+}
+
+// cpCommand implements cp [-r] [-f] <source>... <dest>
+func cpCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("cp", args, "rRf", map[string]rune{"recursive": 'r', "force": 'f'})
+	if usage != nil {
+		return *usage
+	}
+	pairs, usage := targets(vfs, "cp", operands)
+	if usage != nil {
+		return *usage
+	}
+
+	var result commandResult
+	for _, pair := range pairs {
+		copyPath(vfs, pair[0], pair[1], flags['r'] || flags['R'], flags['f'], &result)
+	}
+	return result
+}
+
+// copyPath copies one file, or a directory tree when recursive. Like GNU
+// cp, a recursive copy keeps symlinks instead of following them.
+func copyPath(vfs *VirtualFileSystem, source, target string, recursive, force bool, result *commandResult) {
+	resolve := vfs.stat
+	if recursive {
+		resolve = vfs.lstat
+	}
+	from, node, err := resolve(source)
+	if err != nil {
+		result.fail("cp: cannot stat '"+source+"': "+errorText(err), 1)
+		return
+	}
+	to, existing, err := vfs.stat(target)
+	exists := err == nil
+
+	switch {
+	case node.isDir():
+		if !recursive {
+			result.fail("cp: -r not specified; omitting directory '"+source+"'", 1)
+			return
+		}
+		if dest := vfs.abs(target); dest == from || strings.HasPrefix(dest, from+"/") {
+			result.fail("cp: cannot copy a directory, '"+source+"', into itself, '"+target+"'", 1)
+			return
+		}
+		if exists && !existing.isDir() {
+			result.fail("cp: cannot overwrite non-directory '"+target+"' with directory '"+source+"'", 1)
+			return
+		}
+		entries, err := vfs.ReadDir(source)
+		if err != nil {
+			result.fail("cp: cannot access '"+source+"': "+errorText(err), 1)
+			return
+		}
+		if !exists {
+			if err := vfs.Mkdir(target); err != nil {
+				result.fail("cp: cannot create directory '"+target+"': "+errorText(err), 1)
+				return
+			}
+		}
+		for _, entry := range entries {
+			copyPath(vfs, path.Join(source, entry), path.Join(target, entry), recursive, force, result)
+		}
+		if !exists {
+			vfs.Chmod(target, node.mode.Perm())
+		}
+
+	case node.isSymlink():
+		if _, link, err := vfs.lstat(target); err == nil && !link.isDir() {
+			vfs.Remove(target)
+		}
+		if err := vfs.Symlink(node.target, target); err != nil {
+			result.fail("cp: cannot create symbolic link '"+target+"': "+errorText(err), 1)
+		}
+
+	default:
+		if exists && (to == from || existing == node) {
+			result.fail("cp: '"+source+"' and '"+target+"' are the same file", 1)
+			return
+		}
+		if exists && existing.isDir() {
+			result.fail("cp: cannot overwrite directory '"+target+"' with non-directory", 1)
+			return
+		}
+		if !vfs.readable(node) {
+			result.fail("cp: cannot open '"+source+"' for reading: Permission denied", 1)
+			return
+		}
+		err := vfs.WriteFile(target, node.content)
+		if errors.Is(err, fs.ErrPermission) && exists && force {
+			// -f replaces a destination that can't be opened for writing
+			if vfs.Remove(target) == nil {
+				exists = false
+				err = vfs.WriteFile(target, node.content)
+			}
+		}
+		if err != nil {
+			result.fail("cp: cannot create regular file '"+target+"': "+errorText(err), 1)
+			return
+		}
+		if !exists {
+			vfs.Chmod(target, node.mode.Perm()&^0022)
+		}
+	}
+}
+
+// mvCommand implements mv [-f] [-n] <source>... <dest>
+func mvCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("mv", args, "fn", map[string]rune{"force": 'f', "no-clobber": 'n'})
+	if usage != nil {
+		return *usage
+	}
+	pairs, usage := targets(vfs, "mv", operands)
+	if usage != nil {
+		return *usage
+	}
+
+	var result commandResult
+	for _, pair := range pairs {
+		source, target := pair[0], pair[1]
+		from, node, err := vfs.lstat(source)
+		if err != nil {
+			result.fail("mv: cannot stat '"+source+"': "+errorText(err), 1)
+			continue
+		}
+
+		if to, existing, err := vfs.lstat(target); err == nil {
+			switch {
+			case to == from:
+				result.fail("mv: '"+source+"' and '"+target+"' are the same file", 1)
+				continue
+			case flags['n']:
+				continue
+			case existing.isDir() && !node.isDir():
+				result.fail("mv: cannot overwrite directory '"+target+"' with non-directory", 1)
+				continue
+			case !existing.isDir() && node.isDir():
+				result.fail("mv: cannot overwrite non-directory '"+target+"' with directory '"+source+"'", 1)
+				continue
+			}
+		}
+
+		if err := vfs.Rename(source, target); err != nil {
+			if errors.Is(err, errSubdir) {
+				result.fail("mv: cannot move '"+source+"' to a subdirectory of itself, '"+target+"'", 1)
+			} else {
+				result.fail("mv: cannot move '"+source+"' to '"+target+"': "+errorText(err), 1)
+			}
+		}
+	}
+	return result
+}
+
+// lnCommand implements ln [-s] [-f] <target>... [link]
+func lnCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("ln", args, "sf", map[string]rune{"symbolic": 's', "force": 'f'})
+	if usage != nil {
+		return *usage
+	}
+
+	var pairs [][2]string
+	switch len(operands) {
+	case 0:
+		return missingOperand("ln", "file operand")
+	case 1:
+		// A single operand links it into the working directory
+		pairs = [][2]string{{operands[0], path.Base(operands[0])}}
+	default:
+		pairs, usage = targets(vfs, "ln", operands)
+		if usage != nil {
+			return *usage
+		}
+	}
+
+	var result commandResult
+	for _, pair := range pairs {
+		source, link := pair[0], pair[1]
+		if flags['f'] {
+			if _, existing, err := vfs.lstat(link); err == nil && !existing.isDir() {
+				vfs.Remove(link)
+			}
+		}
+
+		if flags['s'] {
+			if err := vfs.Symlink(source, link); err != nil {
+				result.fail("ln: failed to create symbolic link '"+link+"': "+errorText(err), 1)
+			}
+			continue
+		}
+
+		_, node, err := vfs.stat(source)
+		switch {
+		case err != nil:
+			result.fail("ln: failed to access '"+source+"': "+errorText(err), 1)
+		case node.isDir():
+			result.fail("ln: "+source+": hard link not allowed for directory", 1)
+		default:
+			if err := vfs.Link(source, link); err != nil {
+				result.fail("ln: failed to create hard link '"+link+"': "+errorText(err), 1)
+			}
+		}
+	}
+	return result
+}
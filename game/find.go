@@ -0,0 +1,464 @@
+package game
+
+import (
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// findEntry is one path visited by find
+type findEntry struct {
+	display string // As find prints it, relative to the start path
+	name    string // Absolute path in the filesystem
+	node    *fileNode
+}
+
+// findExpr is a compiled find expression; it reports whether an entry
+// matches, running any actions along the way
+type findExpr func(entry findEntry) bool
+
+// findParser compiles find's expression language. Errors stop parsing
+// and are reported once.
+type findParser struct {
+	vfs      *VirtualFileSystem
+	exec     func(argv []string) commandResult
+	tokens   []string
+	pos      int
+	err      string
+	output   []string
+	result   *commandResult
+	actions  bool // Whether any action ran, suppressing the implicit -print
+	maxDepth int
+	minDepth int
+	batches  []*findBatch
+}
+
+// findBatch collects the paths of one -exec ... {} + action
+type findBatch struct {
+	argv  []string
+	paths []string
+}
+
+// findCommand implements find: walk each start path, evaluating the
+// expression on every entry. exec runs the commands of -exec.
+func findCommand(vfs *VirtualFileSystem, args []string, exec func(argv []string) commandResult) commandResult {
+	// Paths come before the first token that starts an expression
+	var starts []string
+	for len(args) > 0 && !isFindToken(args[0]) {
+		starts, args = append(starts, args[0]), args[1:]
+	}
+	if len(starts) == 0 {
+		starts = []string{"."}
+	}
+
+	var result commandResult
+	p := &findParser{vfs: vfs, exec: exec, tokens: args, result: &result, maxDepth: -1}
+	expr := func(findEntry) bool { return true }
+	if len(args) > 0 {
+		expr = p.parseOr()
+		if p.err == "" && p.pos < len(p.tokens) {
+			if p.tokens[p.pos] == ")" {
+				p.err = "find: invalid expression; you have too many ')'"
+			} else {
+				p.err = "find: paths must precede expression: `" + p.tokens[p.pos] + "'"
+			}
+		}
+		if p.err != "" {
+			return errorResult(p.err, 1)
+		}
+	}
+
+	for _, start := range starts {
+		name, node, err := vfs.lstat(start)
+		if err != nil {
+			result.fail("find: '"+start+"': "+errorText(err), 1)
+			continue
+		}
+		p.walk(findEntry{display: start, name: name, node: node}, 0, expr)
+	}
+	for _, batch := range p.batches {
+		if len(batch.paths) > 0 {
+			p.run(append(batch.argv, batch.paths...))
+		}
+	}
+
+	result.stdout = strings.Join(p.output, "\n")
+	return result
+}
+
+// isFindToken reports whether an argument begins find's expression
+func isFindToken(arg string) bool {
+	return strings.HasPrefix(arg, "-") && len(arg) > 1 || arg == "!" || arg == "(" || arg == ")"
+}
+
+// walk evaluates the expression on an entry, then descends into it
+func (p *findParser) walk(entry findEntry, depth int, expr findExpr) {
+	if depth >= p.minDepth && expr(entry) && !p.actions {
+		p.output = append(p.output, entry.display)
+	}
+	if !entry.node.isDir() || p.maxDepth >= 0 && depth >= p.maxDepth {
+		return
+	}
+
+	names, err := p.vfs.ReadDir(entry.name)
+	if err != nil {
+		p.result.fail("find: '"+entry.display+"': "+errorText(err), 1)
+		return
+	}
+	for _, child := range names {
+		display := strings.TrimSuffix(entry.display, "/") + "/" + child
+		name, node, err := p.vfs.lstat(path.Join(entry.name, child))
+		if err != nil {
+			p.result.fail("find: '"+display+"': "+errorText(err), 1)
+			continue
+		}
+		p.walk(findEntry{display: display, name: name, node: node}, depth+1, expr)
+	}
+}
+
+// run executes a command for -exec, collecting its output
+func (p *findParser) run(argv []string) bool {
+	out := p.exec(argv)
+	if out.stdout != "" {
+		p.output = append(p.output, out.stdout)
+	}
+	if out.stderr != "" {
+		p.result.fail(out.stderr, p.result.exitCode)
+	}
+	return out.exitCode == 0
+}
+
+func (p *findParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// parseOr parses expr -o expr
+func (p *findParser) parseOr() findExpr {
+	left := p.parseAnd()
+	for p.err == "" && (p.peek() == "-o" || p.peek() == "-or") {
+		p.pos++
+		right := p.parseAnd()
+		l := left
+		left = func(entry findEntry) bool { return l(entry) || right(entry) }
+	}
+	return left
+}
+
+// parseAnd parses expr [-a] expr; juxtaposed expressions are joined by -a
+func (p *findParser) parseAnd() findExpr {
+	left := p.parseUnary()
+	for p.err == "" {
+		switch p.peek() {
+		case "", "-o", "-or", ")":
+			return left
+		case "-a", "-and":
+			p.pos++
+		}
+		right := p.parseUnary()
+		l := left
+		left = func(entry findEntry) bool { return l(entry) && right(entry) }
+	}
+	return left
+}
+
+// parseUnary parses ! expr, ( expr ) and primaries
+func (p *findParser) parseUnary() findExpr {
+	token := p.peek()
+	switch token {
+	case "":
+		p.err = "find: invalid expression; expected an expression"
+		return nil
+	case "!", "-not":
+		p.pos++
+		if p.peek() == "" {
+			p.err = "find: invalid expression; '" + token + "' terminated with no argument"
+			return nil
+		}
+		inner := p.parseUnary()
+		return func(entry findEntry) bool { return !inner(entry) }
+	case "(":
+		p.pos++
+		inner := p.parseOr()
+		if p.err == "" && p.peek() != ")" {
+			p.err = "find: invalid expression; I was expecting to find a ')' somewhere but did not see one."
+		}
+		p.pos++
+		return inner
+	case "-o", "-or", "-a", "-and":
+		p.err = "find: invalid expression; you have used a binary operator '" + token + "' with nothing before it."
+		return nil
+	case ")":
+		p.err = "find: invalid expression; you have too many ')'"
+		return nil
+	}
+	p.pos++
+	return p.parsePrimary(token)
+}
+
+// argument consumes the value of a predicate such as -name
+func (p *findParser) argument(predicate string) (string, bool) {
+	if p.pos >= len(p.tokens) {
+		p.err = "find: missing argument to `" + predicate + "'"
+		return "", false
+	}
+	p.pos++
+	return p.tokens[p.pos-1], true
+}
+
+// parsePrimary compiles one test, action or option
+func (p *findParser) parsePrimary(predicate string) findExpr {
+	always := func(findEntry) bool { return true }
+
+	switch predicate {
+	case "-true":
+		return always
+	case "-false":
+		return func(findEntry) bool { return false }
+
+	case "-print":
+		p.actions = true
+		return func(entry findEntry) bool {
+			p.output = append(p.output, entry.display)
+			return true
+		}
+
+	case "-maxdepth", "-mindepth":
+		value, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			p.err = "find: Expected a positive decimal integer argument to " + predicate + ", but got `" + value + "'"
+			return nil
+		}
+		if predicate == "-maxdepth" {
+			p.maxDepth = depth
+		} else {
+			p.minDepth = depth
+		}
+		return always
+
+	case "-name", "-iname":
+		pattern, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		fold := predicate == "-iname"
+		if fold {
+			pattern = strings.ToLower(pattern)
+		}
+		return func(entry findEntry) bool {
+			base := path.Base(entry.display)
+			if fold {
+				base = strings.ToLower(base)
+			}
+			matched, _ := path.Match(pattern, base)
+			return matched
+		}
+
+	case "-type":
+		kind, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		if len(kind) != 1 || !strings.Contains("fdlbcps", kind) {
+			p.err = "find: Unknown argument to -type: " + kind
+			return nil
+		}
+		return func(entry findEntry) bool {
+			switch kind {
+			case "f":
+				return entry.node.mode.IsRegular()
+			case "d":
+				return entry.node.isDir()
+			case "l":
+				return entry.node.isSymlink()
+			}
+			return false
+		}
+
+	case "-user", "-group":
+		owner, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		return func(entry findEntry) bool {
+			if predicate == "-user" {
+				return entry.node.owner == owner
+			}
+			return entry.node.group == owner
+		}
+
+	case "-size":
+		value, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		unit := int64(512)
+		units := map[byte]int64{'c': 1, 'w': 2, 'b': 512, 'k': 1024, 'M': 1 << 20, 'G': 1 << 30}
+		if n := len(value); n > 0 {
+			if size, exists := units[value[n-1]]; exists {
+				unit, value = size, value[:n-1]
+			}
+		}
+		compare, ok := p.number(predicate, value)
+		if !ok {
+			return nil
+		}
+		return func(entry findEntry) bool {
+			// Files measure what cat or wc reads from them, with the
+			// trailing newline text is stored without
+			size := entry.node.size()
+			if entry.node.mode.IsRegular() {
+				size = int64(len(streamBytes(string(entry.node.content))))
+			}
+			// Sizes round up to whole units, as in GNU find
+			return compare((size + unit - 1) / unit)
+		}
+
+	case "-mtime", "-mmin":
+		value, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		compare, ok := p.number(predicate, value)
+		if !ok {
+			return nil
+		}
+		period := 24 * time.Hour
+		if predicate == "-mmin" {
+			period = time.Minute
+		}
+		now := time.Now()
+		return func(entry findEntry) bool {
+			return compare(int64(math.Floor(float64(now.Sub(entry.node.modTime)) / float64(period))))
+		}
+
+	case "-newer":
+		reference, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		_, node, err := p.vfs.stat(reference)
+		if err != nil {
+			p.err = "find: '" + reference + "': " + errorText(err)
+			return nil
+		}
+		return func(entry findEntry) bool { return entry.node.modTime.After(node.modTime) }
+
+	case "-perm":
+		value, ok := p.argument(predicate)
+		if !ok {
+			return nil
+		}
+		spec, match := value, "exact"
+		switch {
+		case strings.HasPrefix(value, "-"):
+			spec, match = value[1:], "all"
+		case strings.HasPrefix(value, "/"):
+			spec, match = value[1:], "any"
+		}
+		mode, ok := parseFileMode(spec, 0)
+		if !ok {
+			p.err = "find: invalid mode '" + value + "'"
+			return nil
+		}
+		return func(entry findEntry) bool {
+			perm := entry.node.mode.Perm()
+			switch match {
+			case "all":
+				return perm&mode == mode
+			case "any":
+				return mode == 0 || perm&mode != 0
+			}
+			return perm == mode
+		}
+
+	case "-empty":
+		return func(entry findEntry) bool {
+			if entry.node.isDir() {
+				return len(p.vfs.children(entry.name)) == 0
+			}
+			return entry.node.mode.IsRegular() && len(entry.node.content) == 0
+		}
+
+	case "-readable", "-writable", "-executable":
+		access := map[string]os.FileMode{"-readable": 4, "-writable": 2, "-executable": 1}[predicate]
+		return func(entry findEntry) bool {
+			_, node, err := p.vfs.stat(entry.name)
+			return err == nil && p.vfs.allowed(node, access)
+		}
+
+	case "-exec":
+		var argv []string
+		for p.pos < len(p.tokens) && p.tokens[p.pos] != ";" && p.tokens[p.pos] != "+" {
+			argv = append(argv, p.tokens[p.pos])
+			p.pos++
+		}
+		if p.pos >= len(p.tokens) || len(argv) == 0 {
+			p.err = "find: missing argument to `-exec'"
+			return nil
+		}
+		p.actions = true
+
+		// -exec command {} + runs once with every path in place of the {}
+		if p.tokens[p.pos] == "+" && argv[len(argv)-1] == "{}" {
+			p.pos++
+			batch := &findBatch{argv: argv[:len(argv)-1]}
+			p.batches = append(p.batches, batch)
+			return func(entry findEntry) bool {
+				batch.paths = append(batch.paths, entry.display)
+				return true
+			}
+		}
+		if p.tokens[p.pos] == "+" {
+			p.err = "find: missing argument to `-exec'"
+			return nil
+		}
+		p.pos++
+		return func(entry findEntry) bool {
+			command := make([]string, len(argv))
+			for i, arg := range argv {
+				command[i] = strings.ReplaceAll(arg, "{}", entry.display)
+			}
+			return p.run(command)
+		}
+	}
+
+	if strings.HasPrefix(predicate, "-") {
+		p.err = "find: unknown predicate `" + predicate + "'"
+	} else {
+		p.err = "find: paths must precede expression: `" + predicate + "'"
+	}
+	return nil
+}
+
+// number parses a numeric test argument: +n for more than n, -n for
+// less than n, and n for exactly n
+func (p *findParser) number(predicate, value string) (func(int64) bool, bool) {
+	sign := byte(0)
+	if value != "" && (value[0] == '+' || value[0] == '-') {
+		sign, value = value[0], value[1:]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		p.err = "find: invalid argument `" + value + "' to `" + predicate + "'"
+		return nil, false
+	}
+	return func(x int64) bool {
+		switch sign {
+		case '+':
+			return x > n
+		case '-':
+			return x < n
+		}
+		return x == n
+	}, true
+}
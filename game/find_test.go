@@ -0,0 +1,55 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// Expected output was recorded from GNU find on the same tree. GNU find
+// walks directories in readdir order, so most cases sort.
+func TestFind(t *testing.T) {
+	files := map[string]interface{}{
+		"notes.txt":      "hello",
+		"big.log":        strings.Repeat("0123456789", 150),
+		"src/main.go":    "package main",
+		"src/util.GO":    "package util",
+		"src/deep/x.txt": "x",
+		"secret/.hidden": "flag{hidden}",
+	}
+	setup := []string{
+		"touch empty.txt",
+		"mkdir emptydir",
+		"chmod 600 notes.txt",
+		"chmod 755 src/main.go",
+		"chmod 640 big.log",
+		"chmod 644 src/util.GO src/deep/x.txt secret/.hidden empty.txt",
+		"ln -s notes.txt link",
+	}
+	runCommandTests(t, files, []commandTest{
+		{setup: setup, name: "all", command: "find . | sort", stdout: ".\n./big.log\n./empty.txt\n./emptydir\n./link\n./notes.txt\n./secret\n./secret/.hidden\n./src\n./src/deep\n./src/deep/x.txt\n./src/main.go\n./src/util.GO"},
+		{setup: setup, name: "-name", command: "find . -name '*.txt' | sort", stdout: "./empty.txt\n./notes.txt\n./src/deep/x.txt"},
+		{setup: setup, name: "-iname", command: "find . -iname '*.go' | sort", stdout: "./src/main.go\n./src/util.GO"},
+		{setup: setup, name: "-type d", command: "find . -type d | sort", stdout: ".\n./emptydir\n./secret\n./src\n./src/deep"},
+		{setup: setup, name: "-type l", command: "find . -type l", stdout: "./link"},
+		{setup: setup, name: "-type f -name", command: "find src -type f -name 'x*'", stdout: "src/deep/x.txt"},
+		{setup: setup, name: "-maxdepth", command: "find . -maxdepth 1 -type f | sort", stdout: "./big.log\n./empty.txt\n./notes.txt"},
+		{setup: setup, name: "-mindepth", command: "find src -mindepth 2", stdout: "src/deep/x.txt"},
+		{setup: setup, name: "sorted walk", command: "find src", stdout: "src\nsrc/deep\nsrc/deep/x.txt\nsrc/main.go\nsrc/util.GO"},
+		{setup: setup, name: "-size +", command: "find . -size +1k | sort", stdout: ".\n./big.log\n./emptydir\n./secret\n./src\n./src/deep"},
+		{setup: setup, name: "-size c", command: "find . -type f -size -7c | sort", stdout: "./empty.txt\n./notes.txt\n./src/deep/x.txt"},
+		{setup: setup, name: "-size exact c", command: "find . -size 6c", stdout: "./notes.txt"},
+		{setup: setup, name: "-empty", command: "find . -empty | sort", stdout: "./empty.txt\n./emptydir"},
+		{setup: setup, name: "-perm exact", command: "find . -type f -perm 600", stdout: "./notes.txt"},
+		{setup: setup, name: "-perm all", command: "find . -type f -perm -644 | sort", stdout: "./empty.txt\n./secret/.hidden\n./src/deep/x.txt\n./src/main.go\n./src/util.GO"},
+		{setup: setup, name: "-perm any", command: "find . -type f -perm /111", stdout: "./src/main.go"},
+		{setup: setup, name: "-not", command: "find src -not -name '*.go' | sort", stdout: "src\nsrc/deep\nsrc/deep/x.txt\nsrc/util.GO"},
+		{setup: setup, name: "!", command: "find src ! -type d | sort", stdout: "src/deep/x.txt\nsrc/main.go\nsrc/util.GO"},
+		{setup: setup, name: "parentheses and -o", command: `find . -type f \( -name '*.go' -o -name '*.log' \) | sort`, stdout: "./big.log\n./src/main.go"},
+		{setup: setup, name: "hidden file", command: "find . -name '.*' -type f", stdout: "./secret/.hidden"},
+		{setup: setup, name: "-exec", command: `find src -name main.go -exec cat {} \;`, stdout: "package main"},
+		{setup: setup, name: "-exec plus", command: "find src -name '*.txt' -exec cat {} +", stdout: "x"},
+		{setup: setup, name: "missing", command: "find nope", stderr: "find: 'nope': No such file or directory", exitCode: 1},
+		{setup: setup, name: "unknown predicate", command: "find . -bogus", stderr: "find: unknown predicate `-bogus'", exitCode: 1},
+		{setup: setup, name: "missing argument", command: "find . -name", stderr: "find: missing argument to `-name'", exitCode: 1},
+	})
+}
@@ -0,0 +1,342 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+)
+
+// grepOptions are the parsed flags of one grep invocation
+type grepOptions struct {
+	invert, count, listFiles, onlyMatching, quiet, lineNumbers bool
+	before, after                                              int
+	withNames, text                                            bool // text (-a) prints binary files' matching lines
+	context                                                    bool // -A, -B or -C given, even as 0: "--" between groups
+}
+
+// grepCommand implements grep over files, directory trees (-r) and stdin
+func grepCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptionValues("grep", args, "ivncrRlEoqHhwFa", "ABCe", map[string]rune{
+		"ignore-case": 'i', "invert-match": 'v', "line-number": 'n', "count": 'c', "recursive": 'r',
+		"dereference-recursive": 'R', "files-with-matches": 'l', "extended-regexp": 'E', "only-matching": 'o',
+		"quiet": 'q', "silent": 'q', "with-filename": 'H', "no-filename": 'h', "word-regexp": 'w',
+		"fixed-strings": 'F', "text": 'a', "after-context": 'A', "before-context": 'B', "context": 'C', "regexp": 'e'})
+	if usage != nil {
+		usage.exitCode = 2
+		return *usage
+	}
+
+	// Each -e adds patterns; like GNU grep, a newline separates patterns too
+	pattern, set := strings.Join(values['e'], "\n"), len(values['e']) > 0
+	if !set {
+		if len(operands) == 0 {
+			return errorResult("Usage: grep [OPTION]... PATTERNS [FILE]...\nTry 'grep --help' for more information.", 2)
+		}
+		pattern, operands = operands[0], operands[1:]
+	}
+
+	opts := grepOptions{
+		invert:       flags['v'],
+		count:        flags['c'],
+		listFiles:    flags['l'],
+		onlyMatching: flags['o'],
+		quiet:        flags['q'],
+		lineNumbers:  flags['n'],
+		text:         flags['a'],
+	}
+	for flag, context := range map[rune]*int{'A': &opts.after, 'B': &opts.before} {
+		list := values[flag]
+		if len(list) == 0 {
+			list = values['C']
+		}
+		if len(list) == 0 {
+			continue
+		}
+		value := list[len(list)-1]
+		opts.context = true
+		lines, err := strconv.Atoi(value)
+		if err != nil || lines < 0 {
+			return errorResult("grep: "+value+": invalid context length argument", 2)
+		}
+		*context = lines
+	}
+
+	re, err := compileGrepPattern(pattern, flags['E'], flags['F'], flags['i'], flags['w'])
+	if err != nil {
+		return errorResult("grep: "+err.Error(), 2)
+	}
+
+	// Collect what to search: stdin, the named files, or their trees with -r
+	var result commandResult
+	recursive := flags['r'] || flags['R']
+	var inputs []textInput
+	switch {
+	case len(operands) == 0 && recursive:
+		inputs = grepTree(vfs, ".", "", flags['R'], &result)
+	case len(operands) == 0:
+		inputs = readInputs(vfs, nil, stdin, "", &result)
+	}
+	for _, name := range operands {
+		if name == "-" && stdin != nil {
+			inputs = append(inputs, textInput{content: *stdin})
+			continue
+		}
+		if _, node, err := vfs.stat(name); err == nil && node.isDir() {
+			if recursive {
+				inputs = append(inputs, grepTree(vfs, name, name, true, &result)...)
+			} else {
+				result.fail("grep: "+name+": Is a directory", 2)
+			}
+			continue
+		}
+		inputs = append(inputs, readInputs(vfs, []string{name}, stdin, "grep: %s: %s", &result)...)
+	}
+	opts.withNames = (len(operands) > 1 || recursive) && !flags['h'] || flags['H']
+
+	var output []string
+	matched, printed := false, false
+	for _, input := range inputs {
+		lines, found := grepInput(re, input, opts, &printed)
+		matched = matched || found
+		output = append(output, lines...)
+		if found && opts.quiet {
+			break
+		}
+	}
+
+	if !opts.quiet {
+		result.stdout = strings.Join(output, "\n")
+	}
+	switch {
+	case matched && (opts.quiet || result.exitCode == 0):
+		result.exitCode = 0
+	case result.exitCode != 0:
+		result.exitCode = 2
+	default:
+		result.exitCode = 1
+	}
+	return result
+}
+
+// grepTree reads every file under a directory for grep -r, naming them
+// from prefix. Symlinks inside the tree are only followed with -R.
+func grepTree(vfs *VirtualFileSystem, dir, prefix string, follow bool, result *commandResult) []textInput {
+	entries, err := vfs.ReadDir(dir)
+	if err != nil {
+		result.fail("grep: "+strings.TrimSuffix(prefix, "/")+": "+errorText(err), 2)
+		return nil
+	}
+
+	var inputs []textInput
+	for _, entry := range entries {
+		name := path.Join(dir, entry)
+		display := entry
+		if prefix != "" {
+			display = strings.TrimSuffix(prefix, "/") + "/" + entry
+		}
+		_, node, err := vfs.lstat(name)
+		if err == nil && node.isSymlink() {
+			if !follow {
+				continue
+			}
+			_, node, err = vfs.stat(name)
+		}
+		switch {
+		case err != nil:
+			result.fail("grep: "+display+": "+errorText(err), 2)
+		case node.isDir():
+			inputs = append(inputs, grepTree(vfs, name, display, follow, result)...)
+		default:
+			content, err := vfs.ReadFile(name)
+			if err != nil {
+				result.fail("grep: "+display+": "+errorText(err), 2)
+				continue
+			}
+			inputs = append(inputs, textInput{name: display, content: string(content)})
+		}
+	}
+	return inputs
+}
+
+// grepInput matches one input and renders its output lines
+func grepInput(re *regexp.Regexp, input textInput, opts grepOptions, printed *bool) ([]string, bool) {
+	name := input.name
+	if name == "" {
+		name = "(standard input)"
+	}
+	lines := textLines(input.content)
+
+	selected := make([]bool, len(lines))
+	count := 0
+	for i, line := range lines {
+		if re.MatchString(line) != opts.invert {
+			selected[i] = true
+			count++
+		}
+	}
+
+	switch {
+	case opts.quiet:
+		return nil, count > 0
+	case opts.listFiles:
+		if count > 0 {
+			return []string{name}, true
+		}
+		return nil, false
+	case opts.count:
+		if opts.withNames {
+			return []string{fmt.Sprintf("%s:%d", name, count)}, count > 0
+		}
+		return []string{strconv.Itoa(count)}, count > 0
+	}
+
+	// Matching lines of a binary file would garble the terminal
+	if !opts.text && count > 0 && isBinary([]byte(input.content)) {
+		return []string{"grep: " + name + ": binary file matches"}, true
+	}
+
+	prefix := func(i int, separator string) string {
+		var p string
+		if opts.withNames {
+			p = name + separator
+		}
+		if opts.lineNumbers {
+			p += strconv.Itoa(i+1) + separator
+		}
+		return p
+	}
+
+	var output []string
+	last := -1 // Last line printed, for "--" between context groups
+	for i := range lines {
+		if !selected[i] {
+			continue
+		}
+		from := max(i-opts.before, last+1)
+		if opts.context && *printed && (last < 0 || from > last+1) {
+			output = append(output, "--")
+		}
+		for j := from; j < i; j++ {
+			output = append(output, prefix(j, "-")+lines[j])
+		}
+
+		if opts.onlyMatching {
+			if !opts.invert {
+				for _, match := range re.FindAllString(lines[i], -1) {
+					if match != "" {
+						output = append(output, prefix(i, ":")+match)
+					}
+				}
+			}
+		} else {
+			output = append(output, prefix(i, ":")+lines[i])
+		}
+		last = i
+		*printed = true
+
+		// Trailing context stops at the next selected line, which prints itself
+		for j := i + 1; j <= i+opts.after && j < len(lines) && !selected[j]; j++ {
+			output = append(output, prefix(j, "-")+lines[j])
+			last = j
+		}
+	}
+	return output, count > 0
+}
+
+// compileGrepPattern builds the regexp for a grep pattern. Without -E it
+// is a POSIX basic regex, where \( \) \{ \} \| \+ \? are the operators
+// and the bare characters are literal.
+func compileGrepPattern(patterns string, extended, fixed, ignoreCase, word bool) (*regexp.Regexp, error) {
+	// Newline-separated patterns are alternatives
+	var alternatives []string
+	for _, pattern := range strings.Split(patterns, "\n") {
+		switch {
+		case fixed:
+			pattern = regexp.QuoteMeta(pattern)
+		case !extended:
+			pattern = basicToExtended(pattern)
+		}
+		alternatives = append(alternatives, pattern)
+	}
+	pattern := alternatives[0]
+	if len(alternatives) > 1 {
+		pattern = "(?:" + strings.Join(alternatives, ")|(?:") + ")"
+	}
+	pattern = strings.NewReplacer(`\<`, `\b`, `\>`, `\b`).Replace(pattern)
+	if word {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err == nil {
+		return re, nil
+	}
+	var syntaxErr *syntax.Error
+	if errors.As(err, &syntaxErr) {
+		switch syntaxErr.Code {
+		case syntax.ErrMissingBracket:
+			return nil, errors.New("Unmatched [, [^, [:, [., or [=")
+		case syntax.ErrMissingParen:
+			return nil, errors.New(`Unmatched ( or \(`)
+		case syntax.ErrUnexpectedParen:
+			return nil, errors.New(`Unmatched ) or \)`)
+		case syntax.ErrMissingRepeatArgument, syntax.ErrInvalidRepeatOp:
+			return nil, errors.New("Invalid preceding regular expression")
+		case syntax.ErrTrailingBackslash:
+			return nil, errors.New("Trailing backslash")
+		}
+	}
+	return nil, errors.New("Invalid regular expression")
+}
+
+// basicToExtended rewrites a POSIX basic regex in Go's syntax
+func basicToExtended(pattern string) string {
+	var out strings.Builder
+	inBracket := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+			out.WriteByte(c)
+		case c == '[':
+			inBracket = true
+			out.WriteByte(c)
+			// A ] right after [ or [^ is part of the set
+			if i+1 < len(pattern) && pattern[i+1] == '^' {
+				i++
+				out.WriteByte('^')
+			}
+			if i+1 < len(pattern) && pattern[i+1] == ']' {
+				i++
+				out.WriteString(`\]`)
+			}
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			if strings.IndexByte("(){}|+?", pattern[i]) >= 0 {
+				out.WriteByte(pattern[i])
+			} else {
+				out.WriteByte('\\')
+				out.WriteByte(pattern[i])
+			}
+		case strings.IndexByte("(){}|+?", c) >= 0:
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '*' && (i == 0 || pattern[i-1] == '^' && i == 1):
+			// A leading * is literal
+			out.WriteString(`\*`)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.String()
+}
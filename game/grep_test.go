@@ -0,0 +1,48 @@
+package game
+
+import "testing"
+
+// Expected output was recorded from GNU grep on the same files
+func TestGrep(t *testing.T) {
+	files := map[string]interface{}{
+		"log.txt":       "INFO start\nerror: disk full\nWARN low memory\nError: retry\ninfo done\nerrors: 2",
+		"data.txt":      "id=1 pass=abc123\nid=22 pass=xyz\nid=333 pass=\nfoo.bar\nfooXbar",
+		"dir/a.txt":     "secret in a\nnothing",
+		"dir/sub/b.txt": "another secret\nsecret again",
+		"dir/c.txt":     "plain",
+	}
+	runCommandTests(t, files, []commandTest{
+		{name: "fixed", command: "grep error log.txt", stdout: "error: disk full\nerrors: 2"},
+		{name: "-i", command: "grep -i error log.txt", stdout: "error: disk full\nError: retry\nerrors: 2"},
+		{name: "-v", command: "grep -v -i error log.txt", stdout: "INFO start\nWARN low memory\ninfo done"},
+		{name: "-n", command: "grep -n WARN log.txt", stdout: "3:WARN low memory"},
+		{name: "-c", command: "grep -ci error log.txt", stdout: "3"},
+		{name: "-w", command: "grep -w error log.txt", stdout: "error: disk full"},
+		{name: "-o", command: "grep -o 'id=[0-9]*' data.txt", stdout: "id=1\nid=22\nid=333"},
+		{name: "-E alternation", command: "grep -E 'INFO|WARN' log.txt", stdout: "INFO start\nWARN low memory"},
+		{name: "-E repetition", command: "grep -E 'id=[0-9]{2,}' data.txt", stdout: "id=22 pass=xyz\nid=333 pass="},
+		{name: "basic group", command: `grep 'id=\(22\|333\)' data.txt`, stdout: "id=22 pass=xyz\nid=333 pass="},
+		{name: "anchors", command: "grep '^info' log.txt", stdout: "info done"},
+		{name: "end anchor", command: "grep 'pass=$' data.txt", stdout: "id=333 pass="},
+		{name: "dot", command: "grep 'foo.bar' data.txt", stdout: "foo.bar\nfooXbar"},
+		{name: "-F", command: "grep -F 'foo.bar' data.txt", stdout: "foo.bar"},
+		{name: "bracket class", command: "grep '[[:digit:]][[:digit:]][[:digit:]]' data.txt", stdout: "id=1 pass=abc123\nid=333 pass="},
+		{name: "-e twice", command: "grep -e INFO -e WARN log.txt", stdout: "INFO start\nWARN low memory"},
+		{name: "-A", command: "grep -A 1 WARN log.txt", stdout: "WARN low memory\nError: retry"},
+		{name: "-B", command: "grep -B 1 WARN log.txt", stdout: "error: disk full\nWARN low memory"},
+		{name: "-C group separator", command: "grep -C 0 -i info log.txt", stdout: "INFO start\n--\ninfo done"},
+		// GNU grep -r walks directories in readdir order; ours sorts them
+		{name: "-r", command: "grep -r secret dir", stdout: "dir/a.txt:secret in a\ndir/sub/b.txt:another secret\ndir/sub/b.txt:secret again"},
+		{name: "-rl", command: "grep -rl secret dir", stdout: "dir/a.txt\ndir/sub/b.txt"},
+		{name: "-rh", command: "grep -rh secret dir", stdout: "secret in a\nanother secret\nsecret again"},
+		{name: "-rc", command: "grep -rc secret dir", stdout: "dir/a.txt:1\ndir/c.txt:0\ndir/sub/b.txt:2"},
+		{name: "multiple files", command: "grep -i error log.txt data.txt", stdout: "log.txt:error: disk full\nlog.txt:Error: retry\nlog.txt:errors: 2"},
+		{name: "no match", command: "grep nothing-here log.txt", exitCode: 1},
+		{name: "-q match", command: "grep -q WARN log.txt"},
+		{name: "missing file", command: "grep x nope", stderr: "grep: nope: No such file or directory", exitCode: 2},
+		{name: "directory", command: "grep secret dir", stderr: "grep: dir: Is a directory", exitCode: 2},
+		{name: "stdin", command: "cat log.txt | grep -c INFO", stdout: "1"},
+		{name: "bad regex", command: "grep -E 'a(' log.txt", stderr: "grep: Unmatched ( or \\(", exitCode: 2},
+		{name: "unmatched bracket", command: "grep '[a' log.txt", stderr: "grep: Unmatched [, [^, [:, [., or [=", exitCode: 2},
+	})
+}
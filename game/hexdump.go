@@ -0,0 +1,686 @@
+package game
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// readBytes gathers the input of a byte-oriented tool: its files one
+// after another, or stdin. Text is newline-terminated as a real file
+// would be; binary data is taken as is.
+func readBytes(vfs *VirtualFileSystem, operands []string, stdin *string, openError string, result *commandResult) []byte {
+	var data []byte
+	for _, input := range readInputs(vfs, operands, stdin, openError, result) {
+		data = append(data, streamBytes(input.content)...)
+	}
+	return data
+}
+
+// streamBytes is a stream's bytes: see terminated for text
+func streamBytes(content string) []byte {
+	if isBinary([]byte(content)) {
+		return []byte(content)
+	}
+	return []byte(terminated(content))
+}
+
+// streamString is the inverse of streamBytes: output for the next stage
+// or the terminal, where text leaves off its final newline
+func streamString(data []byte) string {
+	if isBinary(data) {
+		return string(data)
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+// printableByte is how dumps show a byte in their text column
+func printableByte(c byte) byte {
+	if c >= 0x20 && c < 0x7f {
+		return c
+	}
+	return '.'
+}
+
+// parseCount parses a C-style number (16, 0x10, 020) for an option
+func parseCount(value string) (int64, bool) {
+	n, err := strconv.ParseInt(value, 0, 64)
+	return n, err == nil && n >= 0
+}
+
+// xxdCommand implements xxd: a hex dump of a file or stdin, a C array
+// with -i, or with -r the file a dump describes. A second operand names
+// the output file.
+func xxdCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	for i, arg := range args {
+		if arg == "-ps" || arg == "-postscript" || arg == "-plain" {
+			args[i] = "-p"
+		}
+	}
+	flags, values, operands, usage := parseOptions("xxd", args, "bdipru", "cglos", nil)
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) > 2 {
+		return errorResult("Usage:\n       xxd [options] [infile [outfile]]\n    or\n       xxd -r [-s [-]offset] [-c cols] [-ps] [infile [outfile]]", 1)
+	}
+
+	numbers := map[rune]int64{'c': 16, 'g': 2, 'l': -1}
+	switch {
+	case flags['p']:
+		numbers['c'] = 30
+	case flags['b']:
+		numbers['c'], numbers['g'] = 6, 1
+	case flags['i']:
+		numbers['c'] = 12
+	}
+	var seek int64
+	for flag, value := range values {
+		if flag == 's' {
+			continue
+		}
+		n, ok := parseCount(value)
+		if !ok {
+			return errorResult("xxd: invalid number '"+value+"' for -"+string(flag), 1)
+		}
+		numbers[flag] = n
+	}
+	if value, set := values['s']; set {
+		n, ok := parseCount(strings.TrimPrefix(strings.TrimPrefix(value, "+"), "-"))
+		if !ok {
+			return errorResult("xxd: invalid number '"+value+"' for -s", 1)
+		}
+		seek = n
+		if strings.HasPrefix(value, "-") && !flags['r'] {
+			seek = -n
+		}
+	}
+	cols, group := int(numbers['c']), int(numbers['g'])
+	if cols > 256 && !flags['p'] {
+		return errorResult("xxd: invalid number of columns (max. 256).", 1)
+	}
+
+	var result commandResult
+	var input []string
+	if len(operands) > 0 {
+		input = operands[:1]
+	}
+	data := readBytes(vfs, input, stdin, "xxd: %s: %s", &result)
+	if result.exitCode != 0 {
+		result.exitCode = 2
+		return result
+	}
+
+	var output string
+	if flags['r'] {
+		output = streamString(xxdReverse(data, cols, flags['p'], seek))
+	} else {
+		if seek < 0 {
+			if seek += int64(len(data)); seek < 0 {
+				return errorResult("xxd: Sorry, cannot seek.", 4)
+			}
+		}
+		data = data[min(seek, int64(len(data))):]
+		if limit := numbers['l']; limit >= 0 && limit < int64(len(data)) {
+			data = data[:limit]
+		}
+		if flags['i'] {
+			name := ""
+			if len(operands) > 0 && operands[0] != "-" {
+				name = operands[0]
+			}
+			output = xxdInclude(data, cols, name, flags['u'])
+		} else {
+			output = xxdDump(data, cols, group, seek+numbers['o'], flags)
+		}
+	}
+
+	if len(operands) == 2 && operands[1] != "-" {
+		if err := vfs.WriteFile(operands[1], []byte(output)); err != nil {
+			return errorResult("xxd: "+operands[1]+": "+errorText(err), 2)
+		}
+		return result
+	}
+	result.stdout = output
+	return result
+}
+
+// xxdDump renders data as xxd does, starting the offsets at offset
+func xxdDump(data []byte, cols, group int, offset int64, flags map[rune]bool) string {
+	digits := "%02x"
+	if flags['u'] {
+		digits = "%02X"
+	}
+
+	if flags['p'] {
+		var lines []string
+		var line strings.Builder
+		for i, c := range data {
+			fmt.Fprintf(&line, digits, c)
+			if cols > 0 && (i+1)%cols == 0 || i == len(data)-1 {
+				lines = append(lines, line.String())
+				line.Reset()
+			}
+		}
+		return strings.Join(lines, "\n")
+	}
+
+	if cols <= 0 {
+		cols = 16
+	}
+	if group <= 0 {
+		group = cols
+	}
+	width := 2
+	if flags['b'] {
+		digits, width = "%08b", 8
+	}
+	address := "%08x:"
+	if flags['d'] {
+		address = "%08d:"
+	}
+
+	var lines []string
+	for start := 0; start < len(data); start += cols {
+		chunk := data[start:min(start+cols, len(data))]
+		var line strings.Builder
+		fmt.Fprintf(&line, address, offset+int64(start))
+		for i := 0; i < cols; i++ {
+			if i%group == 0 {
+				line.WriteByte(' ')
+			}
+			if i < len(chunk) {
+				fmt.Fprintf(&line, digits, chunk[i])
+			} else {
+				line.WriteString(strings.Repeat(" ", width))
+			}
+		}
+		line.WriteString("  ")
+		for _, c := range chunk {
+			line.WriteByte(printableByte(c))
+		}
+		lines = append(lines, line.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// xxdInclude renders data as a C array named after the file, as xxd -i
+// does; stdin gets just the array's contents
+func xxdInclude(data []byte, cols int, name string, upper bool) string {
+	digits := "0x%02x"
+	if upper {
+		digits = "0X%02X"
+	}
+	if cols <= 0 {
+		cols = 12
+	}
+
+	var lines []string
+	for start := 0; start < len(data); start += cols {
+		var bytes []string
+		for _, c := range data[start:min(start+cols, len(data))] {
+			bytes = append(bytes, fmt.Sprintf(digits, c))
+		}
+		lines = append(lines, "  "+strings.Join(bytes, ", "))
+	}
+	body := strings.Join(lines, ",\n")
+	if name == "" {
+		return body
+	}
+
+	// The variable is the file name with anything but letters and digits
+	// made an underscore, and a leading digit escaped
+	variable := []byte(name)
+	for i, c := range variable {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			variable[i] = '_'
+		}
+	}
+	if variable[0] >= '0' && variable[0] <= '9' {
+		variable = append([]byte("__"), variable...)
+	}
+	if body != "" {
+		body += "\n"
+	}
+	return fmt.Sprintf("unsigned char %s[] = {\n%s};\nunsigned int %s_len = %d;", variable, body, variable, len(data))
+}
+
+// xxdReverse turns a hex dump back into bytes, parsing it the way xxd -r
+// does: each line's offset, then hex pairs until the columns are full or
+// three non-hex characters in a row start the text column. Plain (-p)
+// dumps are just hex pairs.
+func xxdReverse(dump []byte, cols int, plain bool, base int64) []byte {
+	if cols <= 0 {
+		cols = 16
+	}
+	var out []byte
+	n1, n2, n3 := -1, 0, 0
+	p, ignore := cols, true
+	var want, have int64
+
+	for i := 0; i < len(dump); i++ {
+		c := dump[i]
+		if c == '\r' || plain && (c == ' ' || c == '\n' || c == '\t') {
+			continue
+		}
+		n3, n2, n1 = n2, n1, hexValue(c)
+		if n1 < 0 && ignore {
+			continue
+		}
+		ignore = false
+
+		// Reading the line's offset, up to the first non-hex character
+		if !plain && p >= cols {
+			if n1 < 0 {
+				p = 0
+				continue
+			}
+			want = want<<4 | int64(n1)
+			continue
+		}
+		have = base + want
+
+		skip := false
+		switch {
+		case n2 >= 0 && n1 >= 0:
+			for int64(len(out)) < have {
+				out = append(out, 0)
+			}
+			if have < int64(len(out)) {
+				out[have] = byte(n2<<4 | n1)
+			} else {
+				out = append(out, byte(n2<<4|n1))
+			}
+			want++
+			n1 = -1
+			if !plain {
+				p++
+				skip = p >= cols
+			}
+		case n1 < 0 && n2 < 0 && n3 < 0:
+			skip = true
+		}
+		if skip {
+			for i < len(dump) && dump[i] != '\n' {
+				i++
+			}
+			if i == len(dump) {
+				break
+			}
+			c = '\n'
+		}
+		if c == '\n' {
+			if !plain {
+				want = 0
+			}
+			p, ignore = cols, true
+		}
+	}
+	return out
+}
+
+// hexValue is a hex digit's value, or -1
+func hexValue(c byte) int {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0')
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return -1
+}
+
+// hexdumpCommand implements hexdump: 16-bit words by default, or the
+// canonical hex+ASCII layout with -C. Repeated lines collapse to "*"
+// unless -v.
+func hexdumpCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions("hexdump", args, "Cv", "ns", map[string]rune{
+		"canonical": 'C', "no-squeezing": 'v', "length": 'n', "skip": 's'})
+	if usage != nil {
+		return *usage
+	}
+
+	var result commandResult
+	data := readBytes(vfs, operands, stdin, "hexdump: %s: %s", &result)
+	skip, length := int64(0), int64(-1)
+	for flag, number := range map[rune]*int64{'s': &skip, 'n': &length} {
+		if value, set := values[flag]; set {
+			n, ok := parseCount(value)
+			if !ok {
+				return errorResult("hexdump: invalid number: '"+value+"'", 1)
+			}
+			*number = n
+		}
+	}
+	data = data[min(skip, int64(len(data))):]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	if len(data) == 0 {
+		return result
+	}
+
+	line := func(chunk []byte) string {
+		var out strings.Builder
+		if !flags['C'] {
+			for i := 0; i < len(chunk); i += 2 {
+				word := uint16(chunk[i])
+				if i+1 < len(chunk) {
+					word |= uint16(chunk[i+1]) << 8
+				}
+				fmt.Fprintf(&out, " %04x", word)
+			}
+			return out.String()
+		}
+		out.WriteByte(' ')
+		for i := 0; i < 16; i++ {
+			if i == 8 {
+				out.WriteByte(' ')
+			}
+			if i < len(chunk) {
+				fmt.Fprintf(&out, " %02x", chunk[i])
+			} else {
+				out.WriteString("   ")
+			}
+		}
+		out.WriteString("  |")
+		for _, c := range chunk {
+			out.WriteByte(printableByte(c))
+		}
+		out.WriteByte('|')
+		return out.String()
+	}
+
+	address := "%07x"
+	if flags['C'] {
+		address = "%08x"
+	}
+	lines := squeezeLines(data, 16, !flags['v'], func(offset int, chunk []byte) string {
+		return fmt.Sprintf(address, skip+int64(offset)) + line(chunk)
+	})
+	lines = append(lines, fmt.Sprintf(address, skip+int64(len(data))))
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// squeezeLines renders data a line of width bytes at a time, replacing
+// runs of lines identical to the one before with a single "*"
+func squeezeLines(data []byte, width int, squeeze bool, render func(offset int, chunk []byte) string) []string {
+	var lines []string
+	var previous []byte
+	starred := false
+	for start := 0; start < len(data); start += width {
+		chunk := data[start:min(start+width, len(data))]
+		if squeeze && previous != nil && len(chunk) == width && string(chunk) == string(previous) {
+			if !starred {
+				lines = append(lines, "*")
+				starred = true
+			}
+			continue
+		}
+		lines = append(lines, render(start, chunk))
+		previous, starred = chunk, false
+	}
+	return lines
+}
+
+// odType is one output format of od, such as x2 or c
+type odType struct {
+	kind    byte // One of a, c, d, o, u, x
+	size    int  // Bytes per field
+	width   int  // Characters per field, before padding
+	trailer bool // z: an ASCII column at the end of the line
+}
+
+// odWidths are the field widths of od's integer types by size
+var odWidths = map[byte]map[int]int{
+	'd': {1: 4, 2: 6, 4: 11, 8: 20},
+	'o': {1: 3, 2: 6, 4: 11, 8: 22},
+	'u': {1: 3, 2: 5, 4: 10, 8: 20},
+	'x': {1: 2, 2: 4, 4: 8, 8: 16},
+}
+
+// parseOdType parses a -t argument such as x1z, o2 or c
+func parseOdType(spec string) ([]odType, string) {
+	var types []odType
+	for rest := spec; rest != ""; {
+		kind := rest[0]
+		rest = rest[1:]
+		switch kind {
+		case 'c':
+			types = append(types, odType{kind: 'c', size: 1, width: 3})
+			continue
+		case 'd', 'o', 'u', 'x':
+		default:
+			return nil, fmt.Sprintf("od: invalid character '%c' in type string '%s'", kind, spec)
+		}
+
+		size := 4
+		if rest != "" {
+			if named, exists := map[byte]int{'C': 1, 'S': 2, 'I': 4, 'L': 8}[rest[0]]; exists {
+				size, rest = named, rest[1:]
+			} else if digits := len(rest) - len(strings.TrimLeft(rest, "0123456789")); digits > 0 {
+				size, _ = strconv.Atoi(rest[:digits])
+				rest = rest[digits:]
+				if odWidths[kind][size] == 0 {
+					return nil, fmt.Sprintf("od: invalid type string '%s';\nthis system doesn't provide a %d-byte integral type", spec, size)
+				}
+			}
+		}
+		t := odType{kind: kind, size: size, width: odWidths[kind][size]}
+		if strings.HasPrefix(rest, "z") {
+			t.trailer, rest = true, rest[1:]
+		}
+		types = append(types, t)
+	}
+	return types, ""
+}
+
+// odCommand implements od: octal words by default, or the formats given
+// with -t and its shorthands (-b -c -d -o -s -x)
+func odCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	shorthands := map[byte]string{'b': "o1", 'c': "c", 'd': "u2", 'o': "o2", 's': "d2", 'x': "x2"}
+	usage := func(format string, arg interface{}) commandResult {
+		return errorResult(fmt.Sprintf("od: "+format+"\nTry 'od --help' for more information.", arg), 1)
+	}
+
+	var types []odType
+	var operands []string
+	radix := byte('o')
+	skip, limit, width := int64(0), int64(-1), int64(16)
+	squeeze := true
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			operands = append(operands, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			operands = append(operands, arg)
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			flag := arg[j]
+			if spec, exists := shorthands[flag]; exists {
+				parsed, _ := parseOdType(spec)
+				types = append(types, parsed...)
+				continue
+			}
+			if flag == 'v' {
+				squeeze = false
+				continue
+			}
+			if !strings.ContainsRune("AjNtw", rune(flag)) {
+				return usage("invalid option -- '%c'", flag)
+			}
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 == len(args) {
+					return usage("option requires an argument -- '%c'", flag)
+				}
+				i++
+				value = args[i]
+			}
+			switch flag {
+			case 'A':
+				if len(value) != 1 || !strings.Contains("dnox", value) {
+					return usage("invalid output address radix '%s'; it must be one character from [doxn]", value)
+				}
+				radix = value[0]
+			case 't':
+				parsed, err := parseOdType(value)
+				if err != "" {
+					return errorResult(err, 1)
+				}
+				types = append(types, parsed...)
+			default:
+				n, ok := parseCount(value)
+				if !ok || flag == 'w' && n == 0 {
+					return errorResult("od: invalid argument '"+value+"'", 1)
+				}
+				switch flag {
+				case 'j':
+					skip = n
+				case 'N':
+					limit = n
+				case 'w':
+					width = n
+				}
+			}
+			break
+		}
+	}
+	if len(types) == 0 {
+		types, _ = parseOdType("o2")
+	}
+
+	var result commandResult
+	inputs := readInputs(vfs, operands, stdin, "od: %s: %s", &result)
+	if len(inputs) == 0 && result.exitCode != 0 {
+		// Nothing could be opened, so there's no dump, not even an offset
+		return result
+	}
+	var data []byte
+	for _, input := range inputs {
+		data = append(data, streamBytes(input.content)...)
+	}
+	if skip > int64(len(data)) {
+		result.fail("od: cannot skip past end of combined input", 1)
+		return result
+	}
+	data = data[skip:]
+	if limit >= 0 && limit < int64(len(data)) {
+		data = data[:limit]
+	}
+
+	address := func(offset int) string {
+		switch radix {
+		case 'd':
+			return fmt.Sprintf("%07d", skip+int64(offset))
+		case 'x':
+			return fmt.Sprintf("%06x", skip+int64(offset))
+		case 'n':
+			return ""
+		}
+		return fmt.Sprintf("%07o", skip+int64(offset))
+	}
+	indent := strings.Repeat(" ", len(address(0)))
+
+	// Every format's line is padded to the widest one's, as GNU od does
+	perLine := int(width)
+	lineWidth := 0
+	for _, t := range types {
+		lineWidth = max(lineWidth, (t.width+1)*(perLine/t.size))
+	}
+
+	lines := squeezeLines(data, perLine, squeeze, func(offset int, chunk []byte) string {
+		// A short last line is padded with zeros to whole fields
+		largest := 1
+		for _, t := range types {
+			largest = max(largest, t.size)
+		}
+		padded := append([]byte(nil), chunk...)
+		for len(padded)%largest != 0 {
+			padded = append(padded, 0)
+		}
+
+		var out []string
+		for i, t := range types {
+			prefix := indent
+			if i == 0 {
+				prefix = address(offset)
+			}
+			out = append(out, prefix+odLine(t, padded, chunk, perLine, lineWidth))
+		}
+		return strings.Join(out, "\n")
+	})
+	if radix != 'n' {
+		lines = append(lines, address(len(data)))
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// odLine formats one line of data in one of od's formats
+func odLine(t odType, padded, chunk []byte, perLine, lineWidth int) string {
+	fields := perLine / t.size
+	pad := lineWidth - t.width*fields
+	blank := (perLine - len(chunk)) / t.size // A partly filled field still prints
+
+	var out strings.Builder
+	remaining := pad
+	for i := fields; i > blank; i-- {
+		next := pad * (i - 1) / fields
+		at := (fields - i) * t.size
+		fmt.Fprintf(&out, "%*s", t.width+remaining-next, odField(t, padded[at:at+t.size]))
+		remaining = next
+	}
+	if t.trailer {
+		out.WriteString(strings.Repeat(" ", blank*(t.width+1)))
+		out.WriteString("  >")
+		for _, c := range chunk {
+			out.WriteByte(printableByte(c))
+		}
+		out.WriteByte('<')
+	}
+	return out.String()
+}
+
+// odField formats one field of an od line
+func odField(t odType, field []byte) string {
+	if t.kind == 'c' {
+		c := field[0]
+		if escape, exists := map[byte]string{0: `\0`, '\a': `\a`, '\b': `\b`, '\f': `\f`, '\n': `\n`, '\r': `\r`, '\t': `\t`, '\v': `\v`}[c]; exists {
+			return escape
+		}
+		if c >= 0x20 && c < 0x7f {
+			return string(c)
+		}
+		return fmt.Sprintf("%03o", c)
+	}
+
+	var value uint64
+	switch t.size {
+	case 1:
+		value = uint64(field[0])
+	case 2:
+		value = uint64(binary.LittleEndian.Uint16(field))
+	case 4:
+		value = uint64(binary.LittleEndian.Uint32(field))
+	case 8:
+		value = binary.LittleEndian.Uint64(field)
+	}
+	switch t.kind {
+	case 'o':
+		return fmt.Sprintf("%0*o", t.width, value)
+	case 'x':
+		return fmt.Sprintf("%0*x", t.width, value)
+	case 'd':
+		shift := 64 - 8*t.size
+		return strconv.FormatInt(int64(value<<shift)>>shift, 10)
+	}
+	return strconv.FormatUint(value, 10)
+}
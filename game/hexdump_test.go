@@ -0,0 +1,67 @@
+package game
+
+import "testing"
+
+// Expected output was recorded from xxd and GNU od on the same files
+func TestHexTools(t *testing.T) {
+	files := map[string]interface{}{
+		"bytes.bin": map[string]interface{}{"content": "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627", "encoding": "hex"},
+		"image.png": map[string]interface{}{"content": "89504e470d0a1a0a000000000000000049484452fafbfcfdfeff", "encoding": "hex"},
+		"zeros.bin": map[string]interface{}{"content": "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000041", "encoding": "hex"},
+		"hello.txt": "Hello, World!\nsecond line",
+		"dump.hex":  "00000000: 6869 2074 6865 7265 0a                   hi there.",
+		"plain.hex": "666c61677b7878647d0a",
+		"1st.bin":   map[string]interface{}{"content": "", "encoding": "hex"},
+	}
+	runCommandTests(t, files, []commandTest{
+		{name: "xxd", command: "xxd hello.txt", stdout: "00000000: 4865 6c6c 6f2c 2057 6f72 6c64 210a 7365  Hello, World!.se\n00000010: 636f 6e64 206c 696e 650a                 cond line."},
+		{name: "xxd binary", command: "xxd image.png", stdout: "00000000: 8950 4e47 0d0a 1a0a 0000 0000 0000 0000  .PNG............\n00000010: 4948 4452 fafb fcfd feff                 IHDR......"},
+		{name: "xxd squeeze", command: "xxd zeros.bin", stdout: "00000000: 0000 0000 0000 0000 0000 0000 0000 0000  ................\n00000010: 0000 0000 0000 0000 0000 0000 0000 0000  ................\n00000020: 0000 0000 0000 0000 0000 0000 0000 0000  ................\n00000030: 41                                       A"},
+		{name: "xxd -c", command: "xxd -c 8 hello.txt", stdout: "00000000: 4865 6c6c 6f2c 2057  Hello, W\n00000008: 6f72 6c64 210a 7365  orld!.se\n00000010: 636f 6e64 206c 696e  cond lin\n00000018: 650a                 e."},
+		{name: "xxd -g", command: "xxd -g 1 -l 16 bytes.bin", stdout: "00000000: 00 01 02 03 04 05 06 07 08 09 0a 0b 0c 0d 0e 0f  ................"},
+		{name: "xxd -l -s", command: "xxd -s 4 -l 6 bytes.bin", stdout: "00000004: 0405 0607 0809                           ......"},
+		{name: "xxd -p", command: "xxd -p bytes.bin", stdout: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d\n1e1f2021222324252627"},
+		{name: "xxd -u", command: "xxd -u image.png", stdout: "00000000: 8950 4E47 0D0A 1A0A 0000 0000 0000 0000  .PNG............\n00000010: 4948 4452 FAFB FCFD FEFF                 IHDR......"},
+		{name: "xxd -b", command: "xxd -b -l 4 hello.txt", stdout: "00000000: 01001000 01100101 01101100 01101100                    Hell"},
+		{name: "xxd -i", command: "xxd -i image.png", stdout: "unsigned char image_png[] = {\n  0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a, 0x00, 0x00, 0x00, 0x00,\n  0x00, 0x00, 0x00, 0x00, 0x49, 0x48, 0x44, 0x52, 0xfa, 0xfb, 0xfc, 0xfd,\n  0xfe, 0xff\n};\nunsigned int image_png_len = 26;"},
+		{name: "xxd -i -c", command: "xxd -i -c 4 hello.txt", stdout: "unsigned char hello_txt[] = {\n  0x48, 0x65, 0x6c, 0x6c,\n  0x6f, 0x2c, 0x20, 0x57,\n  0x6f, 0x72, 0x6c, 0x64,\n  0x21, 0x0a, 0x73, 0x65,\n  0x63, 0x6f, 0x6e, 0x64,\n  0x20, 0x6c, 0x69, 0x6e,\n  0x65, 0x0a\n};\nunsigned int hello_txt_len = 26;"},
+		{name: "xxd -i -u stdin", command: "cat image.png | xxd -i -u", stdout: "  0X89, 0X50, 0X4E, 0X47, 0X0D, 0X0A, 0X1A, 0X0A, 0X00, 0X00, 0X00, 0X00,\n  0X00, 0X00, 0X00, 0X00, 0X49, 0X48, 0X44, 0X52, 0XFA, 0XFB, 0XFC, 0XFD,\n  0XFE, 0XFF"},
+		{name: "xxd -r", command: "xxd -r dump.hex", stdout: "hi there"},
+		{name: "xxd -r -p", command: "xxd -r -p plain.hex", stdout: "flag{xxd}"},
+		{name: "xxd roundtrip", command: "xxd image.png | xxd -r | xxd -p", stdout: "89504e470d0a1a0a000000000000000049484452fafbfcfdfeff"},
+		{name: "xxd stdin", command: "cat hello.txt | xxd -l 5", stdout: "00000000: 4865 6c6c 6f                             Hello"},
+		{name: "xxd missing", command: "xxd nope", stderr: "xxd: nope: No such file or directory", exitCode: 2},
+		{name: "xxd -i empty", command: "xxd -i 1st.bin", stdout: "unsigned char __1st_bin[] = {\n};\nunsigned int __1st_bin_len = 0;"},
+		{name: "od", command: "od hello.txt", stdout: "0000000 062510 066154 026157 053440 071157 062154 005041 062563\n0000020 067543 062156 066040 067151 005145\n0000032"},
+		{name: "od -c", command: "od -c hello.txt", stdout: "0000000   H   e   l   l   o   ,       W   o   r   l   d   !  \\n   s   e\n0000020   c   o   n   d       l   i   n   e  \\n\n0000032"},
+		{name: "od -An -tx1", command: "od -An -tx1 image.png", stdout: " 89 50 4e 47 0d 0a 1a 0a 00 00 00 00 00 00 00 00\n 49 48 44 52 fa fb fc fd fe ff"},
+		{name: "od -t x1z", command: "od -A x -t x1z image.png", stdout: "000000 89 50 4e 47 0d 0a 1a 0a 00 00 00 00 00 00 00 00  >.PNG............<\n000010 49 48 44 52 fa fb fc fd fe ff                    >IHDR......<\n00001a"},
+		{name: "od squeeze", command: "od -t x1 zeros.bin", stdout: "0000000 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n*\n0000060 41\n0000061"},
+		{name: "od -v", command: "od -v -t x1 zeros.bin", stdout: "0000000 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n0000020 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n0000040 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00 00\n0000060 41\n0000061"},
+		{name: "od -b", command: "od -b -N 4 hello.txt", stdout: "0000000 110 145 154 154\n0000004"},
+		{name: "od -j -N", command: "od -A d -j 2 -N 6 -t u1 bytes.bin", stdout: "0000002   2   3   4   5   6   7\n0000008"},
+		{name: "od -t d2", command: "od -t d2 image.png", stdout: "0000000  20617  18254   2573   2586      0      0      0      0\n0000020  18505  21060  -1030   -516     -2\n0000032"},
+		{name: "od -w", command: "od -w8 -t x1 -N 16 bytes.bin", stdout: "0000000 00 01 02 03 04 05 06 07\n0000010 08 09 0a 0b 0c 0d 0e 0f\n0000020"},
+		{name: "od missing", command: "od nope", stderr: "od: nope: No such file or directory", exitCode: 1},
+	})
+}
+
+// hexdump isn't in coreutils; expected output follows util-linux hexdump
+func TestHexdump(t *testing.T) {
+	files := map[string]interface{}{
+		"hello.txt": "Hello, World!\nsecond line",
+		"zeros.bin": map[string]interface{}{"content": "00000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000041", "encoding": "hex"},
+		"odd.bin":   map[string]interface{}{"content": "000102", "encoding": "hex"},
+	}
+	runCommandTests(t, files, []commandTest{
+		{name: "-C", command: "hexdump -C hello.txt", stdout: "00000000  48 65 6c 6c 6f 2c 20 57  6f 72 6c 64 21 0a 73 65  |Hello, World!.se|\n00000010  63 6f 6e 64 20 6c 69 6e  65 0a                    |cond line.|\n0000001a"},
+		{name: "hd", command: "hd hello.txt", stdout: "00000000  48 65 6c 6c 6f 2c 20 57  6f 72 6c 64 21 0a 73 65  |Hello, World!.se|\n00000010  63 6f 6e 64 20 6c 69 6e  65 0a                    |cond line.|\n0000001a"},
+		{name: "words", command: "hexdump hello.txt", stdout: "0000000 6548 6c6c 2c6f 5720 726f 646c 0a21 6573\n0000010 6f63 646e 6c20 6e69 0a65\n000001a"},
+		{name: "odd length", command: "hexdump odd.bin", stdout: "0000000 0100 0002\n0000003"},
+		{name: "squeeze", command: "hexdump -C zeros.bin", stdout: "00000000  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n*\n00000030  41                                                |A|\n00000031"},
+		{name: "-v", command: "hexdump -v -C zeros.bin", stdout: "00000000  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n00000010  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n00000020  00 00 00 00 00 00 00 00  00 00 00 00 00 00 00 00  |................|\n00000030  41                                                |A|\n00000031"},
+		{name: "-s -n", command: "hexdump -C -s 4 -n 6 hello.txt", stdout: "00000004  6f 2c 20 57 6f 72                                 |o, Wor|\n0000000a"},
+		{name: "stdin", command: "cat hello.txt | hexdump -C -n 5", stdout: "00000000  48 65 6c 6c 6f                                    |Hello|\n00000005"},
+		{name: "missing", command: "hexdump nope", stderr: "hexdump: nope: No such file or directory", exitCode: 1},
+	})
+}
@@ -0,0 +1,739 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/netip"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHostname names the player's machine when the level doesn't
+const defaultHostname = "codeheist"
+
+// Addresses of a level's network: the player's machine, then each host
+// in order unless it has its own
+const (
+	playerAddress    = "10.0.0.10"
+	firstHostAddress = 11
+)
+
+// maxPasswordTries is how many passwords ssh asks for before giving up
+const maxPasswordTries = 3
+
+// LevelHost is another machine on the level's network, with its own
+// files, accounts, processes and services. Players reach it with ssh.
+type LevelHost struct {
+	Name       string                 `json:"name"`
+	Address    string                 `json:"address,omitempty"`    // IPv4 address, 10.0.0.11 onwards by default
+	Users      []HostUser             `json:"users"`                // Accounts; the first owns relative paths
+	Filesystem map[string]interface{} `json:"filesystem,omitempty"` // Like a level's, relative to the first user's home
+	Processes  []LevelProcess         `json:"processes,omitempty"`
+	Services   []LevelService         `json:"services,omitempty"` // Listening besides ssh
+	Motd       string                 `json:"motd,omitempty"`     // Shown on login
+}
+
+// HostUser is an account on a host
+type HostUser struct {
+	Name     string   `json:"name"`
+	Password string   `json:"password,omitempty"` // Empty for accounts that only take keys
+	Keys     []string `json:"keys,omitempty"`     // Private keys that log in, as the key files read
+}
+
+// sshHop is the shell a player left by logging in to a machine, and the
+// shell that machine had before, to put back on logout
+type sshHop struct {
+	vfs     *VirtualFileSystem
+	user    string
+	home    string
+	cwd     string
+	machine *VirtualFileSystem
+	shell   *process
+	host    string // As the player typed it, for "Connection to host closed."
+}
+
+// sshLogin is an ssh or scp waiting for the player to type a password
+type sshLogin struct {
+	machine *VirtualFileSystem
+	user    string
+	host    string
+	tries   int
+	then    func() (commandResult, bool) // Runs once the password is right
+}
+
+func (l *sshLogin) prompt() string { return l.user + "@" + l.host + "'s password: " }
+
+// homeDir is where a user's home directory is on any machine
+func homeDir(user string) string {
+	if user == "root" {
+		return "/root"
+	}
+	return "/home/" + user
+}
+
+// hostAddress is a level host's address, the one it's given or the next
+// free one after the player's
+func hostAddress(i int, host *LevelHost) string {
+	if host.Address != "" {
+		return host.Address
+	}
+	return "10.0.0." + strconv.Itoa(firstHostAddress+i)
+}
+
+// account is the machine's account for a user, or nil
+func (vfs *VirtualFileSystem) account(user string) *HostUser {
+	for i := range vfs.accounts {
+		if vfs.accounts[i].Name == user {
+			return &vfs.accounts[i]
+		}
+	}
+	return nil
+}
+
+// connect names the player's machine and, when the level has other
+// hosts, starts them and puts every machine on one network
+func (vfs *VirtualFileSystem) connect(level *Level) {
+	if level.Hostname != "" {
+		vfs.hostname = level.Hostname
+	}
+	if len(level.Hosts) == 0 {
+		return
+	}
+
+	vfs.address = playerAddress
+	network := map[string]*VirtualFileSystem{vfs.hostname: vfs}
+	for i := range level.Hosts {
+		host := &level.Hosts[i]
+		network[host.Name] = newHost(level.ID, host, hostAddress(i, host))
+	}
+
+	// Every machine can look the others up in /etc/hosts
+	machines := make([]*VirtualFileSystem, 0, len(network))
+	for _, machine := range network {
+		machines = append(machines, machine)
+	}
+	sortByAddress(machines)
+	hosts := "127.0.0.1\tlocalhost"
+	for _, machine := range machines {
+		hosts += "\n" + machine.address + "\t" + machine.hostname
+	}
+	for _, machine := range machines {
+		machine.network = network
+		for name, content := range map[string]string{"/etc/hostname": machine.hostname, "/etc/hosts": hosts} {
+			if _, exists := machine.files[name]; !exists {
+				machine.install(name, &fileNode{content: []byte(content), mode: defaultFileMode, owner: "root"})
+			}
+		}
+	}
+}
+
+// sortByAddress orders machines by IP address
+func sortByAddress(machines []*VirtualFileSystem) {
+	sort.Slice(machines, func(i, j int) bool {
+		a, _ := netip.ParseAddr(machines[i].address)
+		b, _ := netip.ParseAddr(machines[j].address)
+		return a.Less(b)
+	})
+}
+
+// newHost builds one of a level's other machines
+func newHost(levelID int, host *LevelHost, address string) *VirtualFileSystem {
+	user := "root"
+	if len(host.Users) > 0 {
+		user = host.Users[0].Name
+	}
+	machine := NewVirtualFS(user)
+	machine.hostname, machine.address = host.Name, address
+	machine.accounts = host.Users
+	machine.motd = host.Motd
+	for _, account := range host.Users {
+		if home := homeDir(account.Name); machine.files[home] == nil {
+			machine.install(home, &fileNode{mode: os.ModeDir | defaultDirMode, owner: account.Name})
+		}
+	}
+
+	for filename, value := range host.Filesystem {
+		node, err := levelFileNode(value)
+		if err != nil {
+			log.Printf("⚠️ Level %d: host %s: skipping %s: %v", levelID, host.Name, filename, err)
+			continue
+		}
+		name := machine.homePath(filename)
+		if node.owner == "" {
+			// Files in an account's home directory are that user's
+			for _, account := range host.Users {
+				if home := homeDir(account.Name); strings.HasPrefix(name, home+"/") {
+					node.owner = account.Name
+				}
+			}
+		}
+		machine.install(name, node)
+	}
+	machine.seedProcesses(levelID, host.Processes)
+	machine.services = append(machine.services, host.Services...)
+	return machine
+}
+
+// as runs fn on the machine as one of its users, from their home
+// directory, then puts back whoever was using it
+func (vfs *VirtualFileSystem) as(user string, fn func()) {
+	savedUser, savedHome, savedCwd := vfs.user, vfs.home, vfs.cwd
+	vfs.user, vfs.home, vfs.cwd = user, homeDir(user), homeDir(user)
+	fn()
+	vfs.user, vfs.home, vfs.cwd = savedUser, savedHome, savedCwd
+}
+
+// enter logs the session in to a machine as a user, keeping the shell
+// it leaves to come back to
+func (s *Session) enter(machine *VirtualFileSystem, user, host string) {
+	from := s.VirtualFS
+	s.hops = append(s.hops, sshHop{vfs: from, user: from.user, home: from.home, cwd: from.cwd,
+		machine: machine, shell: machine.procs.procs[shellPID], host: host})
+
+	machine.user, machine.home = user, homeDir(user)
+	machine.cwd = "/"
+	if node := machine.files[machine.home]; node != nil && node.isDir() {
+		machine.cwd = machine.home
+	}
+	machine.exit(shellPID)
+	machine.startShell()
+	s.VirtualFS = machine
+}
+
+// leave logs out of the machine the session is on, back to the shell
+// it came from, and says where the connection went
+func (s *Session) leave() string {
+	hop := s.hops[len(s.hops)-1]
+	s.hops = s.hops[:len(s.hops)-1]
+	hop.machine.exit(shellPID)
+	if hop.shell != nil {
+		hop.machine.spawn(hop.shell)
+	}
+	s.VirtualFS = hop.vfs
+	s.VirtualFS.user, s.VirtualFS.home, s.VirtualFS.cwd = hop.user, hop.home, hop.cwd
+	return "Connection to " + hop.host + " closed."
+}
+
+// leaveAll logs out of every machine back to the player's own
+func (s *Session) leaveAll() {
+	for len(s.hops) > 0 {
+		s.leave()
+	}
+	s.login = nil
+}
+
+// logoutCommand implements exit and logout in a shell reached by ssh
+func logoutCommand(session *Session, command string) commandResult {
+	if len(session.hops) == 0 {
+		return errorResult("command not found: "+command, 127)
+	}
+	return stdoutResult("logout\n" + session.leave())
+}
+
+// hostnameCommand implements hostname [-f|-s|-i|-I]
+func hostnameCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("hostname", args, "fsiIA", map[string]rune{
+		"fqdn": 'f', "long": 'f', "short": 's', "ip-address": 'i', "all-ip-addresses": 'I'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) > 0 {
+		return errorResult("hostname: you must be root to change the host name", 1)
+	}
+	switch {
+	case flags['i']:
+		return stdoutResult(vfs.address)
+	case flags['I']:
+		return stdoutResult(vfs.address + " ")
+	}
+	return stdoutResult(vfs.hostname)
+}
+
+// sshUsage is ssh's usage message
+const sshUsage = `usage: ssh [-46AaCfGgKkMNnqsTtVvXxYy] [-B bind_interface] [-b bind_address]
+           [-c cipher_spec] [-D [bind_address:]port] [-E log_file]
+           [-e escape_char] [-F configfile] [-I pkcs11] [-i identity_file]
+           [-J destination] [-L address] [-l login_name] [-m mac_spec]
+           [-O ctl_cmd] [-o option] [-P tag] [-p port] [-R address]
+           [-S ctl_path] [-W host:port] [-w local_tun[:remote_tun]]
+           destination [command [argument ...]]`
+
+// sshOptions are the options ssh and scp share
+type sshOptions struct {
+	identities []string
+	port       int
+	login      string
+	recursive  bool // scp -r
+}
+
+// parseSSHOptions reads options around the destination and up to the
+// remote command for ssh, or anywhere for scp. portFlag is p for ssh
+// and P for scp.
+func parseSSHOptions(command string, args []string, portFlag byte, valued string) (sshOptions, []string, *commandResult) {
+	options := sshOptions{port: 22}
+	var operands []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			operands = append(operands, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			// ssh takes options after the destination, up to the command
+			if command == "ssh" && len(operands) > 0 {
+				operands = append(operands, args[i:]...)
+				break
+			}
+			operands = append(operands, arg)
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			option := arg[j]
+			if !strings.ContainsRune(valued, rune(option)) {
+				switch {
+				case option == 'r' && command == "scp":
+					options.recursive = true
+				case option == 'V' && command == "ssh":
+					usage := errorResult("OpenSSH_9.6p1 Ubuntu-3ubuntu13.5, OpenSSL 3.0.13 30 Jan 2024", 0)
+					return options, nil, &usage
+				case strings.IndexByte("46ACNqTtvp", option) < 0:
+					message := command + ": illegal option -- " + string(option)
+					if command == "ssh" {
+						message = "ssh: illegal option -- " + string(option) + "\n" + sshUsage
+					}
+					usage := errorResult(message, 255)
+					return options, nil, &usage
+				}
+				continue
+			}
+
+			value := arg[j+1:]
+			if value == "" {
+				if i+1 >= len(args) {
+					usage := errorResult(command+": option requires an argument -- "+string(option), 255)
+					return options, nil, &usage
+				}
+				i++
+				value = args[i]
+			}
+			switch option {
+			case 'i':
+				options.identities = append(options.identities, value)
+			case portFlag:
+				port, err := strconv.Atoi(value)
+				if err != nil || port < 1 || port > 65535 {
+					usage := errorResult("Bad port '"+value+"'", 255)
+					return options, nil, &usage
+				}
+				options.port = port
+			case 'l':
+				options.login = value
+			}
+			break
+		}
+	}
+	return options, operands, nil
+}
+
+// splitDestination reads [user@]host, or ssh://[user@]host[:port]
+func splitDestination(destination string, options *sshOptions, current string) (user, host string) {
+	if rest, found := strings.CutPrefix(destination, "ssh://"); found {
+		destination = rest
+		if at := strings.LastIndex(rest, ":"); at > strings.LastIndex(rest, "@") {
+			if port, err := strconv.Atoi(rest[at+1:]); err == nil {
+				options.port = port
+				destination = rest[:at]
+			}
+		}
+	}
+	user, host = current, destination
+	if options.login != "" {
+		user = options.login
+	}
+	if at := strings.LastIndex(destination, "@"); at >= 0 {
+		user, host = destination[:at], destination[at+1:]
+	}
+	return user, host
+}
+
+// speaksSSH reports whether a service is an SSH daemon
+func (s *LevelService) speaksSSH() bool {
+	return s.Behavior == ServiceBanner && strings.HasPrefix(s.Reply, "SSH-")
+}
+
+// dial finds the SSH daemon a command connects to, or says why it can't
+func (vfs *VirtualFileSystem) dial(host string, port int) (*VirtualFileSystem, *commandResult) {
+	machine, _, found := vfs.resolveHost(host)
+	if !found {
+		failure := errorResult("ssh: Could not resolve hostname "+strings.ToLower(host)+": Name or service not known", 255)
+		return nil, &failure
+	}
+	s := machine.listener(port)
+	if s == nil {
+		failure := errorResult(fmt.Sprintf("ssh: connect to host %s port %d: Connection refused", host, port), 255)
+		return nil, &failure
+	}
+	if !s.speaksSSH() {
+		failure := errorResult(fmt.Sprintf("kex_exchange_identification: Connection closed by remote host\nConnection closed by %s port %d", machine.address, port), 255)
+		return nil, &failure
+	}
+	return machine, nil
+}
+
+// unprotectedKey is ssh's refusal of a key others can read
+const unprotectedKey = `@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+@         WARNING: UNPROTECTED PRIVATE KEY FILE!          @
+@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@@
+Permissions %04o for '%s' are too open.
+It is required that your private key files are NOT accessible by others.
+This private key will be ignored.
+Load key "%s": bad permissions`
+
+// authenticate tries the player's keys against an account, as ssh does
+// before asking for a password. It reports whether one was accepted,
+// with anything ssh warned about on the way.
+func (vfs *VirtualFileSystem) authenticate(machine *VirtualFileSystem, user string, identities []string) (bool, []string) {
+	explicit := len(identities) > 0
+	if !explicit {
+		for _, name := range []string{"id_rsa", "id_ecdsa", "id_ed25519"} {
+			identities = append(identities, "~/.ssh/"+name)
+		}
+	}
+
+	var warnings []string
+	account := machine.account(user)
+	for _, identity := range identities {
+		_, node, err := vfs.stat(identity)
+		switch {
+		case err != nil:
+			if explicit {
+				warnings = append(warnings, "Warning: Identity file "+identity+" not accessible: "+errorText(err)+".")
+			}
+			continue
+		case node.isDir():
+			warnings = append(warnings, "Load key \""+identity+"\": invalid format")
+			continue
+		case node.owner == vfs.user && node.mode.Perm()&0077 != 0:
+			warnings = append(warnings, fmt.Sprintf(unprotectedKey, node.mode.Perm(), identity, identity))
+			continue
+		case !vfs.readable(node):
+			warnings = append(warnings, "Load key \""+identity+"\": Permission denied")
+			continue
+		}
+		if account == nil {
+			continue
+		}
+		for _, key := range account.Keys {
+			if strings.TrimSpace(key) == strings.TrimSpace(string(node.content)) {
+				return true, warnings
+			}
+		}
+	}
+	return false, warnings
+}
+
+// connectAs logs in to a machine and runs then, right away if a key is
+// accepted or once the player types the account's password
+func (e *GameEngine) connectAs(session *Session, machine *VirtualFileSystem, user, host string, identities []string,
+	then func() (commandResult, bool)) (commandResult, bool) {
+	accepted, warnings := session.VirtualFS.authenticate(machine, user, identities)
+	if accepted {
+		result, completed := then()
+		result.stderr = strings.Join(append(warnings, result.stderr), "\n")
+		result.stderr = strings.Trim(result.stderr, "\n")
+		return result, completed
+	}
+	session.login = &sshLogin{machine: machine, user: user, host: host, then: then}
+	return commandResult{stdout: session.login.prompt(), stderr: strings.Join(warnings, "\n")}, false
+}
+
+// enterPassword handles the line typed at an ssh password prompt
+func (e *GameEngine) enterPassword(session *Session, password string) (commandResult, bool) {
+	login := session.login
+	session.login = nil
+	if account := login.machine.account(login.user); account != nil && account.Password != "" && password == account.Password {
+		return login.then()
+	}
+	login.tries++
+	if login.tries >= maxPasswordTries {
+		return errorResult(login.user+"@"+login.host+": Permission denied (publickey,password).", 255), false
+	}
+	session.login = login
+	return commandResult{stdout: login.prompt(), stderr: "Permission denied, please try again."}, false
+}
+
+// sshCommand implements ssh [-i key] [-p port] [user@]host [command]:
+// without a command it logs the session in to the host until exit
+func (e *GameEngine) sshCommand(session *Session, level *Level, args []string) (commandResult, bool) {
+	options, operands, usage := parseSSHOptions("ssh", args, 'p', "BbcDEeFIiJLlmOoPpRSWw")
+	if usage != nil {
+		return *usage, false
+	}
+	if len(operands) == 0 {
+		return errorResult(sshUsage, 255), false
+	}
+	user, host := splitDestination(operands[0], &options, session.VirtualFS.user)
+	machine, failure := session.VirtualFS.dial(host, options.port)
+	if failure != nil {
+		return *failure, false
+	}
+
+	if remote := strings.Join(operands[1:], " "); remote != "" {
+		return e.connectAs(session, machine, user, host, options.identities, func() (commandResult, bool) {
+			depth := len(session.hops)
+			session.enter(machine, user, host)
+			defer func() {
+				// The command may have logged out, or in somewhere else
+				for len(session.hops) > depth {
+					session.leave()
+				}
+			}()
+			return e.runLine(remote, session, level, false)
+		})
+	}
+	return e.connectAs(session, machine, user, host, options.identities, func() (commandResult, bool) {
+		session.enter(machine, user, host)
+		greeting := joinLines(machine.motd, lastLogin(time.Now()))
+		if session.VirtualFS.cwd != session.VirtualFS.home {
+			return commandResult{stdout: greeting, stderr: "Could not chdir to home directory " + session.VirtualFS.home + ": No such file or directory"}, false
+		}
+		return stdoutResult(greeting), false
+	})
+}
+
+// scpPath is one side of a copy: a path on this machine, or on a host
+// when written [user@]host:path
+type scpPath struct {
+	user, host, path string
+	remote           bool
+}
+
+func parseSCPPath(operand, current string) scpPath {
+	colon := strings.Index(operand, ":")
+	if colon <= 0 || strings.Contains(operand[:colon], "/") {
+		return scpPath{path: operand}
+	}
+	user, host := current, operand[:colon]
+	if at := strings.LastIndex(host, "@"); at >= 0 {
+		user, host = host[:at], host[at+1:]
+	}
+	name := operand[colon+1:]
+	if name == "" {
+		name = "."
+	}
+	return scpPath{user: user, host: host, path: name, remote: true}
+}
+
+// transfer is a file or directory read from one machine for writing
+// on another, by its path under the copied tree
+type transfer struct {
+	name    string
+	dir     bool
+	mode    os.FileMode
+	content []byte
+}
+
+// gather reads a file, or with recursive a directory tree, for scp
+func gather(vfs *VirtualFileSystem, source string, recursive bool, result *commandResult) []transfer {
+	_, node, err := vfs.stat(source)
+	if err != nil {
+		result.fail("scp: "+source+": "+errorText(err), 1)
+		return nil
+	}
+	if !node.isDir() {
+		if !vfs.readable(node) {
+			result.fail("scp: "+source+": Permission denied", 1)
+			return nil
+		}
+		return []transfer{{name: path.Base(source), mode: node.mode.Perm(), content: node.content}}
+	}
+	if !recursive {
+		result.fail("scp: "+source+": not a regular file", 1)
+		return nil
+	}
+	entries, err := vfs.ReadDir(source)
+	if err != nil {
+		result.fail("scp: "+source+": "+errorText(err), 1)
+		return nil
+	}
+	tree := []transfer{{name: path.Base(vfs.abs(source)), dir: true, mode: node.mode.Perm()}}
+	for _, entry := range entries {
+		for _, item := range gather(vfs, path.Join(source, entry), true, result) {
+			item.name = path.Join(tree[0].name, item.name)
+			tree = append(tree, item)
+		}
+	}
+	return tree
+}
+
+// deposit writes gathered files under target, or as target when it
+// isn't a directory and there's only one
+func deposit(vfs *VirtualFileSystem, items []transfer, target string, result *commandResult) {
+	_, node, err := vfs.stat(target)
+	intoDir := err == nil && node.isDir()
+	for _, item := range items {
+		dest := target
+		if intoDir {
+			dest = path.Join(target, item.name)
+		} else if _, rest, nested := strings.Cut(item.name, "/"); nested {
+			dest = path.Join(target, rest)
+		}
+		if item.dir {
+			if err := vfs.Mkdir(dest); err != nil && !errors.Is(err, fs.ErrExist) {
+				result.fail("scp: "+dest+": "+errorText(err), 1)
+			}
+			continue
+		}
+		_, _, err := vfs.stat(dest)
+		if writeErr := vfs.WriteFile(dest, item.content); writeErr != nil {
+			result.fail("scp: "+dest+": "+errorText(writeErr), 1)
+			continue
+		}
+		if err != nil {
+			vfs.Chmod(dest, item.mode)
+		}
+	}
+}
+
+// scpCommand implements scp [-r] [-i key] [-P port] source... target,
+// between this machine and one host
+func (e *GameEngine) scpCommand(session *Session, args []string) (commandResult, bool) {
+	options, operands, usage := parseSSHOptions("scp", args, 'P', "cFiJloPS")
+	if usage != nil {
+		return *usage, false
+	}
+	if len(operands) < 2 {
+		return errorResult("usage: scp [-346ABCOpqRrsTv] [-c cipher] [-D sftp_server_path] [-F ssh_config]\n           [-i identity_file] [-J destination] [-l limit] [-o ssh_option]\n           [-P port] [-S program] [-X sftp_option] source ... target", 1), false
+	}
+
+	local := session.VirtualFS
+	var paths []scpPath
+	var remote *scpPath
+	for _, operand := range operands {
+		p := parseSCPPath(operand, local.user)
+		if p.remote {
+			if remote != nil && (remote.host != p.host || remote.user != p.user) {
+				return errorResult("scp: copies between two remote hosts aren't supported here", 1), false
+			}
+			remote = &p
+		}
+		paths = append(paths, p)
+	}
+	if remote == nil {
+		// Both ends are local, which is just cp
+		var result commandResult
+		pairs, usage := targets(local, "scp", operands)
+		if usage != nil {
+			return *usage, false
+		}
+		for _, pair := range pairs {
+			copyPath(local, pair[0], pair[1], options.recursive, false, &result)
+		}
+		return result, false
+	}
+	sources, target := paths[:len(paths)-1], paths[len(paths)-1]
+	if target.remote == sources[0].remote {
+		return errorResult("scp: copies between two remote hosts aren't supported here", 1), false
+	}
+
+	machine, failure := local.dial(remote.host, options.port)
+	if failure != nil {
+		failure.exitCode = 1
+		failure.stderr += "\nscp: Connection closed"
+		return *failure, false
+	}
+	return e.connectAs(session, machine, remote.user, remote.host, options.identities, func() (commandResult, bool) {
+		var result commandResult
+		var items []transfer
+		if target.remote {
+			for _, source := range sources {
+				items = append(items, gather(local, source.path, options.recursive, &result)...)
+			}
+			machine.as(remote.user, func() { deposit(machine, items, target.path, &result) })
+		} else {
+			machine.as(remote.user, func() {
+				for _, source := range sources {
+					items = append(items, gather(machine, source.path, options.recursive, &result)...)
+				}
+			})
+			deposit(local, items, target.path, &result)
+		}
+		return result, false
+	})
+}
+
+// Prompt is the shell prompt on levels with several machines, such as
+// "alice@web01:~$ ", or empty when the frontend's own prompt will do
+func (e *GameEngine) Prompt(sessionID string) string {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return ""
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	vfs := session.VirtualFS
+	if vfs == nil || vfs.network == nil && len(session.hops) == 0 {
+		return ""
+	}
+	dir := vfs.cwd
+	if dir == vfs.home {
+		dir = "~"
+	} else if rest, inHome := strings.CutPrefix(dir, vfs.home+"/"); inHome {
+		dir = "~/" + rest
+	}
+	sign := "$"
+	if vfs.user == "root" {
+		sign = "#"
+	}
+	return vfs.user + "@" + vfs.hostname + ":" + dir + sign + " "
+}
+
+// Remote reports whether the session is logged in to another machine,
+// where exit and logout return instead of hanging up
+func (e *GameEngine) Remote(sessionID string) bool {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return len(session.hops) > 0
+}
+
+// PasswordPending reports whether ssh is waiting for a password, which
+// the terminal shouldn't echo
+func (e *GameEngine) PasswordPending(sessionID string) bool {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.login != nil
+}
+
+// CancelLogin abandons a password prompt, as Ctrl-C does
+func (e *GameEngine) CancelLogin(sessionID string) {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.login = nil
+}
+
+// lastLogin is the line sshd greets a login with
+func lastLogin(now time.Time) string {
+	return "Last login: " + now.Add(-26*time.Hour).Format("Mon Jan _2 15:04:05 2006") + " from " + playerAddress
+}
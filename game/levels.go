@@ -56,7 +56,7 @@ func initializeLevels() map[int]*Level {
 			Description: "The password is in a file you don't have permission to read",
 			WelcomeMsg:  "Sometimes files are protected. You need the right permissions to access them.",
 			Filesystem: map[string]interface{}{
-				"secret.txt":   "bandit5{FilePermissionMaster2024}",
+				"secret.txt":   map[string]interface{}{"content": "bandit5{FilePermissionMaster2024}", "mode": "0000"},
 				"readable.txt": "This file is readable but not helpful",
 				".permissions": "Try: chmod 700 secret.txt",
 			},
@@ -82,7 +82,7 @@ Server stopped`,
 				"notes.txt": "The log file contains important information among all the noise.",
 			},
 			Solution: "bandit6{GrepNinja2024}",
-			Hint:     "Use 'grep' to search for patterns in files. Try: grep Password data.log",
+			Hint:     "Use 'grep' to search for patterns in files, and -o to print only the match. Try: grep -o 'bandit6{.*}' data.log",
 		},
 		6: {
 			ID:          6,
@@ -90,8 +90,11 @@ Server stopped`,
 			Description: "Extract text from a binary file",
 			WelcomeMsg:  "Some files aren't plain text. You'll need special tools to extract readable content.",
 			Filesystem: map[string]interface{}{
-				"binary.data": "← Binary data → bandit7{BinaryHunter} ← More binary data →",
-				"hint.txt":    "Sometimes binary files contain readable strings...",
+				"binary.data": map[string]interface{}{
+					"encoding": "base64",
+					"content":  "f0VMRgIBAQAAAAAAAAAAAAIAPgABAAAAABBAAAAAAABAAAAAAAAAAAAAAAAAAAAAAAAAAEAAOAABAEAAAAAAAIuQuAHDi0gAAAIPkMOLBYmJiwBIw4sQBS9saWI2NC9sZC1saW51eC14ODYtNjQuc28uMgC45f//DwEQkP8FiwUBAmJhbmRpdDd7QmluYXJ5SHVudGVyfQBID4uQDwG45YlHQ0M6IChHTlUpIDEyLjIuMAABiYvli0gudGV4dAAuZGF0YQAuYnNzAMMFAYsQ",
+				},
+				"hint.txt": "Sometimes binary files contain readable strings...",
 			},
 			Solution: "bandit7{BinaryHunter}",
 			Hint:     "Use 'strings' command to extract readable text from binary files.",
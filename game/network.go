@@ -0,0 +1,788 @@
+package game
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Behaviours a level's service can have
+const (
+	ServiceEcho     = "echo"     // Sends back whatever it's sent
+	ServicePassword = "password" // Sends Reply for the right password, Wrong for anything else
+	ServiceBanner   = "banner"   // Sends Reply and hangs up, whatever it's sent
+	ServiceHTTP     = "http"     // Serves Paths; a POST of the right password gets Reply
+)
+
+// LevelService is a daemon listening on a port of the level's machine,
+// like the ones bandit's later levels send passwords to
+type LevelService struct {
+	Port     int               `json:"port"`
+	Behavior string            `json:"behavior"`         // ServiceEcho, ServicePassword, ServiceBanner or ServiceHTTP
+	TLS      bool              `json:"tls,omitempty"`    // Speaks TLS: reach it with openssl s_client, ncat --ssl or curl -k
+	Name     string            `json:"name,omitempty"`   // What nmap calls it, from the port number by default
+	PID      int               `json:"pid,omitempty"`    // The process listening; the port closes when it's killed
+	Banner   string            `json:"banner,omitempty"` // Sent as soon as a client connects
+	Expect   string            `json:"expect,omitempty"` // The password ServicePassword and ServiceHTTP check for
+	Reply    string            `json:"reply,omitempty"`  // Sent for the right password, or always by ServiceBanner
+	Wrong    string            `json:"wrong,omitempty"`  // Sent for a wrong password
+	Paths    map[string]string `json:"paths,omitempty"`  // ServiceHTTP: page content by path
+}
+
+// sshService is the SSH daemon every machine runs on port 22
+var sshService = LevelService{Port: 22, Behavior: ServiceBanner, Name: "ssh", PID: sshdPID, Reply: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5"}
+
+// wellKnownPorts names the services nmap and ss recognise by port
+var wellKnownPorts = map[int]string{
+	21: "ftp", 22: "ssh", 23: "telnet", 25: "smtp", 53: "domain", 80: "http", 110: "pop3", 143: "imap",
+	443: "https", 3306: "mysql", 5432: "postgresql", 6379: "redis", 8080: "http-proxy", 8443: "https-alt",
+}
+
+// validate checks a level's service definition
+func (s *LevelService) validate() error {
+	if s.Port < 1 || s.Port > 65535 {
+		return fmt.Errorf("port %d is out of range", s.Port)
+	}
+	switch s.Behavior {
+	case ServiceEcho, ServiceBanner, ServiceHTTP:
+	case ServicePassword:
+		if s.Expect == "" {
+			return fmt.Errorf("port %d: password services need the password they expect", s.Port)
+		}
+	default:
+		return fmt.Errorf("port %d: unknown behavior %q", s.Port, s.Behavior)
+	}
+	return nil
+}
+
+// serviceName is what nmap and ss call the service on a port
+func (s *LevelService) serviceName() string {
+	switch {
+	case s.Name != "":
+		return s.Name
+	case wellKnownPorts[s.Port] != "":
+		return wellKnownPorts[s.Port]
+	}
+	return "unknown"
+}
+
+// listener is the service accepting connections on a port, or nil when
+// the port is closed or its process has been killed
+func (vfs *VirtualFileSystem) listener(port int) *LevelService {
+	for i := range vfs.services {
+		s := &vfs.services[i]
+		if s.Port == port && (s.PID == 0 || vfs.procs.procs[s.PID] != nil) {
+			return s
+		}
+	}
+	return nil
+}
+
+// listening is every open port's service, by port
+func (vfs *VirtualFileSystem) listening() []*LevelService {
+	var open []*LevelService
+	for i := range vfs.services {
+		if s := &vfs.services[i]; vfs.listener(s.Port) == s {
+			open = append(open, s)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool { return open[i].Port < open[j].Port })
+	return open
+}
+
+// resolveHost finds the machine a host name or address points to, and
+// its address: this one as localhost or by name, or another on the
+// level's network
+func (vfs *VirtualFileSystem) resolveHost(host string) (*VirtualFileSystem, string, bool) {
+	switch strings.ToLower(host) {
+	case "localhost", "127.0.0.1", "0.0.0.0", "::1", "localhost.localdomain":
+		return vfs, "127.0.0.1", true
+	case vfs.hostname, vfs.address:
+		return vfs, vfs.address, true
+	}
+	for _, machine := range vfs.network {
+		if host == machine.hostname || host == machine.address {
+			return machine, machine.address, true
+		}
+	}
+	return nil, "", false
+}
+
+// respond is everything a service sends a client that connects and
+// writes input, then hangs up
+func (s *LevelService) respond(input string) string {
+	var reply string
+	switch s.Behavior {
+	case ServiceEcho:
+		reply = input
+	case ServiceBanner:
+		reply = s.Reply
+	case ServicePassword:
+		if input == "" {
+			break
+		}
+		line, _, _ := strings.Cut(strings.TrimLeft(input, "\n"), "\n")
+		if strings.TrimSpace(line) == s.Expect {
+			reply = s.Reply
+		} else {
+			reply = s.Wrong
+			if reply == "" {
+				reply = "Wrong! Please enter the correct current password."
+			}
+		}
+	case ServiceHTTP:
+		if input == "" {
+			break
+		}
+		request, body, _ := strings.Cut(strings.ReplaceAll(input, "\r\n", "\n"), "\n\n")
+		fields := strings.Fields(request)
+		if len(fields) < 2 {
+			return httpResponse(400, "Bad Request", false)
+		}
+		status, page := s.serve(fields[0], fields[1], body)
+		return httpResponse(status, page, fields[0] == "HEAD")
+	}
+	return joinLines(s.Banner, reply)
+}
+
+// serve answers an HTTP request with a status and page
+func (s *LevelService) serve(method, target, body string) (int, string) {
+	target, _, _ = strings.Cut(target, "?")
+	if method == "POST" && s.Expect != "" {
+		if strings.TrimSpace(body) == s.Expect || formHasValue(body, s.Expect) {
+			return 200, s.Reply
+		}
+		wrong := s.Wrong
+		if wrong == "" {
+			wrong = "Wrong password"
+		}
+		return 403, wrong
+	}
+	if method != "GET" && method != "HEAD" && method != "POST" {
+		return 405, "Method Not Allowed"
+	}
+	if page, exists := s.Paths[target]; exists {
+		return 200, page
+	}
+	if target == "/" && len(s.Paths) == 0 {
+		return 200, s.Reply
+	}
+	return 404, "Not Found"
+}
+
+// formHasValue reports whether a form-encoded body holds a value
+func formHasValue(body, value string) bool {
+	form, err := url.ParseQuery(strings.TrimSpace(body))
+	if err != nil {
+		return false
+	}
+	for _, values := range form {
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// httpStatusText is the reason phrase of the statuses services send
+var httpStatusText = map[int]string{200: "OK", 400: "Bad Request", 403: "Forbidden", 404: "Not Found", 405: "Method Not Allowed"}
+
+// httpResponse renders a whole response as it comes over the wire,
+// headers first
+func httpResponse(status int, page string, headOnly bool) string {
+	header := fmt.Sprintf("HTTP/1.1 %d %s\nServer: nginx/1.24.0\nContent-Type: text/plain\nContent-Length: %d\nConnection: close\n",
+		status, httpStatusText[status], len(terminated(page)))
+	if headOnly {
+		return header
+	}
+	return header + "\n" + page
+}
+
+// joinLines joins the parts that aren't empty, one per line
+func joinLines(parts ...string) string {
+	var lines []string
+	for _, part := range parts {
+		if part != "" {
+			lines = append(lines, part)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parsePorts reads a port list such as 22,80,30000-30010, or - for all
+func parsePorts(spec string) ([]int, bool) {
+	if spec == "-" {
+		spec = "1-65535"
+	}
+	var ports []int
+	for _, part := range strings.Split(spec, ",") {
+		low, high, isRange := strings.Cut(part, "-")
+		first, err1 := strconv.Atoi(low)
+		last, err2 := first, error(nil)
+		if isRange {
+			last, err2 = strconv.Atoi(high)
+		}
+		if err1 != nil || err2 != nil || first < 1 || last > 65535 || first > last {
+			return nil, false
+		}
+		for port := first; port <= last; port++ {
+			ports = append(ports, port)
+		}
+	}
+	return ports, true
+}
+
+// ncCommand implements nc (OpenBSD netcat) and ncat: connect, send
+// stdin and print the reply, or with -z just report which ports are
+// open. ncat --ssl speaks TLS.
+func ncCommand(vfs *VirtualFileSystem, command string, args []string, stdin *string) commandResult {
+	var ssl bool
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--ssl":
+			ssl = true
+		case "--verbose":
+			rest = append(rest, "-v")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	flags, _, operands, usage := parseOptions(command, rest, "46CdDFhklnNrStuvzZ", "iIOpqswWx", nil)
+	if usage != nil {
+		return *usage
+	}
+	if flags['l'] {
+		return errorResult(command+": listening isn't available in this terminal; connect to the level's services instead", 1)
+	}
+	if flags['u'] {
+		return errorResult(command+": UDP isn't available in this terminal; its services are all TCP", 1)
+	}
+	if len(operands) != 2 {
+		return errorResult("usage: "+command+" [-46CDdFhklNnrStUuvZz] [-I length] [-i interval] [-O length]\n\t  [-P proxy_username] [-p source_port] [-q seconds] [-s source]\n\t  [-T keyword] [-V rtable] [-W recvlimit] [-w timeout]\n\t  [-X proxy_protocol] [-x proxy_address[:port]] \t  [destination] [port]", 1)
+	}
+	host := operands[0]
+	ports, ok := parsePorts(operands[1])
+	if !ok {
+		return errorResult(command+": port range not valid", 1)
+	}
+	machine, address, found := vfs.resolveHost(host)
+	if !found {
+		return errorResult(fmt.Sprintf("%s: getaddrinfo for host \"%s\" port %s: Name or service not known", command, host, operands[1]), 1)
+	}
+
+	var result commandResult
+	var output []string
+	anyOpen := false
+	for _, port := range ports {
+		s := machine.listener(port)
+		if s == nil {
+			if flags['v'] || !flags['z'] {
+				result.fail(fmt.Sprintf("%s: connect to %s (%s) port %d (tcp) failed: Connection refused", command, host, address, port), result.exitCode)
+			}
+			continue
+		}
+		anyOpen = true
+		if flags['v'] {
+			name := "*"
+			if wellKnownPorts[port] != "" {
+				name = wellKnownPorts[port]
+			}
+			result.fail(fmt.Sprintf("Connection to %s (%s) %d port [tcp/%s] succeeded!", host, address, port, name), result.exitCode)
+		}
+		if flags['z'] {
+			continue
+		}
+		input := ""
+		if stdin != nil {
+			input = terminated(*stdin)
+		}
+		// Plain text sent to a TLS port is no handshake; the server hangs up
+		if s.TLS == ssl {
+			output = append(output, strings.TrimSuffix(s.respond(input), "\n"))
+		} else if ssl {
+			result.fail("Ncat: Input/output error.", 1)
+		}
+		break
+	}
+	if !anyOpen {
+		result.exitCode = 1
+	}
+	result.stdout = joinLines(output...)
+	return result
+}
+
+// telnetCommand implements telnet host [port]
+func telnetCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	if len(args) == 0 || len(args) > 2 {
+		return errorResult("Usage: telnet host [port]", 1)
+	}
+	port := 23
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			if n = portByName(args[1]); n == 0 {
+				return errorResult("telnet: could not resolve "+args[0]+"/"+args[1]+": Servname not supported for ai_socktype", 1)
+			}
+		}
+		port = n
+	}
+	machine, address, found := vfs.resolveHost(args[0])
+	if !found {
+		return errorResult("telnet: could not resolve "+args[0]+"/"+strconv.Itoa(port)+": Name or service not known", 1)
+	}
+	trying := "Trying " + address + "..."
+	s := machine.listener(port)
+	if s == nil {
+		return commandResult{stdout: trying, stderr: "telnet: Unable to connect to remote host: Connection refused", exitCode: 1}
+	}
+	input := ""
+	if stdin != nil {
+		input = terminated(*stdin)
+	}
+	reply := ""
+	if !s.TLS {
+		reply = strings.TrimSuffix(s.respond(input), "\n")
+	}
+	return stdoutResult(joinLines(trying, "Connected to "+args[0]+".", "Escape character is '^]'.", reply, "Connection closed by foreign host."))
+}
+
+// portByName is the port of a well-known service name, or 0
+func portByName(name string) int {
+	for port, known := range wellKnownPorts {
+		if known == name {
+			return port
+		}
+	}
+	return 0
+}
+
+// curlCommand implements curl for http:// and https:// URLs on the
+// level's machine
+func curlCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	var rest []string
+	for _, arg := range args {
+		switch arg {
+		case "--insecure":
+			rest = append(rest, "-k")
+		case "--silent":
+			rest = append(rest, "-s")
+		case "--include":
+			rest = append(rest, "-i")
+		case "--head":
+			rest = append(rest, "-I")
+		case "--location":
+			rest = append(rest, "-L")
+		case "--fail":
+			rest = append(rest, "-f")
+		case "--data", "--data-raw":
+			rest = append(rest, "-d")
+		case "--request":
+			rest = append(rest, "-X")
+		case "--output":
+			rest = append(rest, "-o")
+		case "--header":
+			rest = append(rest, "-H")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	flags, values, operands, usage := parseOptions("curl", rest, "fiIkLsSv", "dHoX", nil)
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) == 0 {
+		return errorResult("curl: try 'curl --help' or 'curl --manual' for more information", 2)
+	}
+	fail := func(code int, message string) commandResult {
+		if flags['s'] && !flags['S'] {
+			return commandResult{exitCode: code}
+		}
+		return errorResult(fmt.Sprintf("curl: (%d) %s", code, message), code)
+	}
+
+	raw := operands[0]
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	target, err := url.Parse(raw)
+	if err != nil || target.Hostname() == "" {
+		return fail(3, "URL rejected: Malformed input to a URL function")
+	}
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fail(1, "Protocol \""+target.Scheme+"\" not supported")
+	}
+	port := map[string]int{"http": 80, "https": 443}[target.Scheme]
+	if target.Port() != "" {
+		port, _ = strconv.Atoi(target.Port())
+	}
+	machine, _, found := vfs.resolveHost(target.Hostname())
+	if !found {
+		return fail(6, "Could not resolve host: "+target.Hostname())
+	}
+	s := machine.listener(port)
+	switch {
+	case s == nil:
+		return fail(7, fmt.Sprintf("Failed to connect to %s port %d after 0 ms: Couldn't connect to server", target.Hostname(), port))
+	case s.TLS && target.Scheme == "http":
+		return fail(52, "Empty reply from server")
+	case !s.TLS && target.Scheme == "https":
+		return fail(35, "OpenSSL/3.0.13: error:0A00010B:SSL routines::wrong version number")
+	case s.TLS && !flags['k']:
+		return fail(60, "SSL certificate problem: self-signed certificate\nMore details here: https://curl.se/docs/sslcerts.html\n\ncurl failed to verify the legitimacy of the server and therefore could not\nestablish a secure connection to it. To learn more about this situation and\nhow to fix it, please visit the web page mentioned above.")
+	}
+
+	method := "GET"
+	body, posting := values['d']
+	switch {
+	case values['X'] != "":
+		method = values['X']
+	case flags['I']:
+		method = "HEAD"
+	case posting:
+		method = "POST"
+	}
+	path := target.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	var response string
+	if s.Behavior == ServiceHTTP {
+		status, page := s.serve(method, path, body)
+		if flags['f'] && status >= 400 {
+			return fail(22, fmt.Sprintf("The requested URL returned error: %d", status))
+		}
+		response = page
+		if flags['i'] || flags['I'] {
+			response = httpResponse(status, page, flags['I'])
+		}
+	} else {
+		// Anything but HTTP answers without a status line
+		return fail(1, "Received HTTP/0.9 when not allowed")
+	}
+
+	result := stdoutResult(strings.TrimSuffix(response, "\n"))
+	if output, set := values['o']; set {
+		if err := vfs.WriteFile(output, []byte(terminated(response))); err != nil {
+			return fail(23, "Failure writing output to destination")
+		}
+		result.stdout = ""
+	}
+	return result
+}
+
+// sClientCommand implements openssl s_client -connect host:port: the
+// TLS handshake report, then the service's reply to stdin. Without
+// -quiet or -ign_eof, input lines starting with Q, R or k are commands
+// to s_client itself, as on a real one.
+func sClientCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	connect, quiet, ignoreEOF := "", false, false
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-connect":
+			if i+1 >= len(args) {
+				return errorResult("s_client: Option -connect needs a value\ns_client: Use -help for summary.", 1)
+			}
+			i++
+			connect = args[i]
+		case "-quiet":
+			quiet, ignoreEOF = true, true
+		case "-ign_eof":
+			ignoreEOF = true
+		case "-servername", "-CAfile", "-cert", "-key":
+			i++
+		case "-crlf", "-brief", "-showcerts", "-no_ign_eof", "-tls1_2", "-tls1_3":
+		default:
+			if strings.HasPrefix(args[i], "-") {
+				return errorResult("s_client: Unknown option: "+args[i]+"\ns_client: Use -help for summary.", 1)
+			}
+			connect = args[i]
+		}
+	}
+	if connect == "" {
+		connect = "localhost:4433"
+	}
+	host, portText, found := strings.Cut(connect, ":")
+	port, err := strconv.Atoi(portText)
+	if !found || err != nil {
+		return errorResult("s_client: -connect argument or target parameter malformed or ambiguous", 1)
+	}
+	machine, _, resolved := vfs.resolveHost(host)
+	if !resolved {
+		return errorResult("BIO_lookup_ex:system lib\nconnect:errno=0", 1)
+	}
+	s := machine.listener(port)
+	if s == nil {
+		return errorResult("connect:errno=111", 1)
+	}
+	if !s.TLS {
+		return commandResult{stdout: "CONNECTED(00000003)", stderr: "error:0A00010B:SSL routines:ssl3_get_record:wrong version number\n---\nno peer certificate available\n---", exitCode: 1}
+	}
+
+	verify := "depth=0 CN = " + host + "\nverify error:num=18:self-signed certificate\nverify return:1\ndepth=0 CN = " + host + "\nverify return:1"
+	input := ""
+	var notes []string
+	if stdin != nil {
+		var kept []string
+		for _, line := range textLines(*stdin) {
+			if !ignoreEOF && line != "" {
+				switch line[0] {
+				case 'Q':
+					notes = append(notes, "DONE")
+				case 'R':
+					notes = append(notes, "RENEGOTIATING")
+					continue
+				case 'k', 'K':
+					notes = append(notes, "KEYUPDATE")
+					continue
+				}
+				if line[0] == 'Q' {
+					break
+				}
+			}
+			kept = append(kept, line)
+		}
+		input = strings.Join(kept, "\n")
+		if input != "" {
+			input += "\n"
+		}
+	}
+	reply := strings.TrimSuffix(s.respond(input), "\n")
+	if quiet {
+		return commandResult{stdout: reply, stderr: verify}
+	}
+
+	session := strings.Join([]string{
+		"CONNECTED(00000003)",
+		"---",
+		"Certificate chain",
+		" 0 s:CN = " + host,
+		"   i:CN = " + host,
+		"   a:PKEY: rsaEncryption, 2048 (bit); sigalg: RSA-SHA256",
+		"   v:NotBefore: " + time.Now().AddDate(0, -2, 0).UTC().Format("Jan _2 15:04:05 2006 GMT") + "; NotAfter: " + time.Now().AddDate(1, 0, 0).UTC().Format("Jan _2 15:04:05 2006 GMT"),
+		"---",
+		"Server certificate",
+		"subject=CN = " + host,
+		"issuer=CN = " + host,
+		"---",
+		"No client certificate CA names sent",
+		"Peer signing digest: SHA256",
+		"Peer signature type: RSA-PSS",
+		"Server Temp Key: X25519, 253 bits",
+		"---",
+		"SSL handshake has read 1339 bytes and written 373 bytes",
+		"Verification error: self-signed certificate",
+		"---",
+		"New, TLSv1.3, Cipher is TLS_AES_256_GCM_SHA384",
+		"Server public key is 2048 bit",
+		"Secure Renegotiation IS NOT supported",
+		"Compression: NONE",
+		"Expansion: NONE",
+		"No ALPN negotiated",
+		"Early data was not sent",
+		"Verify return code: 18 (self-signed certificate)",
+		"---",
+	}, "\n")
+	return commandResult{stdout: joinLines(session, strings.Join(notes, "\n"), reply, "closed"), stderr: verify}
+}
+
+// nmapCommand implements a TCP connect scan of the level's machine
+func nmapCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	var portSpec string
+	var versions bool
+	var targets []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-p":
+			if i+1 >= len(args) {
+				return errorResult("nmap: option requires an argument -- 'p'\nSee the output of nmap -h for a summary of options.", 255)
+			}
+			i++
+			portSpec = args[i]
+		case strings.HasPrefix(arg, "-p"):
+			portSpec = arg[2:]
+		case arg == "-sV" || arg == "-A":
+			versions = true
+		case arg == "-sT" || arg == "-sS" || arg == "-Pn" || arg == "-n" || arg == "-v" || arg == "--open" || strings.HasPrefix(arg, "-T"):
+		case strings.HasPrefix(arg, "-"):
+			return errorResult("nmap: unrecognized option '"+arg+"'\nSee the output of nmap -h for a summary of options.", 255)
+		default:
+			targets = append(targets, arg)
+		}
+	}
+	if len(targets) == 0 {
+		return errorResult("WARNING: No targets were specified, so 0 hosts scanned.\nNmap done: 0 IP addresses (0 hosts up) scanned in 0.02 seconds", 0)
+	}
+
+	var ports []int
+	if portSpec != "" {
+		var ok bool
+		if ports, ok = parsePorts(portSpec); !ok {
+			return errorResult("Ports specified must be between 0 and 65535 inclusive\nQUITTING!", 1)
+		}
+	}
+
+	now := time.Now()
+	lines := []string{"Starting Nmap 7.94SVN ( https://nmap.org ) at " + now.Format("2006-01-02 15:04 MST")}
+	addresses, hostsUp := 0, 0
+	for _, target := range targets {
+		// A subnet reports the level's machines in it
+		if prefix, err := netip.ParsePrefix(target); err == nil {
+			addresses += 1 << (prefix.Addr().BitLen() - prefix.Bits())
+			for _, machine := range vfs.machinesIn(prefix) {
+				hostsUp++
+				lines = append(lines, scanReport(machine, machine.hostname+" ("+machine.address+")", ports, versions)...)
+			}
+			continue
+		}
+
+		addresses++
+		machine, address, found := vfs.resolveHost(target)
+		if !found {
+			lines = append(lines, "Failed to resolve \""+target+"\".")
+			continue
+		}
+		hostsUp++
+		name := target
+		if target != address {
+			name += " (" + address + ")"
+		}
+		lines = append(lines, scanReport(machine, name, ports, versions)...)
+	}
+	lines = append(lines, fmt.Sprintf("Nmap done: %d IP %s (%d %s up) scanned in 0.09 seconds",
+		addresses, plural(addresses, "address", "addresses"), hostsUp, plural(hostsUp, "host", "hosts")))
+	return stdoutResult(strings.Join(lines, "\n"))
+}
+
+// machinesIn is every machine of the level's network in a subnet, by address
+func (vfs *VirtualFileSystem) machinesIn(prefix netip.Prefix) []*VirtualFileSystem {
+	var found []*VirtualFileSystem
+	for _, machine := range vfs.network {
+		if address, err := netip.ParseAddr(machine.address); err == nil && prefix.Contains(address) {
+			found = append(found, machine)
+		}
+	}
+	sortByAddress(found)
+	return found
+}
+
+// scanReport is nmap's report on one machine. Without a port list nmap
+// tries its 1000 most common ports; here that's the first thousand and
+// the machine's own.
+func scanReport(machine *VirtualFileSystem, name string, ports []int, versions bool) []string {
+	if ports == nil {
+		for port := 1; port <= 1000; port++ {
+			ports = append(ports, port)
+		}
+		for _, s := range machine.services {
+			if s.Port > 1000 {
+				ports = append(ports, s.Port)
+			}
+		}
+		sort.Ints(ports)
+	}
+
+	var rows [][3]string
+	closed := 0
+	for _, port := range ports {
+		if s := machine.listener(port); s != nil {
+			service := s.serviceName()
+			if s.TLS && versions {
+				service = "ssl/" + service
+			}
+			rows = append(rows, [3]string{fmt.Sprintf("%d/tcp", port), "open", service})
+		} else if len(ports) <= 25 {
+			name := wellKnownPorts[port]
+			if name == "" {
+				name = "unknown"
+			}
+			rows = append(rows, [3]string{fmt.Sprintf("%d/tcp", port), "closed", name})
+		} else {
+			closed++
+		}
+	}
+
+	lines := []string{"Nmap scan report for " + name, "Host is up (0.000085s latency)."}
+	if closed > 0 {
+		lines = append(lines, fmt.Sprintf("Not shown: %d closed tcp ports (conn-refused)", closed))
+	}
+	if len(rows) == 0 {
+		lines = append(lines, fmt.Sprintf("All %d scanned ports on %s are in ignored states.", len(ports), name))
+	} else {
+		width := len("PORT")
+		for _, row := range rows {
+			width = max(width, len(row[0]))
+		}
+		lines = append(lines, fmt.Sprintf("%-*s STATE  SERVICE", width, "PORT"))
+		for _, row := range rows {
+			lines = append(lines, strings.TrimRight(fmt.Sprintf("%-*s %-6s %s", width, row[0], row[1], row[2]), " "))
+		}
+	}
+	return append(lines, "")
+}
+
+// ssCommand implements ss for the machine's listening TCP sockets
+func ssCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, operands, usage := parseFlags("ss", args, "46alnptux", map[string]rune{
+		"all": 'a', "listening": 'l', "numeric": 'n', "processes": 'p', "tcp": 't', "udp": 'u'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) > 0 {
+		return errorResult("ss: filters are not supported here: "+strings.Join(operands, " "), 1)
+	}
+
+	tcp := flags['t'] || !flags['u'] && !flags['x']
+	listening := flags['l'] || flags['a']
+	var open []*LevelService
+	if tcp && listening {
+		open = vfs.listening()
+	}
+
+	portWidth := 4
+	ports := make([]string, len(open))
+	for i, s := range open {
+		ports[i] = strconv.Itoa(s.Port)
+		if !flags['n'] && wellKnownPorts[s.Port] != "" {
+			ports[i] = wellKnownPorts[s.Port]
+		}
+		portWidth = max(portWidth, len(ports[i]))
+	}
+
+	header := "State  Recv-Q Send-Q"
+	if !flags['t'] || flags['u'] {
+		header = "Netid State  Recv-Q Send-Q"
+	}
+	lines := []string{fmt.Sprintf("%s %13s:%-*s %12s:%-4s%s", header, "Local Address", portWidth, "Port", "Peer Address", "Port", "Process")}
+	for i, s := range open {
+		state := "LISTEN 0      128   "
+		if !flags['t'] || flags['u'] {
+			state = "tcp   " + state
+		}
+		process := ""
+		if p := vfs.procs.procs[s.PID]; flags['p'] && p != nil && p.user == vfs.user {
+			process = fmt.Sprintf("users:((\"%s\",pid=%d,fd=3))", p.comm(), p.pid)
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%s %13s:%-*s %12s:%-4s %s", state, "0.0.0.0", portWidth, ports[i], "0.0.0.0", "*", process), " "))
+	}
+	return stdoutResult(strings.Join(lines, "\n"))
+}
+
+// replied reports whether a service sent back the level's password,
+// which completes the level just like finding it in a file
+func replied(result commandResult, level *Level) bool {
+	for _, line := range strings.Split(result.stdout, "\n") {
+		if line == level.Solution {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package game
+
+// FileObjective clears a level once the player has arranged the
+// filesystem as asked, e.g. moved a file into place or deleted a lock,
+// or killed the process holding the lock. Relative paths are in the
+// home directory.
+type FileObjective struct {
+	Exists  []string          `json:"exists,omitempty"`  // Paths that must exist
+	Missing []string          `json:"missing,omitempty"` // Paths that must be gone
+	Content map[string]string `json:"content,omitempty"` // Files and the content they must hold
+	Stopped []int             `json:"stopped,omitempty"` // PIDs of level processes that must be killed
+	Running []int             `json:"running,omitempty"` // PIDs that must survive, to punish killing everything
+}
+
+// met checks the objective without the player's permissions
+func (o *FileObjective) met(vfs *VirtualFileSystem) bool {
+	for _, name := range o.Exists {
+		if _, exists := vfs.files[vfs.homePath(name)]; !exists {
+			return false
+		}
+	}
+	for _, name := range o.Missing {
+		if _, exists := vfs.files[vfs.homePath(name)]; exists {
+			return false
+		}
+	}
+	for _, pid := range o.Stopped {
+		if vfs.procs.procs[pid] != nil {
+			return false
+		}
+	}
+	for _, pid := range o.Running {
+		if vfs.procs.procs[pid] == nil {
+			return false
+		}
+	}
+	for name, content := range o.Content {
+		node, exists := vfs.files[vfs.homePath(name)]
+		if !exists || !node.mode.IsRegular() || string(node.content) != content {
+			return false
+		}
+	}
+	return true
+}
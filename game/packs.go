@@ -0,0 +1,283 @@
+package game
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Name of the pack holding the built-in levels
+const builtinPack = "bandit"
+
+// LevelPack is a named set of levels that a CTF event can enable
+type LevelPack struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Levels      []*Level `json:"levels"`
+}
+
+func builtinLevelPack(levels map[int]*Level) *LevelPack {
+	pack := &LevelPack{
+		Name:        builtinPack,
+		Description: "The classic CodeHeist levels",
+	}
+	for i := 0; i < len(levels); i++ {
+		pack.Levels = append(pack.Levels, levels[i])
+	}
+	return pack
+}
+
+// LoadLevelPacks reads every *.json level pack in a directory
+func (e *GameEngine) LoadLevelPacks(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var pack LevelPack
+		if err := json.Unmarshal(data, &pack); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if err := pack.validate(); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if _, exists := e.Packs[pack.Name]; exists {
+			return fmt.Errorf("%s: duplicate level pack %q", path, pack.Name)
+		}
+
+		e.Packs[pack.Name] = &pack
+		log.Printf("📦 Loaded level pack %s (%d levels)", pack.Name, len(pack.Levels))
+	}
+	return nil
+}
+
+func (p *LevelPack) validate() error {
+	if !teamNamePattern.MatchString(p.Name) {
+		return fmt.Errorf("pack names must be 1-32 letters, digits, '-' or '_'")
+	}
+	if len(p.Levels) == 0 {
+		return fmt.Errorf("pack %s has no levels", p.Name)
+	}
+
+	ids := make(map[int]bool)
+	for _, level := range p.Levels {
+		if ids[level.ID] {
+			return fmt.Errorf("pack %s: duplicate level id %d", p.Name, level.ID)
+		}
+		ids[level.ID] = true
+		if level.Solution == "" {
+			return fmt.Errorf("pack %s: level %d has no solution", p.Name, level.ID)
+		}
+		for name, value := range level.Filesystem {
+			if _, err := levelFileNode(value); err != nil {
+				return fmt.Errorf("pack %s: level %d: file %s: %w", p.Name, level.ID, name, err)
+			}
+		}
+		if level.Expiry != "" && level.Expiry != ExpiryReset && level.Expiry != ExpiryFail {
+			return fmt.Errorf("pack %s: level %d: unknown expiry %q (want %q or %q)", p.Name, level.ID, level.Expiry, ExpiryReset, ExpiryFail)
+		}
+		if err := validateProcesses(level); err != nil {
+			return fmt.Errorf("pack %s: level %d: %w", p.Name, level.ID, err)
+		}
+		if err := validateServices(level); err != nil {
+			return fmt.Errorf("pack %s: level %d: %w", p.Name, level.ID, err)
+		}
+		if err := validateHosts(level); err != nil {
+			return fmt.Errorf("pack %s: level %d: %w", p.Name, level.ID, err)
+		}
+	}
+	return nil
+}
+
+// levelFileNode decodes one entry of a level's filesystem: either the
+// file's content, or an object describing a file, directory or symlink
+// such as {"content": "...", "mode": "0400", "owner": "bandit7"} or
+// {"type": "symlink", "target": "dir1"}. Binary content is given with
+// "encoding": "base64" or "hex".
+func levelFileNode(value interface{}) (*fileNode, error) {
+	switch value := value.(type) {
+	case string:
+		return &fileNode{content: []byte(value), mode: defaultFileMode}, nil
+	case map[string]interface{}:
+		node := &fileNode{mode: defaultFileMode}
+		switch kind, _ := value["type"].(string); kind {
+		case "", "file":
+		case "dir":
+			node.mode = os.ModeDir | defaultDirMode
+		case "symlink":
+			target, _ := value["target"].(string)
+			if target == "" {
+				return nil, fmt.Errorf("symlinks need a target")
+			}
+			node.mode = os.ModeSymlink | 0777
+			node.target = target
+		default:
+			return nil, fmt.Errorf("unknown type %q", kind)
+		}
+
+		if content, exists := value["content"]; exists {
+			text, ok := content.(string)
+			if !ok || !node.mode.IsRegular() {
+				return nil, fmt.Errorf("content must be a string on a file")
+			}
+			node.content = []byte(text)
+			var err error
+			switch encoding, _ := value["encoding"].(string); encoding {
+			case "":
+			case "base64":
+				node.content, err = base64.StdEncoding.DecodeString(text)
+			case "hex":
+				node.content, err = hex.DecodeString(strings.Join(strings.Fields(text), ""))
+			default:
+				return nil, fmt.Errorf("unknown encoding %q", encoding)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("content is not valid %s: %v", value["encoding"], err)
+			}
+		}
+		if mode, exists := value["mode"]; exists {
+			text, _ := mode.(string)
+			perm, err := strconv.ParseUint(text, 8, 32)
+			if err != nil || perm > 0777 {
+				return nil, fmt.Errorf("mode must be an octal string such as \"0640\"")
+			}
+			node.mode = node.mode.Type() | os.FileMode(perm)
+		}
+		node.owner, _ = value["owner"].(string)
+		node.group, _ = value["group"].(string)
+		if modified, exists := value["modified"]; exists {
+			text, _ := modified.(string)
+			at, err := time.Parse(time.RFC3339, text)
+			if err != nil {
+				return nil, fmt.Errorf("modified must be an RFC 3339 time such as \"2024-01-02T15:04:05Z\"")
+			}
+			node.modTime = at
+		}
+		return node, nil
+	}
+	return nil, fmt.Errorf("must be a string or an object")
+}
+
+// validateProcesses checks a level's processes, and that its objective
+// only names PIDs the level starts
+func validateProcesses(level *Level) error {
+	taken := map[int]bool{initPID: true, sshdPID: true, shellPID: true}
+	seeded := make(map[int]bool)
+	for _, seed := range level.Processes {
+		if strings.TrimSpace(seed.Command) == "" {
+			return fmt.Errorf("processes need a command")
+		}
+		if seed.PID < 0 || taken[seed.PID] {
+			return fmt.Errorf("process %q: pid %d is taken", seed.Command, seed.PID)
+		}
+		for _, name := range seed.Ignores {
+			if _, ok := parseSignal(name); !ok {
+				return fmt.Errorf("process %q: unknown signal %q", seed.Command, name)
+			}
+		}
+		if seed.PID != 0 {
+			taken[seed.PID], seeded[seed.PID] = true, true
+		}
+	}
+	if level.Objective != nil {
+		for _, pid := range append(append([]int{}, level.Objective.Stopped...), level.Objective.Running...) {
+			if !seeded[pid] {
+				return fmt.Errorf("objective: pid %d is not one of the level's processes", pid)
+			}
+		}
+	}
+	return nil
+}
+
+// validateServices checks a level's services, that no two share a port
+// and that each listens from one of the level's processes
+func validateServices(level *Level) error {
+	bound := map[int]bool{sshService.Port: true}
+	seeded := make(map[int]bool)
+	for _, seed := range level.Processes {
+		seeded[seed.PID] = seed.PID != 0
+	}
+	for i := range level.Services {
+		service := &level.Services[i]
+		if err := service.validate(); err != nil {
+			return err
+		}
+		if bound[service.Port] {
+			return fmt.Errorf("port %d is taken", service.Port)
+		}
+		if service.PID != 0 && !seeded[service.PID] {
+			return fmt.Errorf("port %d: pid %d is not one of the level's processes", service.Port, service.PID)
+		}
+		bound[service.Port] = true
+	}
+	return nil
+}
+
+// validateHosts checks a level's other machines: unique names and
+// addresses, accounts, and each one's files, processes and services
+func validateHosts(level *Level) error {
+	hostname := level.Hostname
+	if hostname == "" {
+		hostname = defaultHostname
+	}
+	names := map[string]bool{hostname: true, "localhost": true}
+	addresses := map[string]bool{playerAddress: true}
+	for i := range level.Hosts {
+		host := &level.Hosts[i]
+		if host.Name == "" || strings.ContainsAny(host.Name, " @:/") {
+			return fmt.Errorf("host %d: invalid name %q", i, host.Name)
+		}
+		if names[host.Name] {
+			return fmt.Errorf("host %s: the name is taken", host.Name)
+		}
+		names[host.Name] = true
+		address, err := netip.ParseAddr(hostAddress(i, host))
+		if err != nil || !address.Is4() {
+			return fmt.Errorf("host %s: invalid address %q", host.Name, host.Address)
+		}
+		if addresses[address.String()] {
+			return fmt.Errorf("host %s: address %s is taken", host.Name, address)
+		}
+		addresses[address.String()] = true
+
+		if len(host.Users) == 0 {
+			return fmt.Errorf("host %s: needs at least one user", host.Name)
+		}
+		users := make(map[string]bool)
+		for _, user := range host.Users {
+			if user.Name == "" || users[user.Name] {
+				return fmt.Errorf("host %s: user names must be unique and not empty", host.Name)
+			}
+			users[user.Name] = true
+		}
+		for name, value := range host.Filesystem {
+			if _, err := levelFileNode(value); err != nil {
+				return fmt.Errorf("host %s: file %s: %w", host.Name, name, err)
+			}
+		}
+		machine := &Level{Processes: host.Processes, Services: host.Services}
+		if err := validateProcesses(machine); err != nil {
+			return fmt.Errorf("host %s: %w", host.Name, err)
+		}
+		if err := validateServices(machine); err != nil {
+			return fmt.Errorf("host %s: %w", host.Name, err)
+		}
+	}
+	return nil
+}
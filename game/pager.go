@@ -0,0 +1,200 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pager holds the state of an interactive less/more session.
+// While a pager is open, keystrokes go to HandlePagerKey instead of the shell.
+type Pager struct {
+	Name  string // "less" or "more"
+	text  string
+	lines []string
+	top   int // first visible line (less) or lines already shown (more)
+	cols  int
+	rows  int
+}
+
+func newPager(name, text string, cols, rows int) *Pager {
+	p := &Pager{Name: name, text: text}
+	p.resize(cols, rows)
+	return p
+}
+
+func (p *Pager) resize(cols, rows int) {
+	p.cols = cols
+	p.rows = rows
+	p.lines = wrapLines(strings.TrimRight(p.text, "\n"), cols)
+	p.clampTop()
+}
+
+// pageSize is the number of content lines, leaving one row for the status line
+func (p *Pager) pageSize() int {
+	if p.rows <= 1 {
+		return 1
+	}
+	return p.rows - 1
+}
+
+func (p *Pager) fitsScreen() bool {
+	return len(p.lines) <= p.pageSize()
+}
+
+func (p *Pager) clampTop() {
+	if p.Name == "more" {
+		return
+	}
+	maxTop := len(p.lines) - p.pageSize()
+	if maxTop < 0 {
+		maxTop = 0
+	}
+	if p.top > maxTop {
+		p.top = maxTop
+	}
+	if p.top < 0 {
+		p.top = 0
+	}
+}
+
+// open returns the first screen of output
+func (p *Pager) open() string {
+	if p.Name == "more" {
+		return p.moreAdvance(p.pageSize())
+	}
+	return "\x1b[?1049h" + p.lessScreen()
+}
+
+// HandleKey processes one keystroke and returns what to draw and
+// whether the pager has exited
+func (p *Pager) HandleKey(key string) (string, bool) {
+	if p.Name == "more" {
+		return p.moreKey(key)
+	}
+	return p.lessKey(key)
+}
+
+func (p *Pager) lessKey(key string) (string, bool) {
+	switch key {
+	case "q", "Q", "\x03":
+		return "\x1b[?1049l", true
+	case " ", "f", "\x06", "\x1b[6~":
+		p.top += p.pageSize()
+	case "b", "\x02", "\x1b[5~":
+		p.top -= p.pageSize()
+	case "\r", "\n", "j", "e", "\x1b[B":
+		p.top++
+	case "k", "y", "\x1b[A":
+		p.top--
+	case "d":
+		p.top += p.pageSize() / 2
+	case "u":
+		p.top -= p.pageSize() / 2
+	case "g", "<":
+		p.top = 0
+	case "G", ">":
+		p.top = len(p.lines)
+	default:
+		return "\a", false // bell on unknown keys, like less
+	}
+
+	p.clampTop()
+	return p.lessScreen(), false
+}
+
+func (p *Pager) lessScreen() string {
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+
+	end := p.top + p.pageSize()
+	if end > len(p.lines) {
+		end = len(p.lines)
+	}
+	for _, line := range p.lines[p.top:end] {
+		screen.WriteString(line)
+		screen.WriteString("\r\n")
+	}
+	// Fill the rest of the screen like less does
+	for i := end - p.top; i < p.pageSize(); i++ {
+		screen.WriteString("~\r\n")
+	}
+
+	status := ":"
+	if end >= len(p.lines) {
+		status = "(END)"
+	}
+	screen.WriteString("\x1b[7m" + status + "\x1b[0m")
+	return screen.String()
+}
+
+func (p *Pager) moreKey(key string) (string, bool) {
+	switch key {
+	case "q", "Q", "\x03":
+		return "\r\x1b[K", true
+	case " ", "f", "\x1b[6~":
+		return p.moreStep(p.pageSize())
+	case "\r", "\n", "\x1b[B":
+		return p.moreStep(1)
+	default:
+		return "\a", false
+	}
+}
+
+// moreStep erases the --More-- prompt and prints the next n lines
+func (p *Pager) moreStep(n int) (string, bool) {
+	output := "\r\x1b[K" + p.moreAdvance(n)
+	return output, p.top >= len(p.lines)
+}
+
+func (p *Pager) moreAdvance(n int) string {
+	end := p.top + n
+	if end > len(p.lines) {
+		end = len(p.lines)
+	}
+
+	var output strings.Builder
+	for _, line := range p.lines[p.top:end] {
+		output.WriteString(line)
+		output.WriteString("\r\n")
+	}
+	p.top = end
+
+	if p.top < len(p.lines) {
+		percent := p.top * 100 / len(p.lines)
+		output.WriteString(fmt.Sprintf("\x1b[7m--More--(%d%%)\x1b[0m", percent))
+	}
+	return output.String()
+}
+
+// HandlePagerKey feeds a keystroke to the session's open pager.
+// It returns the terminal output to draw and whether the pager closed.
+func (e *GameEngine) HandlePagerKey(sessionID, key string) (string, bool) {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return "", true
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	if session.Pager == nil {
+		return "", true
+	}
+	output, done := session.Pager.HandleKey(key)
+	if done {
+		session.Pager = nil
+	}
+	return output, done
+}
+
+// PagerActive reports whether the session is inside less or more
+func (e *GameEngine) PagerActive(sessionID string) bool {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.Pager != nil
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSessionNotFound   = errors.New("session not found")
+	ErrInvalidPlayerName = errors.New("player names must be 1-32 letters, digits, '-' or '_'")
+	ErrPlayerTaken       = errors.New("player name taken, sign in with its token")
+	ErrSharedSession     = errors.New("team sessions are shared, sign in on a session of your own")
+)
+
+// Player keeps achievements and speedrun bests across sessions and
+// frontends. Sessions get an anonymous player of their own until they
+// sign in with a name.
+type Player struct {
+	Name         string               // Empty for anonymous players
+	Achievements map[string]time.Time // Unlock time by achievement ID
+	BestSplits   []time.Duration      // Splits of the fastest completed speedrun
+	Runs         int                  // Completed speedruns
+	token        string
+	mu           sync.Mutex // Taken after the session's lock
+}
+
+// SignIn attaches a session to a named player, creating the player on
+// first use, and returns the token later sign-ins need. What the
+// session earned before signing in carries over.
+func (e *GameEngine) SignIn(sessionID, name, token string) (string, error) {
+	if !teamNamePattern.MatchString(name) {
+		return "", ErrInvalidPlayerName
+	}
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return "", ErrSessionNotFound
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.TeamName != "" {
+		return "", ErrSharedSession
+	}
+
+	value, loaded := e.Players.LoadOrStore(strings.ToLower(name), &Player{
+		Name:  name,
+		token: newSessionToken(),
+	})
+	player := value.(*Player)
+	if loaded && subtle.ConstantTimeCompare([]byte(token), []byte(player.token)) != 1 {
+		return "", ErrPlayerTaken
+	}
+
+	previous := session.player
+	if previous != player {
+		player.mu.Lock()
+		previous.mu.Lock()
+		player.merge(previous)
+		previous.mu.Unlock()
+		player.mu.Unlock()
+		session.player = player
+	}
+	log.Printf("👤 Session %s signed in as %s", session.ID, player.Name)
+	return player.token, nil
+}
+
+// merge adds what another player earned, keeping the faster speedrun;
+// callers hold both locks
+func (p *Player) merge(other *Player) {
+	for id, at := range other.Achievements {
+		if earlier, done := p.Achievements[id]; !done || at.Before(earlier) {
+			if p.Achievements == nil {
+				p.Achievements = make(map[string]time.Time)
+			}
+			p.Achievements[id] = at
+		}
+	}
+	p.Runs += other.Runs
+	if best := other.BestSplits; len(best) > 0 &&
+		(len(p.BestSplits) == 0 || best[len(best)-1] < p.BestSplits[len(p.BestSplits)-1]) {
+		p.BestSplits = best
+	}
+}
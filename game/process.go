@@ -0,0 +1,655 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PIDs of the processes every machine starts with
+const (
+	initPID  = 1
+	sshdPID  = 612
+	shellPID = 2071
+)
+
+// firstLevelPID is where PIDs for a level's processes start when it
+// doesn't pick them; commands the player starts come after the shell
+const firstLevelPID = 700
+
+// Memory of the simulated machine in KiB, for %MEM and top
+const memTotalKB = 2031820
+
+// signalNames are the signals kill understands, by number
+var signalNames = map[int]string{
+	1: "HUP", 2: "INT", 3: "QUIT", 9: "KILL", 10: "USR1", 11: "SEGV", 12: "USR2",
+	13: "PIPE", 14: "ALRM", 15: "TERM", 17: "CHLD", 18: "CONT", 19: "STOP", 20: "TSTP",
+}
+
+// signalDescriptions are how bash reports a job ended by a signal
+var signalDescriptions = map[int]string{
+	1: "Hangup", 2: "Interrupt", 3: "Quit", 9: "Killed", 10: "User defined signal 1",
+	11: "Segmentation fault", 12: "User defined signal 2", 13: "Broken pipe",
+	14: "Alarm clock", 15: "Terminated", 19: "Stopped", 20: "Stopped",
+}
+
+// LevelProcess is a process a level starts with, such as a daemon whose
+// command line holds the flag. Levels can make a player find one and
+// kill it with an objective's Stopped PIDs.
+type LevelProcess struct {
+	PID     int               `json:"pid,omitempty"`     // Picked when 0
+	User    string            `json:"user,omitempty"`    // Defaults to root
+	Command string            `json:"command"`           // Command line, split like the shell does
+	Name    string            `json:"name,omitempty"`    // Short name ps and top show, from the command by default
+	Env     map[string]string `json:"env,omitempty"`     // Environment, in /proc/<pid>/environ
+	Files   []string          `json:"files,omitempty"`   // Files it holds open, as /proc/<pid>/fd links
+	Ignores []string          `json:"ignores,omitempty"` // Signals it survives, such as "TERM"
+	CPU     float64           `json:"cpu,omitempty"`     // %CPU for ps and top
+	Mem     float64           `json:"mem,omitempty"`     // %MEM for ps and top
+}
+
+// process is one entry of a process table. Entries are copied along
+// with the table, so the slices in them are never modified.
+type process struct {
+	pid, ppid int
+	user      string
+	name      string
+	argv      []string
+	env       []string // NAME=value
+	files     []string // Open files, from fd 3 up
+	ignores   []string // Signals it survives
+	state     byte     // 'R' running, 'S' sleeping or 'T' stopped
+	flags     string   // The rest of ps's STAT: "s" session leader, "+" foreground
+	tty       string   // "?" when it has no terminal
+	cpu, mem  float64
+	started   time.Time
+	job       int       // Background job number, 0 for other processes
+	line      string    // The job's command line as typed
+	until     time.Time // When a background sleep finishes
+}
+
+// processTable is the running processes of one machine. Snapshots
+// take a copy, so resetting a level brings killed processes back.
+type processTable struct {
+	procs   map[int]*process
+	next    int // Next PID to try
+	lastJob int // PID of the last background job, $!
+	booted  time.Time
+}
+
+// clone copies the table for a snapshot
+func (t *processTable) clone() *processTable {
+	copied := *t
+	copied.procs = make(map[int]*process, len(t.procs))
+	for pid, p := range t.procs {
+		entry := *p
+		copied.procs[pid] = &entry
+	}
+	return &copied
+}
+
+// allocate hands out the next unused PID
+func (t *processTable) allocate() int {
+	for t.procs[t.next] != nil {
+		t.next++
+	}
+	t.next++
+	return t.next - 1
+}
+
+// sorted lists the processes by PID
+func (t *processTable) sorted() []*process {
+	var list []*process
+	for _, p := range t.procs {
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].pid < list[j].pid })
+	return list
+}
+
+// comm is the short name ps and top show: the program, without its
+// directory or a login shell's leading dash
+func (p *process) comm() string {
+	if p.name != "" {
+		return p.name
+	}
+	program, _, _ := strings.Cut(p.argv[0], " ")
+	program = path.Base(strings.TrimSuffix(strings.TrimPrefix(program, "-"), ":"))
+	if len(program) > 15 {
+		program = program[:15]
+	}
+	return program
+}
+
+func (p *process) args() string { return strings.Join(p.argv, " ") }
+func (p *process) stat() string { return string(p.state) + p.flags }
+
+// cpuTime is the CPU time the process has used, from its %CPU over its
+// lifetime
+func (p *process) cpuTime(now time.Time) time.Duration {
+	return time.Duration(float64(now.Sub(p.started)) * p.cpu / 100).Truncate(time.Second)
+}
+
+// rss is the resident memory in KiB: a share of the machine's for a
+// process given %MEM, or a few MiB that vary by PID
+func (p *process) rss() int {
+	if p.mem > 0 {
+		return int(p.mem * memTotalKB / 100)
+	}
+	return 1800 + p.pid%11*384
+}
+
+func (p *process) vsz() int { return p.rss()*4 + 5240 }
+
+func (p *process) memPercent() float64 {
+	return float64(p.rss()) * 100 / memTotalKB
+}
+
+// startProcesses gives a new machine init, an SSH daemon and the
+// player's login shell
+func (vfs *VirtualFileSystem) startProcesses() {
+	now := time.Now()
+	vfs.procs = &processTable{procs: make(map[int]*process), next: shellPID + 1, booted: now.Add(-76 * time.Hour)}
+	vfs.install("/proc", &fileNode{mode: os.ModeDir | 0555, owner: "root"})
+	vfs.spawn(&process{pid: initPID, user: "root", argv: []string{"/sbin/init"}, state: 'S', flags: "s", tty: "?", started: vfs.procs.booted})
+	vfs.spawn(&process{pid: sshdPID, ppid: initPID, user: "root", argv: []string{"sshd: /usr/sbin/sshd -D [listener] 0 of 10-100 startups"},
+		state: 'S', flags: "s", tty: "?", started: vfs.procs.booted.Add(4 * time.Second)})
+	vfs.startShell()
+}
+
+// startShell starts the login shell of whoever is using the machine
+func (vfs *VirtualFileSystem) startShell() {
+	vfs.spawn(&process{pid: shellPID, ppid: sshdPID, user: vfs.user, argv: []string{"-bash"}, state: 'S', flags: "s", tty: "pts/0", started: time.Now(),
+		env: []string{"USER=" + vfs.user, "HOME=" + vfs.home, "SHELL=/bin/bash", "TERM=xterm-256color", "PATH=/usr/local/bin:/usr/bin:/bin"}})
+}
+
+// seedProcesses starts a level's processes under init
+func (vfs *VirtualFileSystem) seedProcesses(levelID int, seeds []LevelProcess) {
+	next := firstLevelPID
+	for _, seed := range seeds {
+		argv := parseCommandWithQuotes(seed.Command)
+		if len(argv) == 0 {
+			log.Printf("⚠️ Level %d: skipping a process with no command", levelID)
+			continue
+		}
+		pid := seed.PID
+		if pid == 0 || vfs.procs.procs[pid] != nil {
+			if pid != 0 {
+				log.Printf("⚠️ Level %d: PID %d is taken, renumbering %s", levelID, pid, argv[0])
+			}
+			for vfs.procs.procs[next] != nil {
+				next++
+			}
+			pid = next
+		}
+		user := seed.User
+		if user == "" {
+			user = "root"
+		}
+		var env []string
+		for name, value := range seed.Env {
+			env = append(env, name+"="+value)
+		}
+		sort.Strings(env)
+		vfs.spawn(&process{pid: pid, ppid: initPID, user: user, name: seed.Name, argv: argv, env: env, files: seed.Files,
+			ignores: seed.Ignores, state: 'S', tty: "?", cpu: seed.CPU, mem: seed.Mem,
+			started: vfs.procs.booted.Add(time.Duration(pid) * time.Second)})
+	}
+}
+
+// spawn adds a process and its /proc directory
+func (vfs *VirtualFileSystem) spawn(p *process) {
+	vfs.procs.procs[p.pid] = p
+	dir := "/proc/" + strconv.Itoa(p.pid)
+	vfs.install(dir, &fileNode{mode: os.ModeDir | 0555, owner: p.user, modTime: p.started})
+	vfs.install(dir+"/cmdline", &fileNode{content: []byte(strings.Join(p.argv, "\x00") + "\x00"), mode: 0444, owner: p.user, modTime: p.started})
+	var environ []byte
+	if len(p.env) > 0 {
+		environ = []byte(strings.Join(p.env, "\x00") + "\x00")
+	}
+	vfs.install(dir+"/environ", &fileNode{content: environ, mode: 0400, owner: p.user, modTime: p.started})
+	vfs.install(dir+"/comm", &fileNode{content: []byte(p.comm()), mode: 0444, owner: p.user, modTime: p.started})
+	vfs.writeStatus(p)
+
+	terminal := "/dev/null"
+	if p.tty != "?" {
+		terminal = "/dev/" + p.tty
+	}
+	vfs.install(dir+"/fd", &fileNode{mode: os.ModeDir | 0500, owner: p.user, modTime: p.started})
+	for fd, target := range append([]string{terminal, terminal, terminal}, p.files...) {
+		vfs.install(fmt.Sprintf("%s/fd/%d", dir, fd), &fileNode{mode: os.ModeSymlink | 0700, target: target, owner: p.user, modTime: p.started})
+	}
+}
+
+// writeStatus refreshes /proc/<pid>/status after a state change
+func (vfs *VirtualFileSystem) writeStatus(p *process) {
+	states := map[byte]string{'R': "R (running)", 'S': "S (sleeping)", 'T': "T (stopped)"}
+	status := fmt.Sprintf("Name:\t%s\nState:\t%s\nPid:\t%d\nPPid:\t%d", p.comm(), states[p.state], p.pid, p.ppid)
+	vfs.install(fmt.Sprintf("/proc/%d/status", p.pid), &fileNode{content: []byte(status), mode: 0444, owner: p.user, modTime: p.started})
+}
+
+// exit removes a process and its /proc directory
+func (vfs *VirtualFileSystem) exit(pid int) {
+	delete(vfs.procs.procs, pid)
+	dir := "/proc/" + strconv.Itoa(pid)
+	vfs.unshare()
+	for name := range vfs.files {
+		if name == dir || strings.HasPrefix(name, dir+"/") {
+			delete(vfs.files, name)
+		}
+	}
+	vfs.changed("/proc")
+}
+
+// signal delivers a signal, returning how a job it ends or stops is
+// reported, or "" when nothing the player would see happened. KILL and
+// STOP can't be ignored. The player's shell ignores everything: there's
+// no logging out of the game that way.
+func (vfs *VirtualFileSystem) signal(p *process, sig int) string {
+	switch {
+	case p.pid == shellPID:
+		return ""
+	case sig == 19 || sig == 20 && !p.ignoring(sig):
+		p.state = 'T'
+		vfs.writeStatus(p)
+		return jobStatus(vfs.procs, p, "Stopped")
+	case sig == 18:
+		if p.state == 'T' {
+			p.state = 'S'
+			vfs.writeStatus(p)
+		}
+		return ""
+	case sig == 17 || sig != 9 && p.ignoring(sig):
+		return ""
+	}
+	notice := jobStatus(vfs.procs, p, signalDescriptions[sig])
+	vfs.exit(p.pid)
+	return notice
+}
+
+// ignoring reports whether the process survives a signal
+func (p *process) ignoring(sig int) bool {
+	for _, name := range p.ignores {
+		if n, ok := parseSignal(name); ok && n == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSignal reads a signal as a number, a name or a SIG-prefixed name
+func parseSignal(spec string) (int, bool) {
+	if n, err := strconv.Atoi(spec); err == nil {
+		_, known := signalNames[n]
+		return n, known || n == 0
+	}
+	name := strings.TrimPrefix(strings.ToUpper(spec), "SIG")
+	for n, known := range signalNames {
+		if known == name {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// jobStatus is bash's line for a job changing state, or "" for other
+// processes
+func jobStatus(t *processTable, p *process, status string) string {
+	if p.job == 0 {
+		return ""
+	}
+	line := p.line
+	if status == "Running" {
+		line += " &"
+	}
+	return fmt.Sprintf("[%d]%c  %-24s%s", p.job, jobMarker(t, p.job), status, line)
+}
+
+// jobMarker flags the current job (the newest) with + and the one
+// before it with -
+func jobMarker(t *processTable, job int) byte {
+	var newer int
+	for _, p := range t.procs {
+		if p.job > job {
+			newer++
+		}
+	}
+	switch newer {
+	case 0:
+		return '+'
+	case 1:
+		return '-'
+	}
+	return ' '
+}
+
+// jobs lists the background jobs in order
+func (t *processTable) jobs() []*process {
+	var list []*process
+	for _, p := range t.procs {
+		if p.job != 0 {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].job < list[j].job })
+	return list
+}
+
+// reapJobs ends background sleeps whose time is up, returning bash's
+// Done lines for them
+func (vfs *VirtualFileSystem) reapJobs() []string {
+	var notices []string
+	now := time.Now()
+	for _, p := range vfs.procs.jobs() {
+		if !p.until.IsZero() && p.state != 'T' && now.After(p.until) {
+			notices = append(notices, jobStatus(vfs.procs, p, "Done"))
+			vfs.exit(p.pid)
+		}
+	}
+	return notices
+}
+
+// parseDuration reads sleep's NUMBER[smhd] argument
+func parseDuration(spec string) (time.Duration, bool) {
+	unit := time.Second
+	if n := len(spec); n > 0 {
+		if scale, suffixed := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}[spec[n-1]]; suffixed {
+			unit, spec = scale, spec[:n-1]
+		}
+	}
+	value, err := strconv.ParseFloat(spec, 64)
+	if err != nil || value < 0 {
+		return 0, false
+	}
+	return time.Duration(value * float64(unit)), true
+}
+
+// sleepCommand implements sleep. The game can't hold up the terminal,
+// so a foreground sleep returns at once; a background one lives in the
+// process table for its duration.
+func sleepCommand(args []string) commandResult {
+	if len(args) == 0 {
+		return errorResult("sleep: missing operand\nTry 'sleep --help' for more information.", 1)
+	}
+	for _, arg := range args {
+		if _, ok := parseDuration(arg); !ok {
+			return errorResult("sleep: invalid time interval '"+arg+"'\nTry 'sleep --help' for more information.", 1)
+		}
+	}
+	return commandResult{}
+}
+
+// startJob runs a command line ending in & as a background job. A sleep
+// keeps running; anything else finishes straight away, its output
+// followed by the job's Done line.
+func (e *GameEngine) startJob(line string, session *Session, level *Level) (commandResult, bool) {
+	vfs := session.VirtualFS
+	argv := parseCommandWithQuotes(splitPipeline(line)[0])
+	if len(argv) == 0 {
+		return errorResult("syntax error near unexpected token `&'", 2), false
+	}
+	job := 1
+	for _, p := range vfs.procs.jobs() {
+		job = max(job, p.job+1)
+	}
+	p := &process{pid: vfs.procs.allocate(), ppid: shellPID, user: vfs.user, argv: argv, state: 'S', tty: "pts/0",
+		started: time.Now(), job: job, line: line}
+	vfs.procs.lastJob = p.pid
+	started := fmt.Sprintf("[%d] %d", job, p.pid)
+
+	if argv[0] == "sleep" && len(argv) > 1 {
+		var total time.Duration
+		for _, arg := range argv[1:] {
+			duration, _ := parseDuration(arg)
+			total += duration
+		}
+		if sleepCommand(argv[1:]).exitCode == 0 {
+			p.until = p.started.Add(total)
+			vfs.spawn(p)
+			return stdoutResult(started), false
+		}
+	}
+
+	result, completed := e.runLine(line, session, level, false)
+	vfs.procs.procs[p.pid] = p
+	status := "Done"
+	if result.exitCode != 0 {
+		status = fmt.Sprintf("Exit %d", result.exitCode)
+	}
+	done := jobStatus(vfs.procs, p, status)
+	delete(vfs.procs.procs, p.pid)
+
+	output := []string{started}
+	if result.stdout != "" {
+		output = append(output, result.stdout)
+	}
+	result.stdout = strings.Join(append(output, done), "\n")
+	result.exitCode = 0
+	return result, completed
+}
+
+// splitBackground strips an unquoted & that ends a command line
+func splitBackground(line string) (string, bool) {
+	if !strings.HasSuffix(line, "&") || strings.HasSuffix(line, "&&") || strings.HasSuffix(line, ">&") {
+		return line, false
+	}
+	var quote byte
+	for i := 0; i < len(line)-1; i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' || c == '"':
+			quote = c
+		}
+	}
+	if quote != 0 || strings.HasSuffix(line, "\\&") {
+		return line, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(line, "&")), true
+}
+
+// jobsCommand implements the jobs builtin
+func jobsCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	flags, _, usage := parseFlags("jobs", args, "lp", nil)
+	if usage != nil {
+		return *usage
+	}
+	var lines []string
+	for _, p := range vfs.procs.jobs() {
+		status := "Running"
+		if p.state == 'T' {
+			status = "Stopped"
+		}
+		switch {
+		case flags['p']:
+			lines = append(lines, strconv.Itoa(p.pid))
+		case flags['l']:
+			line := p.line
+			if status == "Running" {
+				line += " &"
+			}
+			lines = append(lines, fmt.Sprintf("[%d]%c  %d %-24s%s", p.job, jobMarker(vfs.procs, p.job), p.pid, status, line))
+		default:
+			lines = append(lines, jobStatus(vfs.procs, p, status))
+		}
+	}
+	return stdoutResult(strings.Join(lines, "\n"))
+}
+
+// findJob resolves a jobspec (%1, %+, %-, %sleep), or the current job
+// when spec is empty
+func findJob(vfs *VirtualFileSystem, spec string) *process {
+	jobs := vfs.procs.jobs()
+	if len(jobs) == 0 {
+		return nil
+	}
+	switch spec = strings.TrimPrefix(spec, "%"); spec {
+	case "", "+", "%":
+		return jobs[len(jobs)-1]
+	case "-":
+		if len(jobs) < 2 {
+			return jobs[len(jobs)-1]
+		}
+		return jobs[len(jobs)-2]
+	}
+	if n, err := strconv.Atoi(spec); err == nil {
+		for _, p := range jobs {
+			if p.job == n {
+				return p
+			}
+		}
+		return nil
+	}
+	for _, p := range jobs {
+		if strings.HasPrefix(p.line, spec) {
+			return p
+		}
+	}
+	return nil
+}
+
+// fgBgCommand implements fg and bg. A job brought to the foreground
+// runs to completion at once, since the game can't wait on it.
+func fgBgCommand(vfs *VirtualFileSystem, command string, args []string) commandResult {
+	spec := ""
+	if len(args) > 0 {
+		spec = args[0]
+	}
+	p := findJob(vfs, spec)
+	if p == nil {
+		if spec == "" {
+			spec = "current"
+		}
+		return errorResult(command+": "+spec+": no such job", 1)
+	}
+	if command == "bg" {
+		if p.state != 'T' {
+			return errorResult(fmt.Sprintf("bg: job %d already in background", p.job), 0)
+		}
+		p.state = 'S'
+		vfs.writeStatus(p)
+		return stdoutResult(fmt.Sprintf("[%d]%c %s &", p.job, jobMarker(vfs.procs, p.job), p.line))
+	}
+	vfs.exit(p.pid)
+	return stdoutResult(p.line)
+}
+
+// killCommand implements the kill builtin: kill [-s SIG | -SIG] PID|%JOB...
+// or kill -l [SIG]
+func killCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	usage := errorResult("kill: usage: kill [-s sigspec | -n signum | -sigspec] pid | jobspec ... or kill -l [sigspec]", 2)
+	if len(args) == 0 {
+		return usage
+	}
+	if args[0] == "-l" || args[0] == "-L" {
+		return signalList(args[1:])
+	}
+
+	sig := 15
+	switch {
+	case args[0] == "-s" || args[0] == "-n":
+		if len(args) < 2 {
+			return errorResult("kill: "+args[0]+": option requires an argument\n"+usage.stderr, 2)
+		}
+		n, ok := parseSignal(args[1])
+		if !ok {
+			return errorResult("kill: "+args[1]+": invalid signal specification", 1)
+		}
+		sig, args = n, args[2:]
+	case args[0] == "--":
+		args = args[1:]
+	case strings.HasPrefix(args[0], "-") && len(args[0]) > 1:
+		n, ok := parseSignal(args[0][1:])
+		if !ok {
+			return errorResult("kill: "+args[0][1:]+": invalid signal specification", 1)
+		}
+		sig, args = n, args[1:]
+	}
+	if len(args) == 0 {
+		return usage
+	}
+
+	var result commandResult
+	var notices []string
+	for _, target := range args {
+		var p *process
+		if strings.HasPrefix(target, "%") {
+			if p = findJob(vfs, target); p == nil {
+				result.fail("kill: "+target+": no such job", 1)
+				continue
+			}
+		} else {
+			pid, err := strconv.Atoi(target)
+			if err != nil {
+				result.fail("kill: "+target+": arguments must be process or job IDs", 1)
+				continue
+			}
+			if p = vfs.procs.procs[pid]; p == nil {
+				result.fail(fmt.Sprintf("kill: (%d) - No such process", pid), 1)
+				continue
+			}
+		}
+		if p.user != vfs.user {
+			result.fail(fmt.Sprintf("kill: (%d) - Operation not permitted", p.pid), 1)
+			continue
+		}
+		if sig == 0 {
+			continue
+		}
+		if notice := vfs.signal(p, sig); notice != "" {
+			notices = append(notices, notice)
+		}
+	}
+	result.stdout = strings.Join(notices, "\n")
+	return result
+}
+
+// signalList is kill -l: every signal, or the name or number of those
+// given
+func signalList(args []string) commandResult {
+	if len(args) == 0 {
+		var numbers []int
+		for n := range signalNames {
+			numbers = append(numbers, n)
+		}
+		sort.Ints(numbers)
+		var lines []string
+		var row []string
+		for _, n := range numbers {
+			row = append(row, fmt.Sprintf("%2d) SIG%s", n, signalNames[n]))
+			if len(row) == 5 {
+				lines = append(lines, strings.Join(row, "\t"))
+				row = nil
+			}
+		}
+		if len(row) > 0 {
+			lines = append(lines, strings.Join(row, "\t"))
+		}
+		return stdoutResult(strings.Join(lines, "\n"))
+	}
+
+	var result commandResult
+	var lines []string
+	for _, arg := range args {
+		n, ok := parseSignal(arg)
+		switch {
+		case !ok || n == 0:
+			result.fail("kill: "+arg+": invalid signal specification", 1)
+		case strings.Trim(arg, "0123456789") == "":
+			lines = append(lines, signalNames[n])
+		default:
+			lines = append(lines, strconv.Itoa(n))
+		}
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
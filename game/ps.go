@@ -0,0 +1,445 @@
+package game
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// psColumn is one column ps can print with -o
+type psColumn struct {
+	header string
+	width  int
+	left   bool // Left-aligned, as text columns are
+	value  func(p *process, now time.Time) string
+}
+
+var psColumns = map[string]psColumn{
+	"pid":     {"PID", 7, false, func(p *process, _ time.Time) string { return strconv.Itoa(p.pid) }},
+	"ppid":    {"PPID", 7, false, func(p *process, _ time.Time) string { return strconv.Itoa(p.ppid) }},
+	"user":    {"USER", 8, true, func(p *process, _ time.Time) string { return psUser(p.user) }},
+	"uid":     {"UID", 8, true, func(p *process, _ time.Time) string { return psUser(p.user) }},
+	"stat":    {"STAT", 4, true, func(p *process, _ time.Time) string { return p.stat() }},
+	"s":       {"S", 1, true, func(p *process, _ time.Time) string { return string(p.state) }},
+	"tty":     {"TT", 8, true, func(p *process, _ time.Time) string { return p.tty }},
+	"comm":    {"COMMAND", 15, true, func(p *process, _ time.Time) string { return p.comm() }},
+	"args":    {"COMMAND", 0, true, func(p *process, _ time.Time) string { return p.args() }},
+	"cmd":     {"CMD", 0, true, func(p *process, _ time.Time) string { return p.args() }},
+	"%cpu":    {"%CPU", 4, false, func(p *process, _ time.Time) string { return fmt.Sprintf("%.1f", p.cpu) }},
+	"%mem":    {"%MEM", 4, false, func(p *process, _ time.Time) string { return fmt.Sprintf("%.1f", p.memPercent()) }},
+	"vsz":     {"VSZ", 6, false, func(p *process, _ time.Time) string { return strconv.Itoa(p.vsz()) }},
+	"rss":     {"RSS", 5, false, func(p *process, _ time.Time) string { return strconv.Itoa(p.rss()) }},
+	"time":    {"TIME", 8, false, func(p *process, now time.Time) string { return clockTime(p.cpuTime(now)) }},
+	"start":   {"STARTED", 8, false, func(p *process, now time.Time) string { return p.started.Format("15:04:05") }},
+	"etime":   {"ELAPSED", 11, false, func(p *process, now time.Time) string { return elapsedTime(now.Sub(p.started)) }},
+	"command": {"COMMAND", 0, true, func(p *process, _ time.Time) string { return p.args() }},
+}
+
+// Other names ps accepts for its columns
+var psColumnAliases = map[string]string{"pcpu": "%cpu", "pmem": "%mem", "state": "s", "tt": "tty", "ucmd": "comm", "euser": "user"}
+
+// psUser fits a user name in ps's 8 columns, marking a cut with +
+func psUser(name string) string {
+	if len(name) > 8 {
+		return name[:7] + "+"
+	}
+	return name
+}
+
+// clockTime is a duration as HH:MM:SS
+func clockTime(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%02d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+}
+
+// minutesTime is a duration as ps aux's M:SS
+func minutesTime(d time.Duration) string {
+	seconds := int(d.Seconds())
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// elapsedTime is ps's [[DD-]HH:]MM:SS
+func elapsedTime(d time.Duration) string {
+	seconds := int(d.Seconds())
+	days, hours := seconds/86400, seconds/3600%24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%d-%02d:%02d:%02d", days, hours, seconds/60%60, seconds%60)
+	case hours > 0:
+		return fmt.Sprintf("%02d:%02d:%02d", hours, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// startTime is ps's START: the time today, or else the date
+func startTime(started, now time.Time) string {
+	if now.Sub(started) < 24*time.Hour {
+		return started.Format("15:04")
+	}
+	return started.Format("Jan02")
+}
+
+// psCommand implements ps with the common BSD (aux) and standard (-ef,
+// -u USER, -p PID, -C NAME, -o COLUMNS) options. ps itself shows up as
+// a running process, as it does on a real machine.
+func psCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	var all, bsdAll, bsdNoTTY, bsdUser, full bool
+	var users, names, format []string
+	pids := map[int]bool{}
+	usage := func(message string) commandResult {
+		return errorResult("error: "+message+"\n\nUsage:\n ps [options]\n\n Try 'ps --help <simple|list|output|threads|misc|all>'\n  or 'ps --help <s|l|o|t|m|a>'\n for additional help text.\n\nFor more details see ps(1).", 1)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			for _, c := range arg {
+				switch c {
+				case 'a':
+					bsdAll = true
+				case 'x':
+					bsdNoTTY = true
+				case 'u':
+					bsdUser = true
+				case 'w':
+				default:
+					return usage("unsupported option (BSD syntax)")
+				}
+			}
+			continue
+		}
+		for j := 1; j < len(arg); j++ {
+			switch c := arg[j]; c {
+			case 'e', 'A':
+				all = true
+			case 'f':
+				full = true
+			case 'u', 'U', 'p', 'C', 'o':
+				value := arg[j+1:]
+				if value == "" {
+					if i+1 >= len(args) {
+						return usage("list of " + map[byte]string{'u': "users", 'U': "users", 'p': "process IDs", 'C': "command names", 'o': "format specifiers"}[c] + " must follow -" + string(c))
+					}
+					i++
+					value = args[i]
+				}
+				list := strings.Split(value, ",")
+				switch c {
+				case 'u', 'U':
+					users = append(users, list...)
+				case 'C':
+					names = append(names, list...)
+				case 'o':
+					format = append(format, list...)
+				case 'p':
+					for _, item := range list {
+						pid, err := strconv.Atoi(item)
+						if err != nil || pid < 1 {
+							return usage("process ID list syntax error")
+						}
+						pids[pid] = true
+					}
+				}
+				j = len(arg)
+			default:
+				return usage("unsupported SysV option")
+			}
+		}
+	}
+
+	now := time.Now()
+	self := &process{pid: vfs.procs.allocate(), ppid: shellPID, user: vfs.user, argv: append([]string{"ps"}, args...),
+		state: 'R', flags: "+", tty: "pts/0", started: now}
+	running := append(vfs.procs.sorted(), self)
+	sort.Slice(running, func(i, j int) bool { return running[i].pid < running[j].pid })
+	var selected []*process
+	for _, p := range running {
+		var chosen bool
+		switch {
+		case len(users) > 0 || len(names) > 0 || len(pids) > 0:
+			chosen = pids[p.pid] || containsString(users, p.user) || containsString(names, p.comm())
+		case all, bsdAll && bsdNoTTY:
+			chosen = true
+		case bsdAll:
+			chosen = p.tty != "?"
+		case bsdNoTTY:
+			chosen = p.user == vfs.user
+		default:
+			chosen = p.tty == "pts/0" && p.user == vfs.user
+		}
+		if chosen {
+			selected = append(selected, p)
+		}
+	}
+
+	var lines []string
+	switch {
+	case len(format) > 0:
+		return psFormat(selected, format, now)
+	case bsdUser:
+		lines = append(lines, "USER         PID %CPU %MEM    VSZ   RSS TTY      STAT START   TIME COMMAND")
+		for _, p := range selected {
+			lines = append(lines, fmt.Sprintf("%-8s %7d %4.1f %4.1f %6d %5d %-8s %-4s %5s %6s %s", psUser(p.user), p.pid, p.cpu, p.memPercent(),
+				p.vsz(), p.rss(), p.tty, p.stat(), startTime(p.started, now), minutesTime(p.cpuTime(now)), p.args()))
+		}
+	case bsdAll || bsdNoTTY:
+		lines = append(lines, "    PID TTY      STAT   TIME COMMAND")
+		for _, p := range selected {
+			lines = append(lines, fmt.Sprintf("%7d %-8s %-4s %6s %s", p.pid, p.tty, p.stat(), minutesTime(p.cpuTime(now)), p.args()))
+		}
+	case full:
+		lines = append(lines, "UID          PID    PPID  C STIME TTY          TIME CMD")
+		for _, p := range selected {
+			lines = append(lines, fmt.Sprintf("%-8s %7d %7d %2d %5s %-8s %8s %s", psUser(p.user), p.pid, p.ppid, int(p.cpu),
+				startTime(p.started, now), p.tty, clockTime(p.cpuTime(now)), p.args()))
+		}
+	default:
+		lines = append(lines, "    PID TTY          TIME CMD")
+		for _, p := range selected {
+			lines = append(lines, fmt.Sprintf("%7d %-8s %8s %s", p.pid, p.tty, clockTime(p.cpuTime(now)), p.comm()))
+		}
+	}
+
+	result := stdoutResult(strings.Join(lines, "\n"))
+	if len(selected) == 0 {
+		result.exitCode = 1
+	}
+	return result
+}
+
+// psFormat prints ps -o columns. A column named with a trailing = has
+// no header, and the header line goes when none of them has one.
+func psFormat(selected []*process, format []string, now time.Time) commandResult {
+	var columns []psColumn
+	headed := false
+	for _, spec := range format {
+		name, header, renamed := strings.Cut(spec, "=")
+		name = strings.ToLower(name)
+		if alias, exists := psColumnAliases[name]; exists {
+			name = alias
+		}
+		column, known := psColumns[name]
+		if !known {
+			return errorResult("error: unknown user-defined format specifier \""+name+"\"\n\nUsage:\n ps [options]", 1)
+		}
+		if renamed {
+			column.header = header
+		}
+		headed = headed || column.header != ""
+		columns = append(columns, column)
+	}
+
+	rows := [][]string{}
+	if headed {
+		var header []string
+		for _, column := range columns {
+			header = append(header, column.header)
+		}
+		rows = append(rows, header)
+	}
+	for _, p := range selected {
+		var row []string
+		for _, column := range columns {
+			row = append(row, column.value(p, now))
+		}
+		rows = append(rows, row)
+	}
+
+	var lines []string
+	for _, row := range rows {
+		var cells []string
+		for i, column := range columns {
+			width := max(column.width, len(column.header))
+			switch {
+			case i == len(columns)-1 && column.left:
+				cells = append(cells, row[i])
+			case column.left:
+				cells = append(cells, fmt.Sprintf("%-*s", width, row[i]))
+			default:
+				cells = append(cells, fmt.Sprintf("%*s", width, row[i]))
+			}
+		}
+		lines = append(lines, strings.Join(cells, " "))
+	}
+	result := stdoutResult(strings.Join(lines, "\n"))
+	if len(selected) == 0 {
+		result.exitCode = 1
+	}
+	return result
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// pgrepCommand implements pgrep and pkill: match processes by a regexp
+// on their name (or with -f their whole command line), then list them
+// or signal them
+func pgrepCommand(vfs *VirtualFileSystem, command string, args []string) commandResult {
+	sig := 15
+	if command == "pkill" && len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		if n, ok := parseSignal(args[0][1:]); ok {
+			sig, args = n, args[1:]
+		}
+	}
+	flags, values, operands, usage := parseOptions(command, args, "acfilnovx", "Uuds", map[string]rune{
+		"list-full": 'a', "count": 'c', "full": 'f', "ignore-case": 'i', "list-name": 'l', "newest": 'n',
+		"oldest": 'o', "exact": 'x', "euid": 'u', "uid": 'U', "delimiter": 'd', "inverse": 'v', "signal": 's'})
+	if usage != nil {
+		usage.exitCode = 2
+		return *usage
+	}
+	if signal, set := values['s']; set {
+		n, ok := parseSignal(signal)
+		if !ok {
+			return errorResult(command+": Unknown signal \""+signal+"\".", 2)
+		}
+		sig = n
+	}
+	users := strings.Split(values['u']+","+values['U'], ",")
+	if len(operands) == 0 && values['u'] == "" && values['U'] == "" {
+		return errorResult(command+": no matching criteria specified\nTry `"+command+" --help' for more information.", 2)
+	}
+	if len(operands) > 1 {
+		return errorResult(command+": only one pattern can be provided\nTry `"+command+" --help' for more information.", 2)
+	}
+
+	pattern := ""
+	if len(operands) == 1 {
+		pattern = operands[0]
+	}
+	if flags['x'] {
+		pattern = "^(?:" + pattern + ")$"
+	}
+	if flags['i'] {
+		pattern = "(?i)" + pattern
+	}
+	matcher, err := regexp.Compile(pattern)
+	if err != nil {
+		return errorResult(command+": invalid regular expression: "+operands[0], 2)
+	}
+
+	var matched []*process
+	for _, p := range vfs.procs.sorted() {
+		subject := p.comm()
+		if flags['f'] {
+			subject = p.args()
+		}
+		hit := matcher.MatchString(subject) && (values['u'] == "" && values['U'] == "" || containsString(users, p.user))
+		if hit != flags['v'] {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) > 0 && (flags['n'] || flags['o']) {
+		sort.SliceStable(matched, func(i, j int) bool { return matched[i].started.Before(matched[j].started) })
+		if flags['n'] {
+			matched = matched[len(matched)-1:]
+		} else {
+			matched = matched[:1]
+		}
+	}
+
+	var result commandResult
+	var lines []string
+	if command == "pkill" {
+		var notices []string
+		for _, p := range matched {
+			if p.user != vfs.user {
+				result.fail(fmt.Sprintf("pkill: killing pid %d failed: Operation not permitted", p.pid), 1)
+				continue
+			}
+			if notice := vfs.signal(p, sig); notice != "" {
+				notices = append(notices, notice)
+			}
+		}
+		if flags['c'] {
+			notices = append([]string{strconv.Itoa(len(matched))}, notices...)
+		}
+		result.stdout = strings.Join(notices, "\n")
+	} else if flags['c'] {
+		lines = append(lines, strconv.Itoa(len(matched)))
+	} else {
+		for _, p := range matched {
+			switch {
+			case flags['a']:
+				lines = append(lines, fmt.Sprintf("%d %s", p.pid, p.args()))
+			case flags['l']:
+				lines = append(lines, fmt.Sprintf("%d %s", p.pid, p.comm()))
+			default:
+				lines = append(lines, strconv.Itoa(p.pid))
+			}
+		}
+		delimiter := "\n"
+		if d, set := values['d']; set {
+			delimiter = d
+		}
+		result.stdout = strings.Join(lines, delimiter)
+	}
+	if len(matched) == 0 && result.exitCode == 0 {
+		result.exitCode = 1
+	}
+	return result
+}
+
+// topCommand implements top as one batch-mode frame: the game's
+// terminal can't redraw a live one
+func topCommand(vfs *VirtualFileSystem, args []string) commandResult {
+	_, values, _, usage := parseOptions("top", args, "bcHiS", "dnpu", nil)
+	if usage != nil {
+		return *usage
+	}
+	if value, set := values['n']; set {
+		if n, err := strconv.Atoi(value); err != nil || n < 1 {
+			return errorResult("top: bad iterations argument '"+value+"'", 1)
+		}
+	}
+
+	now := time.Now()
+	self := &process{pid: vfs.procs.allocate(), ppid: shellPID, user: vfs.user, argv: []string{"top"}, state: 'R', flags: "+", tty: "pts/0", started: now}
+	var shown []*process
+	var states = map[byte]int{}
+	var load, used float64
+	for _, p := range append(vfs.procs.sorted(), self) {
+		states[p.state]++
+		load += p.cpu
+		used += float64(p.rss())
+		if user, set := values['u']; set && p.user != user {
+			continue
+		}
+		if pid, set := values['p']; set && pid != strconv.Itoa(p.pid) {
+			continue
+		}
+		shown = append(shown, p)
+	}
+	sort.SliceStable(shown, func(i, j int) bool { return shown[i].cpu > shown[j].cpu })
+
+	uptime := now.Sub(vfs.procs.booted)
+	idle := max(100-load, 0)
+	total := float64(memTotalKB) / 1024
+	usedMiB := used / 1024
+	lines := []string{
+		fmt.Sprintf("top - %s up %d days, %2d:%02d,  1 user,  load average: %.2f, %.2f, %.2f", now.Format("15:04:05"),
+			int(uptime.Hours())/24, int(uptime.Hours())%24, int(uptime.Minutes())%60, load/100, load/120, load/150),
+		fmt.Sprintf("Tasks: %3d total, %3d running, %3d sleeping, %3d stopped,   0 zombie", len(vfs.procs.procs)+1, states['R'], states['S'], states['T']),
+		fmt.Sprintf("%%Cpu(s): %4.1f us,  0.3 sy,  0.0 ni, %4.1f id,  0.0 wa,  0.0 hi,  0.0 si,  0.0 st", min(load, 100), idle),
+		fmt.Sprintf("MiB Mem : %8.1f total, %8.1f free, %8.1f used, %8.1f buff/cache", total, total-usedMiB-412.3, usedMiB, 412.3),
+		fmt.Sprintf("MiB Swap: %8.1f total, %8.1f free, %8.1f used. %8.1f avail Mem", 0.0, 0.0, 0.0, total-usedMiB),
+		"",
+		"    PID USER      PR  NI    VIRT    RES    SHR S  %CPU  %MEM     TIME+ COMMAND",
+	}
+	for _, p := range shown {
+		cpuTime := p.cpuTime(now)
+		lines = append(lines, fmt.Sprintf("%7d %-8s %3d %3d %7d %6d %6d %c %5.1f %5.1f %9s %s", p.pid, psUser(p.user), 20, 0,
+			p.vsz(), p.rss(), p.rss()*2/3, p.state, p.cpu, p.memPercent(),
+			fmt.Sprintf("%d:%02d.%02d", int(cpuTime.Minutes()), int(cpuTime.Seconds())%60, p.pid%100), p.comm()))
+	}
+	return stdoutResult(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,402 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RaceCountdown is how long players see the countdown before input unlocks
+	RaceCountdown = 5 * time.Second
+
+	defaultRaceSize = 2
+	maxRaceSize     = 16
+)
+
+var (
+	ErrInvalidRaceName = errors.New("race names must be 1-32 letters, digits, '-' or '_'")
+	ErrRaceStarted     = errors.New("this race has already started")
+	ErrRaceFull        = errors.New("this race is full")
+	ErrNotRaceHost     = errors.New("only the player who created the race can start it")
+	ErrRaceTooSmall    = errors.New("a race needs at least two players")
+)
+
+// Race is a head-to-head run through every level. Each player gets an
+// independent session built from the same level definitions, so everyone
+// sees identical content; input unlocks for all of them at StartsAt.
+type Race struct {
+	Name       string
+	Size       int // Players needed before the countdown starts by itself
+	Host       string
+	Players    []*RacePlayer
+	StartsAt   time.Time // Zero until the countdown begins
+	FinishedAt time.Time // Zero until every player finished or left
+	CreatedAt  time.Time
+	mu         sync.Mutex
+}
+
+type RacePlayer struct {
+	Name      string
+	SessionID string
+	Level     int             // Levels cleared so far
+	Splits    []time.Duration // Time from the start to each cleared level
+	Finished  bool
+	Left      bool
+}
+
+// elapsed is the player's time at their last cleared level
+func (p *RacePlayer) elapsed() time.Duration {
+	if len(p.Splits) == 0 {
+		return 0
+	}
+	return p.Splits[len(p.Splits)-1]
+}
+
+// JoinRace adds a player to a race lobby, creating the race on first join
+// with room for size players. Joining the last free spot starts the countdown.
+func (e *GameEngine) JoinRace(raceName, playerName, ip string, size int) (*Race, *Session, string, error) {
+	if !teamNamePattern.MatchString(raceName) {
+		return nil, nil, "", ErrInvalidRaceName
+	}
+	if playerName == "" {
+		playerName = "player"
+	}
+	if !teamNamePattern.MatchString(playerName) {
+		return nil, nil, "", ErrInvalidMemberName
+	}
+	if size < 2 || size > maxRaceSize {
+		size = defaultRaceSize
+	}
+
+	value, loaded := e.Races.LoadOrStore(strings.ToLower(raceName), &Race{
+		Name:      raceName,
+		Size:      size,
+		Host:      playerName,
+		CreatedAt: time.Now(),
+	})
+	race := value.(*Race)
+	if !loaded {
+		log.Printf("🏁 Race %s created for %d players", race.Name, race.Size)
+	}
+
+	race.mu.Lock()
+	if !race.StartsAt.IsZero() {
+		race.mu.Unlock()
+		return nil, nil, "", ErrRaceStarted
+	}
+	if len(race.Players) >= race.Size {
+		race.mu.Unlock()
+		return nil, nil, "", ErrRaceFull
+	}
+
+	name := playerName
+	for i := 2; race.player(name) != nil; i++ {
+		name = fmt.Sprintf("%s%d", playerName, i)
+	}
+
+	session := e.CreateSession(ip)
+	session.RaceName = race.Name
+	race.Players = append(race.Players, &RacePlayer{Name: name, SessionID: session.ID})
+
+	var events []Event
+	if len(race.Players) == race.Size {
+		events = append(events, race.startCountdown())
+	}
+	race.mu.Unlock()
+
+	log.Printf("🏁 %s joined race %s", name, race.Name)
+	e.emit(events...)
+	return race, session, name, nil
+}
+
+// StartRace lets the host start the countdown before the lobby is full
+func (e *GameEngine) StartRace(raceName, playerName string) error {
+	race, exists := e.GetRace(raceName)
+	if !exists {
+		return errors.New("race not found")
+	}
+
+	race.mu.Lock()
+	switch {
+	case !race.StartsAt.IsZero():
+		race.mu.Unlock()
+		return ErrRaceStarted
+	case race.Host != playerName:
+		race.mu.Unlock()
+		return ErrNotRaceHost
+	case len(race.Players) < 2:
+		race.mu.Unlock()
+		return ErrRaceTooSmall
+	}
+	event := race.startCountdown()
+	race.mu.Unlock()
+
+	e.emit(event)
+	return nil
+}
+
+// startCountdown fixes the moment input unlocks; callers hold race.mu
+func (r *Race) startCountdown() Event {
+	r.StartsAt = time.Now().Add(RaceCountdown)
+	log.Printf("🏁 Race %s starts at %s", r.Name, r.StartsAt.Format(time.TimeOnly))
+	return Event{Type: EventRaceCountdown, Group: r.Name, Time: r.StartsAt}
+}
+
+// LeaveRace drops a player from the lobby, or forfeits once running
+func (e *GameEngine) LeaveRace(raceName, playerName string) {
+	race, exists := e.GetRace(raceName)
+	if !exists {
+		return
+	}
+
+	race.mu.Lock()
+	player := race.player(playerName)
+	if player == nil || !race.FinishedAt.IsZero() {
+		race.mu.Unlock()
+		return
+	}
+
+	var events []Event
+	if race.StartsAt.IsZero() {
+		for i, p := range race.Players {
+			if p == player {
+				race.Players = append(race.Players[:i], race.Players[i+1:]...)
+				break
+			}
+		}
+		e.Sessions.Delete(player.SessionID)
+		if len(race.Players) == 0 {
+			e.Races.Delete(strings.ToLower(race.Name))
+		} else if race.Host == player.Name {
+			race.Host = race.Players[0].Name
+		}
+	} else if !player.Finished {
+		player.Left = true
+		events = append(events, Event{
+			Type:      EventRaceProgress,
+			SessionID: player.SessionID,
+			Level:     player.Level,
+			Group:     race.Name,
+			Message:   fmt.Sprintf("%s left the race after %d levels", player.Name, player.Level),
+			Time:      time.Now(),
+		})
+		events = append(events, race.checkFinished(player.SessionID)...)
+	}
+	race.mu.Unlock()
+
+	e.emit(events...)
+}
+
+func (e *GameEngine) GetRace(raceName string) (*Race, bool) {
+	value, exists := e.Races.Load(strings.ToLower(raceName))
+	if !exists {
+		return nil, false
+	}
+	return value.(*Race), true
+}
+
+// RacePlayers returns the session IDs taking part in a race
+func (e *GameEngine) RacePlayers(raceName string) []string {
+	race, exists := e.GetRace(raceName)
+	if !exists {
+		return nil
+	}
+
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	var sessionIDs []string
+	for _, player := range race.Players {
+		sessionIDs = append(sessionIDs, player.SessionID)
+	}
+	return sessionIDs
+}
+
+// RaceResults renders the race's standings table
+func (e *GameEngine) RaceResults(raceName string) string {
+	race, exists := e.GetRace(raceName)
+	if !exists {
+		return ""
+	}
+
+	race.mu.Lock()
+	defer race.mu.Unlock()
+	return race.table(len(e.Levels))
+}
+
+// raceGate keeps racers at the starting line until the countdown ends
+func (e *GameEngine) raceGate(s *Session, command string) string {
+	if s.RaceName == "" || strings.TrimSpace(command) == "race" {
+		return ""
+	}
+	race, exists := e.GetRace(s.RaceName)
+	if !exists {
+		return ""
+	}
+
+	race.mu.Lock()
+	defer race.mu.Unlock()
+
+	switch {
+	case race.StartsAt.IsZero():
+		return fmt.Sprintf("race: waiting for players (%d/%d)", len(race.Players), race.Size)
+	case time.Now().Before(race.StartsAt):
+		return fmt.Sprintf("race: starts in %ds", int(time.Until(race.StartsAt).Seconds())+1)
+	}
+	return ""
+}
+
+// trackRace records splits when a racer clears a level
+func (e *GameEngine) trackRace(event Event) {
+	if event.Type != EventLevelCompleted {
+		return
+	}
+	session, exists := e.GetSession(event.SessionID)
+	if !exists || session.RaceName == "" {
+		return
+	}
+	race, exists := e.GetRace(session.RaceName)
+	if !exists {
+		return
+	}
+
+	race.mu.Lock()
+	var player *RacePlayer
+	for _, p := range race.Players {
+		if p.SessionID == event.SessionID {
+			player = p
+		}
+	}
+	if player == nil || player.Left || player.Finished {
+		race.mu.Unlock()
+		return
+	}
+
+	split := event.Time.Sub(race.StartsAt)
+	player.Level = event.Level + 1
+	player.Splits = append(player.Splits, split)
+	player.Finished = player.Level >= len(e.Levels)
+
+	message := fmt.Sprintf("%s cleared level %d (%s)", player.Name, event.Level, formatSplitTime(split))
+	if player.Finished {
+		message = fmt.Sprintf("%s finished all %d levels in %s!", player.Name, len(e.Levels), formatSplitTime(split))
+	}
+	events := []Event{{
+		Type:      EventRaceProgress,
+		SessionID: player.SessionID,
+		Level:     player.Level,
+		Group:     race.Name,
+		Message:   message,
+		Time:      event.Time,
+	}}
+	events = append(events, race.checkFinished(player.SessionID)...)
+	race.mu.Unlock()
+
+	log.Printf("🏁 Race %s: %s", race.Name, message)
+	e.emit(events...)
+}
+
+// checkFinished ends the race once nobody is still running, crediting
+// the session whose finish or exit ended it; callers hold race.mu
+func (r *Race) checkFinished(sessionID string) []Event {
+	for _, player := range r.Players {
+		if !player.Finished && !player.Left {
+			return nil
+		}
+	}
+	r.FinishedAt = time.Now()
+	log.Printf("🏁 Race %s finished", r.Name)
+	return []Event{{Type: EventRaceFinished, SessionID: sessionID, Group: r.Name, Time: r.FinishedAt}}
+}
+
+// raceStatus renders the `race` command output
+func (e *GameEngine) raceStatus(session *Session) commandResult {
+	if session.RaceName == "" {
+		return errorResult("race: not in a race (join with /ws?race=<name>&name=<you>&size=<players>)", 1)
+	}
+	race, exists := e.GetRace(session.RaceName)
+	if !exists {
+		return errorResult("race: race not found", 1)
+	}
+
+	race.mu.Lock()
+	defer race.mu.Unlock()
+	return stdoutResult(race.table(len(e.Levels)))
+}
+
+// standings orders players: finishers by time, then by levels cleared
+// and who got there first; callers hold r.mu
+func (r *Race) standings() []*RacePlayer {
+	players := append([]*RacePlayer(nil), r.Players...)
+	sort.SliceStable(players, func(i, j int) bool {
+		a, b := players[i], players[j]
+		if a.Level != b.Level {
+			return a.Level > b.Level
+		}
+		if a.Left != b.Left {
+			return !a.Left
+		}
+		return a.elapsed() < b.elapsed()
+	})
+	return players
+}
+
+// table renders the standings; callers hold r.mu
+func (r *Race) table(totalLevels int) string {
+	var table strings.Builder
+
+	state := "running"
+	switch {
+	case r.StartsAt.IsZero():
+		state = fmt.Sprintf("waiting for players %d/%d", len(r.Players), r.Size)
+	case time.Now().Before(r.StartsAt):
+		state = "starting"
+	case !r.FinishedAt.IsZero():
+		state = "finished"
+	}
+	fmt.Fprintf(&table, "🏁 Race %s (%s)\n\n", r.Name, state)
+	fmt.Fprintf(&table, "  %-3s %-16s %-7s %s\n", "#", "Player", "Levels", "Time")
+
+	for i, player := range r.standings() {
+		timing := formatSplitTime(player.elapsed())
+		if player.Left {
+			timing += " (left)"
+		}
+		fmt.Fprintf(&table, "  %-3d %-16s %2d/%-4d %s\n", i+1, player.Name, player.Level, totalLevels, timing)
+	}
+	return strings.TrimRight(table.String(), "\n")
+}
+
+// player finds a racer by name; callers hold r.mu
+func (r *Race) player(name string) *RacePlayer {
+	for _, player := range r.Players {
+		if player.Name == name {
+			return player
+		}
+	}
+	return nil
+}
+
+// formatSplitTime renders a duration as mm:ss.t
+func formatSplitTime(d time.Duration) string {
+	tenths := int(d.Round(100*time.Millisecond) / (100 * time.Millisecond))
+	return fmt.Sprintf("%02d:%02d.%d", tenths/600, tenths/10%60, tenths%10)
+}
+
+// cleanupRaces forgets races whose sessions have all expired
+func (e *GameEngine) cleanupRaces() {
+	e.Races.Range(func(key, value interface{}) bool {
+		for _, sessionID := range e.RacePlayers(value.(*Race).Name) {
+			if _, exists := e.GetSession(sessionID); exists {
+				return true
+			}
+		}
+		e.Races.Delete(key)
+		return true
+	})
+}
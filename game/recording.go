@@ -0,0 +1,119 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Keep long-lived sessions from growing without bound
+const maxRecordingFrames = 50000
+
+// RecordingFrame is one asciicast v2 event
+type RecordingFrame struct {
+	Time float64 // Seconds since the recording started
+	Type string  // "o" output, "i" input, "r" resize, "m" marker
+	Data string
+}
+
+// Recording is the timestamped terminal history of a session
+type Recording struct {
+	StartedAt time.Time
+	Cols      int
+	Rows      int
+	Frames    []RecordingFrame
+	Truncated bool
+}
+
+func newRecording(cols, rows int) *Recording {
+	return &Recording{
+		StartedAt: time.Now(),
+		Cols:      cols,
+		Rows:      rows,
+	}
+}
+
+func (r *Recording) add(frameType, data string) {
+	if len(r.Frames) >= maxRecordingFrames {
+		r.Truncated = true
+		return
+	}
+	r.Frames = append(r.Frames, RecordingFrame{
+		Time: time.Since(r.StartedAt).Seconds(),
+		Type: frameType,
+		Data: data,
+	})
+}
+
+// RecordOutput stores what the player's terminal displayed
+func (e *GameEngine) RecordOutput(sessionID, data string) {
+	e.recordFrame(sessionID, "o", toCRLF(data))
+}
+
+// RecordInput stores what the player typed
+func (e *GameEngine) RecordInput(sessionID, data string) {
+	e.recordFrame(sessionID, "i", data)
+}
+
+func (e *GameEngine) recordFrame(sessionID, frameType, data string) {
+	session, exists := e.GetSession(sessionID)
+	if !exists || data == "" {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if frameType == "i" && session.login != nil {
+		return // Passwords typed at an ssh prompt stay out of replays
+	}
+	session.Recording.add(frameType, data)
+}
+
+// GetRecording returns a copy of the session's recording
+func (e *GameEngine) GetRecording(sessionID string) (Recording, bool) {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return Recording{}, false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	recording := *session.Recording
+	recording.Frames = append([]RecordingFrame(nil), session.Recording.Frames...)
+	return recording, true
+}
+
+// Asciicast encodes the recording in asciinema's v2 .cast format:
+// a JSON header line followed by one [time, type, data] array per line
+func (r Recording) Asciicast(title string) string {
+	var cast strings.Builder
+
+	header, _ := json.Marshal(map[string]interface{}{
+		"version":   2,
+		"width":     r.Cols,
+		"height":    r.Rows,
+		"timestamp": r.StartedAt.Unix(),
+		"title":     title,
+		"env":       map[string]string{"TERM": "xterm-256color", "SHELL": "/bin/bash"},
+	})
+	cast.Write(header)
+	cast.WriteString("\n")
+
+	for _, frame := range r.Frames {
+		event, _ := json.Marshal([]interface{}{
+			json.Number(fmt.Sprintf("%.6f", frame.Time)),
+			frame.Type,
+			frame.Data,
+		})
+		cast.Write(event)
+		cast.WriteString("\n")
+	}
+	return cast.String()
+}
+
+// toCRLF converts bare newlines so output replays correctly in a raw terminal
+func toCRLF(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
@@ -0,0 +1,106 @@
+package game
+
+import (
+	"errors"
+	"math"
+)
+
+// Scoring curves for dynamic challenge values
+const (
+	CurveParabolic = "parabolic" // Stays high for the first few solves, like CTFd
+	CurveLinear    = "linear"
+)
+
+var ErrInvalidScoring = errors.New("scoring needs 0 <= minimum <= initial, decay >= 1 and a parabolic or linear curve")
+
+// ScoringConfig makes challenge values decay as more teams solve them.
+// Every solver's score follows the current value, so early solvers lose
+// points retroactively; only the first-blood bonus is kept for good.
+type ScoringConfig struct {
+	Initial    *int   `json:"initial"`     // Value before anyone solves it
+	Minimum    *int   `json:"minimum"`     // Value it never drops below
+	Decay      *int   `json:"decay"`       // Solves until the minimum is reached
+	Curve      string `json:"curve"`       // CurveParabolic (default) or CurveLinear
+	FirstBlood int    `json:"first_blood"` // Bonus for the first team to solve it
+}
+
+// withDefaults fills in absent fields with CTFd-like values; an absent
+// minimum never exceeds the initial value
+func (sc ScoringConfig) withDefaults() ScoringConfig {
+	if sc.Initial == nil {
+		sc.Initial = intPointer(500)
+	}
+	if sc.Minimum == nil {
+		sc.Minimum = intPointer(min(100, *sc.Initial))
+	}
+	if sc.Decay == nil {
+		sc.Decay = intPointer(10)
+	}
+	if sc.Curve == "" {
+		sc.Curve = CurveParabolic
+	}
+	return sc
+}
+
+func intPointer(n int) *int {
+	return &n
+}
+
+// validate checks a config returned by withDefaults
+func (sc ScoringConfig) validate() error {
+	if *sc.Minimum < 0 || *sc.Minimum > *sc.Initial || *sc.Decay < 1 || sc.FirstBlood < 0 {
+		return ErrInvalidScoring
+	}
+	if sc.Curve != CurveParabolic && sc.Curve != CurveLinear {
+		return ErrInvalidScoring
+	}
+	return nil
+}
+
+// value is what a challenge is worth once solved by solves teams. The
+// first solve doesn't count toward the decay.
+func (sc ScoringConfig) value(solves int) int {
+	initial, minimum := *sc.Initial, *sc.Minimum
+	n := float64(solves - 1)
+	if n <= 0 {
+		return initial
+	}
+
+	span := float64(initial - minimum)
+	decay := float64(*sc.Decay)
+	var value float64
+	switch sc.Curve {
+	case CurveLinear:
+		value = float64(initial) - span*n/decay
+	default:
+		value = float64(initial) - span*(n*n)/(decay*decay)
+	}
+	return int(math.Max(math.Ceil(value), float64(minimum)))
+}
+
+// rescore recomputes every challenge's value and every team's score from
+// scratch, so a decayed value applies to all its solvers; callers hold ev.mu
+func (ev *CTFEvent) rescore() {
+	scoring := ev.Config.Scoring
+	bonus := 0
+	if scoring != nil {
+		bonus = scoring.FirstBlood
+		for _, challenge := range ev.Challenges {
+			challenge.Points = scoring.value(challenge.Solves)
+		}
+	}
+
+	for _, team := range ev.teams {
+		team.Score = 0
+		for id := range team.Solves {
+			challenge := ev.challenge(id)
+			if challenge == nil {
+				continue
+			}
+			team.Score += challenge.Points
+			if challenge.FirstBlood == team.Name {
+				team.Score += bonus
+			}
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package game
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScoringValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ScoringConfig
+		solves []int // Value after 0, 1, 2... solves
+	}{
+		{"defaults", ScoringConfig{}, []int{500, 500, 496, 484, 464, 436, 400, 356, 304, 244, 176, 100, 100}},
+		{"parabolic", ScoringConfig{Initial: intPointer(500), Minimum: intPointer(100), Decay: intPointer(2)}, []int{500, 500, 400, 100, 100}},
+		{"linear", ScoringConfig{Initial: intPointer(500), Minimum: intPointer(100), Decay: intPointer(2), Curve: CurveLinear}, []int{500, 500, 300, 100, 100}},
+		{"rounds up", ScoringConfig{Initial: intPointer(100), Minimum: intPointer(0), Decay: intPointer(3), Curve: CurveLinear}, []int{100, 100, 67, 34, 0, 0}},
+		{"fixed", ScoringConfig{Initial: intPointer(50), Minimum: intPointer(50)}, []int{50, 50, 50}},
+		{"small initial", ScoringConfig{Initial: intPointer(40), Decay: intPointer(1)}, []int{40, 40, 40}},
+	}
+	for _, tt := range tests {
+		config := tt.config.withDefaults()
+		if err := config.validate(); err != nil {
+			t.Errorf("%s: validate() = %v", tt.name, err)
+			continue
+		}
+		for solves, want := range tt.solves {
+			if got := config.value(solves); got != want {
+				t.Errorf("%s: value(%d) = %d, want %d", tt.name, solves, got, want)
+			}
+		}
+	}
+}
+
+func TestScoringValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ScoringConfig
+		valid  bool
+	}{
+		{"defaults", ScoringConfig{}, true},
+		{"minimum zero", ScoringConfig{Minimum: intPointer(0)}, true},
+		{"negative minimum", ScoringConfig{Minimum: intPointer(-1)}, false},
+		{"minimum above initial", ScoringConfig{Initial: intPointer(100), Minimum: intPointer(200)}, false},
+		{"zero decay", ScoringConfig{Decay: intPointer(0)}, false},
+		{"negative first blood", ScoringConfig{FirstBlood: -10}, false},
+		{"unknown curve", ScoringConfig{Curve: "exponential"}, false},
+	}
+	for _, tt := range tests {
+		err := tt.config.withDefaults().validate()
+		if tt.valid && err != nil || !tt.valid && !errors.Is(err, ErrInvalidScoring) {
+			t.Errorf("%s: validate() = %v, want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+// TestCTFScoring plays a challenge with three teams: every solve lowers
+// its value for all solvers, and only the first blood bonus stays
+func TestCTFScoring(t *testing.T) {
+	e := newTestCTF(t, CTFConfig{
+		Teams:   []string{"red", "blue", "green"},
+		Scoring: &ScoringConfig{Initial: intPointer(500), Minimum: intPointer(100), Decay: intPointer(2), FirstBlood: 50},
+	})
+
+	steps := []struct {
+		team, command, stdout, stderr string
+	}{
+		{"red", "submit test{zero}", "🚩 Correct! test/0 is now worth 500 points. red has 550 points.\n🩸 First blood! red was the first to solve test/0 (+50 bonus)", ""},
+		{"red", "submit test{zero}", "", "submit: red already solved test/0"},
+		{"blue", "submit test{one}", "", "submit: incorrect flag for test/0"},
+		{"blue", "cat flag", "test{zero}", ""},
+		{"blue", "submit test{zero}", "🚩 Correct! test/0 is now worth 400 points. blue has 400 points.", ""},
+		{"green", "challenge 1", "🚩 test/1: One\n", ""},
+		{"green", "submit test{one}", "🚩 Correct! test/1 is now worth 500 points. green has 550 points.\n🩸 First blood! green was the first to solve test/1 (+50 bonus)", ""},
+		{"green", "challenge test/0", "🚩 test/0: Zero\n", ""},
+		{"green", "submit test{zero}", "🚩 Correct! test/0 is now worth 100 points. green has 650 points.", ""},
+	}
+	for _, step := range steps {
+		r := e.ExecuteTeamCommand(step.team, "player", step.command)
+		if r.Stdout != step.stdout || r.Stderr != step.stderr {
+			t.Errorf("%s: %s\n got stdout %q stderr %q\nwant stdout %q stderr %q",
+				step.team, step.command, r.Stdout, r.Stderr, step.stdout, step.stderr)
+		}
+	}
+
+	want := []ScoreboardEntry{{Rank: 1, Team: "green", Score: 650, Solves: 2}, {Rank: 2, Team: "red", Score: 150, Solves: 1}, {Rank: 3, Team: "blue", Score: 100, Solves: 1}}
+	board := e.Scoreboard()
+	if len(board) != len(want) {
+		t.Fatalf("Scoreboard() has %d entries, want %d", len(board), len(want))
+	}
+	for i, entry := range board {
+		if entry.Rank != want[i].Rank || entry.Team != want[i].Team || entry.Score != want[i].Score || entry.Solves != want[i].Solves {
+			t.Errorf("Scoreboard()[%d] = %d %s %d points %d solves, want %d %s %d points %d solves", i,
+				entry.Rank, entry.Team, entry.Score, entry.Solves, want[i].Rank, want[i].Team, want[i].Score, want[i].Solves)
+		}
+	}
+}
+
+// TestCTFSession checks that status follows the selected challenge and
+// that nothing but the scoreboard runs once the event has ended
+func TestCTFSession(t *testing.T) {
+	e := newTestCTF(t, CTFConfig{Teams: []string{"red"}})
+	e.ExecuteTeamCommand("red", "player", "challenge 1")
+	status := e.ExecuteTeamCommand("red", "player", "status").Stdout
+	for _, want := range []string{"Challenge: test/1\n", "Challenge Title: One\n", "Progress: 0/2 challenges solved"} {
+		if !strings.Contains(status, want) {
+			t.Errorf("status = %q, want it to contain %q", status, want)
+		}
+	}
+
+	e = newTestCTF(t, CTFConfig{Teams: []string{"red"}, EndsAt: time.Now().Add(-time.Minute)})
+	team, _ := e.GetTeam("red")
+	ended := "event: CodeHeist CTF has ended, see `scoreboard` for the results"
+	for _, r := range []*CommandResponse{
+		e.ExecuteTeamCommand("red", "player", "submit test{zero}"),
+		e.ExecuteCommand(team.SessionID, "submit test{zero}"),
+	} {
+		if r.Stderr != ended || r.ExitCode != 1 {
+			t.Errorf("submit after the event got stderr %q exit %d, want %q exit 1", r.Stderr, r.ExitCode, ended)
+		}
+	}
+	if r := e.ExecuteCommand(team.SessionID, "scoreboard"); r.ExitCode != 0 {
+		t.Errorf("scoreboard after the event got stderr %q exit %d", r.Stderr, r.ExitCode)
+	}
+}
+
+// newTestCTF configures an event over a two-level "test" pack and joins
+// each team with one player
+func newTestCTF(t *testing.T, config CTFConfig) *GameEngine {
+	t.Helper()
+	e := NewEngine()
+	e.Packs["test"] = &LevelPack{Name: "test", Levels: []*Level{
+		{ID: 0, Title: "Zero", Filesystem: map[string]interface{}{"flag": "test{zero}"}, Solution: "test{zero}"},
+		{ID: 1, Title: "One", Filesystem: map[string]interface{}{"flag": "test{one}"}, Solution: "test{one}"},
+	}}
+	config.Packs = []string{"test"}
+	if err := e.ConfigureCTF(config); err != nil {
+		t.Fatalf("ConfigureCTF: %v", err)
+	}
+	for _, name := range config.Teams {
+		if _, _, err := e.JoinTeam(name, "player", "127.0.0.1"); err != nil {
+			t.Fatalf("JoinTeam(%s): %v", name, err)
+		}
+	}
+	return e
+}
@@ -0,0 +1,87 @@
+package game
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"time"
+)
+
+// SessionInfo is a point-in-time view of a session's level state
+type SessionInfo struct {
+	SessionID       string    `json:"session_id"`
+	User            string    `json:"user"`
+	Level           int       `json:"level"`
+	Title           string    `json:"title,omitempty"`
+	Description     string    `json:"description,omitempty"`
+	Welcome         string    `json:"welcome"`
+	LevelsCompleted int       `json:"levels_completed"`
+	TotalLevels     int       `json:"total_levels"`
+	GameCompleted   bool      `json:"game_completed"`
+	LastExitCode    int       `json:"last_exit_code"`
+	CreatedAt       time.Time `json:"created_at"`
+	LastActivity    time.Time `json:"last_activity"`
+	Player          string    `json:"player,omitempty"` // Signed-in player name
+	Team            string    `json:"team,omitempty"`   // Set for shared team sessions
+	Achievements    []string  `json:"achievements"`     // Unlocked achievement IDs
+}
+
+func newSessionToken() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// AuthenticateSession returns the session if the token matches it
+func (e *GameEngine) AuthenticateSession(sessionID, token string) (*Session, bool) {
+	session, exists := e.GetSession(sessionID)
+	if !exists || token == "" {
+		return nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(session.Token)) != 1 {
+		return nil, false
+	}
+	return session, true
+}
+
+// SessionInfo snapshots the session's progress for API clients
+func (e *GameEngine) SessionInfo(sessionID string) (SessionInfo, bool) {
+	session, exists := e.GetSession(sessionID)
+	if !exists {
+		return SessionInfo{}, false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	info := SessionInfo{
+		SessionID:       session.ID,
+		User:            session.User,
+		Level:           session.CurrentLevel,
+		Welcome:         "Welcome to CodeHeist! Your mission awaits...",
+		LevelsCompleted: session.CurrentLevel,
+		TotalLevels:     len(e.Levels),
+		GameCompleted:   session.CurrentLevel >= len(e.Levels),
+		LastExitCode:    session.LastExitCode,
+		CreatedAt:       session.CreatedAt,
+		LastActivity:    session.LastActivity,
+		Team:            session.TeamName,
+	}
+
+	if level, exists := e.Levels[session.CurrentLevel]; exists {
+		info.Title = level.Title
+		info.Description = level.Description
+		info.Welcome = level.WelcomeMsg
+	}
+	player := session.player
+	player.mu.Lock()
+	defer player.mu.Unlock()
+	info.Player = player.Name
+	info.Achievements = []string{}
+	for _, achievement := range achievements {
+		if _, done := player.Achievements[achievement.ID]; done {
+			info.Achievements = append(info.Achievements, achievement.ID)
+		}
+	}
+	return info, true
+}
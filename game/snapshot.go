@@ -0,0 +1,124 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// Named snapshots a player can keep per level
+const maxSnapshots = 16
+
+// vfsSnapshot is a frozen copy of a filesystem and its processes.
+// Taking one is cheap: it shares the file table, which the live
+// filesystem copies on its next write.
+type vfsSnapshot struct {
+	files map[string]*fileNode
+	procs *processTable
+	hosts map[string]*vfsSnapshot // Every machine of a multi-host level, by hostname
+}
+
+// Snapshot freezes the filesystem's current state, or on a level with
+// several machines all of theirs
+func (vfs *VirtualFileSystem) Snapshot() *vfsSnapshot {
+	if vfs.network == nil {
+		return vfs.freeze()
+	}
+	snapshot := &vfsSnapshot{hosts: make(map[string]*vfsSnapshot)}
+	for name, machine := range vfs.network {
+		snapshot.hosts[name] = machine.freeze()
+	}
+	return snapshot
+}
+
+func (vfs *VirtualFileSystem) freeze() *vfsSnapshot {
+	vfs.shared = true
+	return &vfsSnapshot{files: vfs.files, procs: vfs.procs.clone()}
+}
+
+// Restore returns the filesystem, or every machine, to a snapshot's state
+func (vfs *VirtualFileSystem) Restore(snapshot *vfsSnapshot) {
+	if snapshot.hosts == nil {
+		vfs.thaw(snapshot)
+		return
+	}
+	for name, machine := range vfs.network {
+		if frozen := snapshot.hosts[name]; frozen != nil {
+			machine.thaw(frozen)
+		}
+	}
+}
+
+func (vfs *VirtualFileSystem) thaw(snapshot *vfsSnapshot) {
+	vfs.files = snapshot.files
+	vfs.procs = snapshot.procs.clone()
+	vfs.shared = true
+	vfs.changes++
+	if _, node, err := vfs.stat(vfs.cwd); err != nil || !node.isDir() {
+		vfs.cwd = vfs.home
+	}
+}
+
+// resetLevel restores the pristine state the level started with
+func (e *GameEngine) resetLevel(session *Session) commandResult {
+	if session.pristine == nil {
+		return errorResult("reset: nothing to reset", 1)
+	}
+	session.leaveAll()
+	session.VirtualFS.Restore(session.pristine)
+	session.VirtualFS.cwd = session.VirtualFS.home
+	session.Pager = nil
+	log.Printf("🔄 Session %s reset its level", session.ID)
+	return stdoutResult("🔄 Level restored to its starting state")
+}
+
+// snapshotCommand handles `snapshot [list|save|restore|delete] <name>`
+func (e *GameEngine) snapshotCommand(session *Session, args []string) commandResult {
+	if len(args) == 0 || args[0] == "list" {
+		if len(session.snapshots) == 0 {
+			return stdoutResult("No snapshots. Use 'snapshot save <name>' to take one.")
+		}
+		var names []string
+		for name := range session.snapshots {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return stdoutResult(strings.Join(names, "\n"))
+	}
+
+	if len(args) < 2 {
+		return errorResult("snapshot: usage: snapshot [list|save|restore|delete] <name>", 2)
+	}
+	action, name := args[0], args[1]
+	if !teamNamePattern.MatchString(name) {
+		return errorResult("snapshot: names must be 1-32 letters, digits, '-' or '_'", 1)
+	}
+
+	switch action {
+	case "save":
+		if _, exists := session.snapshots[name]; !exists && len(session.snapshots) >= maxSnapshots {
+			return errorResult(fmt.Sprintf("snapshot: at most %d snapshots per level", maxSnapshots), 1)
+		}
+		if session.snapshots == nil {
+			session.snapshots = make(map[string]*vfsSnapshot)
+		}
+		session.snapshots[name] = session.VirtualFS.Snapshot()
+		return stdoutResult("📸 Saved snapshot " + name)
+	case "restore":
+		snapshot, exists := session.snapshots[name]
+		if !exists {
+			return errorResult("snapshot: "+name+": no such snapshot", 1)
+		}
+		session.VirtualFS.Restore(snapshot)
+		session.Pager = nil
+		return stdoutResult("📸 Restored snapshot " + name)
+	case "delete":
+		if _, exists := session.snapshots[name]; !exists {
+			return errorResult("snapshot: "+name+": no such snapshot", 1)
+		}
+		delete(session.snapshots, name)
+		return stdoutResult("🗑️ Deleted snapshot " + name)
+	}
+	return errorResult("snapshot: unknown action '"+action+"'", 2)
+}
@@ -0,0 +1,143 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Speedrun is one timed run through every level, started with
+// `speedrun start`
+type Speedrun struct {
+	StartedAt time.Time
+	Splits    []time.Duration // Time from the start to each cleared level
+	finished  bool            // Started after clearing every level, restored on stop
+}
+
+// speedrunCommand handles `speedrun [start|stop]`
+func (e *GameEngine) speedrunCommand(s *Session, args []string) *CommandResponse {
+	action := ""
+	if len(args) > 0 {
+		action = args[0]
+	}
+
+	switch action {
+	case "":
+		return &CommandResponse{Stdout: e.speedrunSplits(s)}
+	case "start":
+		if s.TeamName != "" || s.RaceName != "" {
+			return &CommandResponse{Stderr: "speedrun: not available in team or race sessions", ExitCode: 1}
+		}
+		// A run restarts from level 0, so only start one where no
+		// progress is lost: a restart, level 0 or a finished game
+		finished := s.CurrentLevel >= len(e.Levels)
+		if s.Speedrun != nil {
+			finished = s.Speedrun.finished
+		} else if s.CurrentLevel != 0 && !finished {
+			return &CommandResponse{Stderr: fmt.Sprintf("speedrun: you're on level %d, finish the game before starting a run", s.CurrentLevel), ExitCode: 1}
+		}
+		s.CurrentLevel = 0
+		s.failedLevel = nil
+		s.Pager = nil
+		e.initializeLevelFilesystem(s, 0)
+		s.Speedrun = &Speedrun{StartedAt: time.Now(), finished: finished}
+		s.Recording.add("m", "Speedrun started")
+		log.Printf("⏱️ Session %s started a speedrun", s.ID)
+		return &CommandResponse{Stdout: "⏱️ Speedrun started! The clock is running from level 0.\n" +
+			e.Levels[0].WelcomeMsg}
+	case "stop":
+		if s.Speedrun == nil {
+			return &CommandResponse{Stderr: "speedrun: no run in progress", ExitCode: 1}
+		}
+		finished := s.Speedrun.finished
+		s.Speedrun = nil
+		if finished {
+			s.CurrentLevel = len(e.Levels)
+			s.failedLevel = nil
+			s.Pager = nil
+			s.leaveAll()
+			e.stopLevelTimer(s)
+			return &CommandResponse{Stdout: "⏱️ Speedrun abandoned, back to your finished game."}
+		}
+		return &CommandResponse{Stdout: "⏱️ Speedrun abandoned."}
+	}
+	return &CommandResponse{Stderr: "speedrun: usage: speedrun [start|stop]", ExitCode: 1}
+}
+
+// recordSplit adds the split for a cleared level to the running speedrun
+// and returns the line to show the player; callers hold s.mu
+func (e *GameEngine) recordSplit(s *Session, level int) string {
+	run := s.Speedrun
+	if run == nil {
+		return ""
+	}
+
+	player := s.player
+	player.mu.Lock()
+	defer player.mu.Unlock()
+
+	split := time.Since(run.StartedAt)
+	run.Splits = append(run.Splits, split)
+	line := fmt.Sprintf("⏱️ Level %d split: %s%s", level, formatSplitTime(split), splitDelta(player.BestSplits, level, split))
+
+	if len(run.Splits) < len(e.Levels) {
+		return line
+	}
+
+	// Run complete
+	s.Speedrun = nil
+	player.Runs++
+	if len(player.BestSplits) == 0 || split < player.BestSplits[len(player.BestSplits)-1] {
+		player.BestSplits = run.Splits
+		log.Printf("⏱️ Session %s set a personal best: %s", s.ID, formatSplitTime(split))
+		return line + fmt.Sprintf("\n🏁 Run finished in %s - new personal best!", formatSplitTime(split))
+	}
+	return line + fmt.Sprintf("\n🏁 Run finished in %s (personal best %s)",
+		formatSplitTime(split), formatSplitTime(player.BestSplits[len(player.BestSplits)-1]))
+}
+
+// speedrunSplits renders the `speedrun` command output: the current run
+// against the personal best
+func (e *GameEngine) speedrunSplits(s *Session) string {
+	player := s.player
+	player.mu.Lock()
+	defer player.mu.Unlock()
+
+	if s.Speedrun == nil && len(player.BestSplits) == 0 {
+		return "No speedruns yet. Type 'speedrun start' to begin a run from level 0."
+	}
+
+	var table strings.Builder
+	if s.Speedrun != nil {
+		fmt.Fprintf(&table, "⏱️ Run in progress: %s\n\n", formatSplitTime(time.Since(s.Speedrun.StartedAt)))
+	} else {
+		fmt.Fprintf(&table, "⏱️ %d completed runs\n\n", player.Runs)
+	}
+	fmt.Fprintf(&table, "  %-6s %-10s %-10s %s\n", "Level", "Split", "Delta", "Best")
+
+	for level := 0; level < len(e.Levels); level++ {
+		split, delta, best := "-", "", "-"
+		if s.Speedrun != nil && level < len(s.Speedrun.Splits) {
+			split = formatSplitTime(s.Speedrun.Splits[level])
+			delta = strings.Trim(splitDelta(player.BestSplits, level, s.Speedrun.Splits[level]), " ()")
+		}
+		if level < len(player.BestSplits) {
+			best = formatSplitTime(player.BestSplits[level])
+		}
+		fmt.Fprintf(&table, "  %-6d %-10s %-10s %s\n", level, split, delta, best)
+	}
+	return strings.TrimRight(table.String(), "\n")
+}
+
+// splitDelta compares a split with the personal best at the same level
+func splitDelta(best []time.Duration, level int, split time.Duration) string {
+	if level >= len(best) {
+		return ""
+	}
+	delta := split - best[level]
+	if delta < 0 {
+		return " (-" + formatSplitTime(-delta) + ")"
+	}
+	return " (+" + formatSplitTime(delta) + ")"
+}
@@ -0,0 +1,187 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Points awarded to a team for each level it clears
+const teamLevelPoints = 100
+
+var teamNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+var (
+	ErrInvalidTeamName   = errors.New("team names must be 1-32 letters, digits, '-' or '_'")
+	ErrInvalidMemberName = errors.New("member names must be 1-32 letters, digits, '-' or '_'")
+)
+
+// Team is a group of players sharing one session: one filesystem,
+// one level progression and one score
+type Team struct {
+	Name      string
+	SessionID string
+	Members   map[string]*TeamMember
+	Score     int
+	CreatedAt time.Time
+	mu        sync.Mutex
+}
+
+type TeamMember struct {
+	Name         string
+	Connected    bool
+	Commands     int
+	LevelsSolved int
+	JoinedAt     time.Time
+}
+
+// JoinTeam adds a member to a team, creating the team and its shared
+// session on first join. Taken names get a numeric suffix.
+func (e *GameEngine) JoinTeam(teamName, memberName, ip string) (*Team, string, error) {
+	if !teamNamePattern.MatchString(teamName) {
+		return nil, "", ErrInvalidTeamName
+	}
+	if memberName == "" {
+		memberName = "player"
+	}
+	if !teamNamePattern.MatchString(memberName) {
+		return nil, "", ErrInvalidMemberName
+	}
+
+	// During a CTF event only registered teams may play
+	if e.currentCTF() != nil {
+		if err := e.RegisterCTFTeam(teamName, false); err != nil {
+			return nil, "", err
+		}
+	}
+
+	key := strings.ToLower(teamName)
+	value, exists := e.Teams.Load(key)
+	if !exists {
+		// Publish the team only once its session exists, so a concurrent
+		// joiner never sees an empty SessionID
+		session := e.CreateSession(ip)
+		session.TeamName = teamName
+		var loaded bool
+		value, loaded = e.Teams.LoadOrStore(key, &Team{
+			Name:      teamName,
+			SessionID: session.ID,
+			Members:   make(map[string]*TeamMember),
+			CreatedAt: time.Now(),
+		})
+		if loaded {
+			// Another member created the team first
+			e.EndSession(session.ID)
+		} else {
+			log.Printf("👥 Team %s created with session %s", teamName, session.ID)
+		}
+	}
+	team := value.(*Team)
+
+	team.mu.Lock()
+	defer team.mu.Unlock()
+
+	name := memberName
+	for i := 2; ; i++ {
+		member, taken := team.Members[name]
+		if !taken {
+			team.Members[name] = &TeamMember{Name: name, Connected: true, JoinedAt: time.Now()}
+			break
+		}
+		if !member.Connected {
+			member.Connected = true // Rejoining after a disconnect
+			break
+		}
+		name = fmt.Sprintf("%s%d", memberName, i)
+	}
+
+	log.Printf("👥 %s joined team %s", name, team.Name)
+	return team, name, nil
+}
+
+// LeaveTeam marks a member as disconnected; their contributions stay
+func (e *GameEngine) LeaveTeam(teamName, memberName string) {
+	team, exists := e.GetTeam(teamName)
+	if !exists {
+		return
+	}
+
+	team.mu.Lock()
+	defer team.mu.Unlock()
+	if member, exists := team.Members[memberName]; exists {
+		member.Connected = false
+	}
+}
+
+func (e *GameEngine) GetTeam(teamName string) (*Team, bool) {
+	value, exists := e.Teams.Load(strings.ToLower(teamName))
+	if !exists {
+		return nil, false
+	}
+	return value.(*Team), true
+}
+
+// ExecuteTeamCommand runs a command in the team's shared session and
+// credits the member who ran it
+func (e *GameEngine) ExecuteTeamCommand(teamName, memberName, command string) *CommandResponse {
+	team, exists := e.GetTeam(teamName)
+	if !exists {
+		return &CommandResponse{Stderr: "Team not found", ExitCode: 1}
+	}
+
+	response := e.ExecuteCommand(team.SessionID, command)
+
+	team.mu.Lock()
+	defer team.mu.Unlock()
+
+	member, exists := team.Members[memberName]
+	if !exists {
+		return response
+	}
+	member.Commands++
+	if response.LevelCompleted {
+		member.LevelsSolved++
+		team.Score += teamLevelPoints
+		log.Printf("👥 %s solved level %d for team %s", memberName, response.NewLevel-1, team.Name)
+	}
+	return response
+}
+
+// teamStatus renders the `team` command output
+func (e *GameEngine) teamStatus(session *Session) commandResult {
+	if session.TeamName == "" {
+		return errorResult("team: not playing in a team (join with /ws?team=<name>&name=<you>)", 1)
+	}
+	team, exists := e.GetTeam(session.TeamName)
+	if !exists {
+		return errorResult("team: team not found", 1)
+	}
+
+	team.mu.Lock()
+	defer team.mu.Unlock()
+
+	var names []string
+	for name := range team.Members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var status strings.Builder
+	fmt.Fprintf(&status, "Team: %s\nScore: %d\nLevel: %d/%d\n\nMembers:\n",
+		team.Name, team.Score, session.CurrentLevel, len(e.Levels))
+	for _, name := range names {
+		member := team.Members[name]
+		state := "offline"
+		if member.Connected {
+			state = "online"
+		}
+		fmt.Fprintf(&status, "  %-16s %-8s %3d commands, %d levels solved\n",
+			member.Name, state, member.Commands, member.LevelsSolved)
+	}
+	return stdoutResult(strings.TrimRight(status.String(), "\n"))
+}
@@ -0,0 +1,117 @@
+package game
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Default xterm dimensions until the client reports its real size
+const (
+	DefaultTerminalCols = 80
+	DefaultTerminalRows = 24
+)
+
+// ResizeTerminal stores the client's terminal dimensions on the session
+func (e *GameEngine) ResizeTerminal(sessionID string, cols, rows int) bool {
+	session, exists := e.GetSession(sessionID)
+	if !exists || cols <= 0 || rows <= 0 {
+		return false
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	session.Cols = cols
+	session.Rows = rows
+	if len(session.Recording.Frames) == 0 {
+		// Nothing recorded yet, so the cast header can use the real size
+		session.Recording.Cols = cols
+		session.Recording.Rows = rows
+	} else {
+		session.Recording.add("r", fmt.Sprintf("%dx%d", cols, rows))
+	}
+	if session.Pager != nil {
+		session.Pager.resize(cols, rows)
+	}
+	return true
+}
+
+// formatColumns lays out names top-to-bottom in as many columns as fit
+// the given width, using the same algorithm as GNU ls -C
+func formatColumns(names []string, width int) string {
+	if len(names) == 0 {
+		return ""
+	}
+	if width <= 0 {
+		width = DefaultTerminalCols
+	}
+
+	// Find the largest column count whose lines are shorter than the width
+	var rows int
+	var colWidths []int
+	for cols := len(names); cols >= 1; cols-- {
+		rows = (len(names) + cols - 1) / cols
+		used := (len(names) + rows - 1) / rows
+		colWidths = make([]int, used)
+
+		lineLen := 0
+		for i, name := range names {
+			col := i / rows
+			w := utf8.RuneCountInString(name)
+			if col != used-1 {
+				w += 2 // column separator
+			}
+			if w > colWidths[col] {
+				lineLen += w - colWidths[col]
+				colWidths[col] = w
+			}
+		}
+
+		if lineLen < width || cols == 1 {
+			break
+		}
+	}
+
+	var result strings.Builder
+	for row := 0; row < rows; row++ {
+		var line strings.Builder
+		for col := 0; col < len(colWidths); col++ {
+			i := col*rows + row
+			if i >= len(names) {
+				break
+			}
+			line.WriteString(names[i])
+			if col*rows+row+rows < len(names) {
+				line.WriteString(strings.Repeat(" ", colWidths[col]-utf8.RuneCountInString(names[i])))
+			}
+		}
+		result.WriteString(strings.TrimRight(line.String(), " "))
+		if row < rows-1 {
+			result.WriteString("\n")
+		}
+	}
+	return result.String()
+}
+
+// wrapLines splits text into screen lines no wider than width
+func wrapLines(text string, width int) []string {
+	if width <= 0 {
+		width = DefaultTerminalCols
+	}
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		runes := []rune(line)
+		if len(runes) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		for len(runes) > width {
+			lines = append(lines, string(runes[:width]))
+			runes = runes[width:]
+		}
+		lines = append(lines, string(runes))
+	}
+	return lines
+}
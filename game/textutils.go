@@ -0,0 +1,731 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// textInput is one file, or stdin, read by a text filter
+type textInput struct {
+	name    string // As typed, "" for stdin
+	content string
+}
+
+// readInputs reads each operand, or stdin when there are none. A "-"
+// operand is stdin inside a pipeline and a file named "-" otherwise, so
+// the dash-file level still needs ./- when piping. Unreadable files are
+// reported with openError, which formats the name and the reason.
+func readInputs(vfs *VirtualFileSystem, operands []string, stdin *string, openError string, result *commandResult) []textInput {
+	if len(operands) == 0 {
+		if stdin == nil {
+			return []textInput{{}}
+		}
+		return []textInput{{content: *stdin}}
+	}
+
+	var inputs []textInput
+	for _, name := range operands {
+		if name == "-" && stdin != nil {
+			inputs = append(inputs, textInput{content: *stdin})
+			continue
+		}
+		content, err := vfs.ReadFile(name)
+		if err != nil {
+			result.fail(fmt.Sprintf(openError, name, errorText(err)), 1)
+			continue
+		}
+		inputs = append(inputs, textInput{name: name, content: string(content)})
+	}
+	return inputs
+}
+
+// terminated treats a stream as newline-terminated text. Files and
+// command output in this shell leave off the final newline, so a filter
+// sees "a\nb" as the two lines a real file would hold.
+func terminated(content string) string {
+	if content == "" || strings.HasSuffix(content, "\n") {
+		return content
+	}
+	return content + "\n"
+}
+
+// lineWidth is the display width of a line, with tabs moving to the
+// next multiple of 8 columns as wc -L counts them
+func lineWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		if r == '\t' {
+			width += 8 - width%8
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// textLines splits a stream into its lines
+func textLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// inputHeader is the "==> name <==" line head and tail print between files
+func inputHeader(input textInput) string {
+	if input.name == "" {
+		return "==> standard input <=="
+	}
+	return "==> " + input.name + " <=="
+}
+
+// headTailCount parses the -n/-c value of head and tail. A leading "-"
+// (head) or "+" (tail) flips where the count is taken from.
+func headTailCount(command, value, unit string) (int, bool, *commandResult) {
+	flipped := strings.HasPrefix(value, "-") && command == "head" ||
+		strings.HasPrefix(value, "+") && command == "tail"
+	count, err := strconv.Atoi(strings.TrimLeft(value, "+-"))
+	if err != nil || count < 0 {
+		usage := errorResult(fmt.Sprintf("%s: invalid number of %s: '%s'", command, unit, value), 1)
+		return 0, false, &usage
+	}
+	return count, flipped, nil
+}
+
+// headTailArgs rewrites the obsolete -5 form into -n 5
+func headTailArgs(args []string) []string {
+	var rewritten []string
+	for i, arg := range args {
+		valueOfOption := i > 0 && (args[i-1] == "-n" || args[i-1] == "-c")
+		if !valueOfOption && len(arg) > 1 && arg[0] == '-' && strings.Trim(arg[1:], "0123456789") == "" {
+			rewritten = append(rewritten, "-n", arg[1:])
+			continue
+		}
+		rewritten = append(rewritten, arg)
+	}
+	return rewritten
+}
+
+// headTailCommand implements head and tail with -n, -c, -q and -v
+func headTailCommand(vfs *VirtualFileSystem, command string, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions(command, headTailArgs(args), "qv", "nc",
+		map[string]rune{"lines": 'n', "bytes": 'c', "quiet": 'q', "silent": 'q', "verbose": 'v'})
+	if usage != nil {
+		return *usage
+	}
+
+	count, flipped, bytes := 10, false, flags['c']
+	if value, set := values['n']; set && !bytes {
+		if count, flipped, usage = headTailCount(command, value, "lines"); usage != nil {
+			return *usage
+		}
+	}
+	if bytes {
+		if count, flipped, usage = headTailCount(command, values['c'], "bytes"); usage != nil {
+			return *usage
+		}
+	}
+
+	var result commandResult
+	inputs := readInputs(vfs, operands, stdin, command+": cannot open '%s' for reading: %s", &result)
+	headers := flags['v'] || len(operands) > 1 && !flags['q']
+
+	var sections []string
+	for _, input := range inputs {
+		var selected string
+		if bytes {
+			selected = strings.TrimSuffix(string(sliceCount([]byte(terminated(input.content)), count, command == "head", flipped)), "\n")
+		} else {
+			selected = strings.Join(sliceCount(textLines(input.content), count, command == "head", flipped), "\n")
+		}
+		if headers {
+			selected = inputHeader(input) + "\n" + selected
+		}
+		sections = append(sections, selected)
+	}
+	result.stdout = strings.Join(sections, "\n\n")
+	return result
+}
+
+// sliceCount picks what head or tail keep: the first count items (head),
+// all but the last count (head -n -N), the last count (tail) or from item
+// count onwards (tail -n +N)
+func sliceCount[T any](items []T, count int, head, flipped bool) []T {
+	n := len(items)
+	switch {
+	case head && !flipped:
+		return items[:min(count, n)]
+	case head:
+		return items[:max(n-count, 0)]
+	case !flipped:
+		return items[max(n-count, 0):]
+	}
+	return items[min(max(count-1, 0), n):]
+}
+
+// wcCommand implements wc with -l, -w, -c, -m and -L
+func wcCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, operands, usage := parseFlags("wc", args, "lwcmL", map[string]rune{
+		"lines": 'l', "words": 'w', "bytes": 'c', "chars": 'm', "max-line-length": 'L'})
+	if usage != nil {
+		return *usage
+	}
+	// Counts print in this order whatever order the flags came in
+	columns := []rune{'l', 'w', 'm', 'c', 'L'}
+	if !flags['l'] && !flags['w'] && !flags['c'] && !flags['m'] && !flags['L'] {
+		flags['l'], flags['w'], flags['c'] = true, true, true
+	}
+
+	var result commandResult
+	inputs := readInputs(vfs, operands, stdin, "wc: %s: %s", &result)
+
+	counts := make([]map[rune]int, len(inputs))
+	total := map[rune]int{}
+	fromStdin := false
+	for i, input := range inputs {
+		text := terminated(input.content)
+		counts[i] = map[rune]int{
+			'l': strings.Count(text, "\n"),
+			'w': len(strings.Fields(text)),
+			'm': utf8.RuneCountInString(text),
+			'c': len(text),
+		}
+		for _, line := range textLines(text) {
+			counts[i]['L'] = max(counts[i]['L'], lineWidth(line))
+		}
+		for column, count := range counts[i] {
+			if column == 'L' {
+				total[column] = max(total[column], count)
+			} else {
+				total[column] += count
+			}
+		}
+		fromStdin = fromStdin || input.name == ""
+	}
+
+	// Like GNU wc, numbers line up to the width of the total byte count,
+	// at least 7 wide for stdin, unless there's a single number to print
+	width := len(strconv.Itoa(total['c']))
+	if fromStdin {
+		width = max(width, 7)
+	}
+	selected := 0
+	for _, column := range columns {
+		if flags[column] {
+			selected++
+		}
+	}
+	if selected == 1 && len(inputs) == 1 {
+		width = 1
+	}
+
+	format := func(count map[rune]int, name string) string {
+		var fields []string
+		for _, column := range columns {
+			if flags[column] {
+				fields = append(fields, fmt.Sprintf("%*d", width, count[column]))
+			}
+		}
+		if name != "" {
+			fields = append(fields, name)
+		}
+		return strings.Join(fields, " ")
+	}
+
+	var lines []string
+	for i, input := range inputs {
+		lines = append(lines, format(counts[i], input.name))
+	}
+	if len(inputs) > 1 {
+		lines = append(lines, format(total, "total"))
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// sortKey is the part of a line sort -k compares, fields start..end
+// (1-based, end 0 for the rest of the line)
+type sortKey struct {
+	start, end int
+}
+
+func parseSortKey(spec string) (sortKey, bool) {
+	startField, endField, hasEnd := strings.Cut(spec, ",")
+	start, err := strconv.Atoi(strings.TrimRight(startField, "bdfgimhnRrV"))
+	if err != nil || start < 1 {
+		return sortKey{}, false
+	}
+	key := sortKey{start: start}
+	if hasEnd {
+		end, err := strconv.Atoi(strings.TrimRight(endField, "bdfgimhnRrV"))
+		if err != nil || end < start {
+			return sortKey{}, false
+		}
+		key.end = end
+	}
+	return key, true
+}
+
+func (k sortKey) extract(line, separator string) string {
+	var fields []string
+	if separator != "" {
+		fields = strings.Split(line, separator)
+	} else {
+		fields = strings.Fields(line)
+	}
+	if k.start > len(fields) {
+		return ""
+	}
+	end := len(fields)
+	if k.end > 0 && k.end < end {
+		end = k.end
+	}
+	join := separator
+	if join == "" {
+		join = " "
+	}
+	return strings.Join(fields[k.start-1:end], join)
+}
+
+// leadingNumber parses the number a line starts with for sort -n;
+// lines without one sort as zero
+func leadingNumber(s string) float64 {
+	s = strings.TrimSpace(s)
+	end := 0
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] == '.' || end == 0 && s[end] == '-') {
+		end++
+	}
+	number, _ := strconv.ParseFloat(s[:end], 64)
+	return number
+}
+
+// sortCommand implements sort with -r, -n, -u, -f, -k and -t
+func sortCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions("sort", args, "rnufb", "kt", map[string]rune{
+		"reverse": 'r', "numeric-sort": 'n', "unique": 'u', "ignore-case": 'f', "key": 'k', "field-separator": 't'})
+	if usage != nil {
+		return *usage
+	}
+
+	var key *sortKey
+	if spec, set := values['k']; set {
+		parsed, ok := parseSortKey(spec)
+		if !ok {
+			return errorResult("sort: invalid number at field start: invalid count at start of '"+spec+"'", 2)
+		}
+		key = &parsed
+	}
+	separator := values['t']
+	if utf8.RuneCountInString(separator) > 1 {
+		return errorResult("sort: multi-character tab '"+separator+"'", 2)
+	}
+
+	var result commandResult
+	var lines []string
+	for _, input := range readInputs(vfs, operands, stdin, "sort: cannot read: %s: %s", &result) {
+		lines = append(lines, textLines(input.content)...)
+	}
+	if result.exitCode != 0 {
+		result.exitCode = 2
+		return result
+	}
+
+	keyOf := func(line string) string {
+		if key != nil {
+			line = key.extract(line, separator)
+		}
+		if flags['b'] {
+			line = strings.TrimLeft(line, " \t")
+		}
+		if flags['f'] {
+			line = strings.ToUpper(line)
+		}
+		return line
+	}
+	compareKeys := func(a, b string) int {
+		ka, kb := keyOf(a), keyOf(b)
+		if flags['n'] {
+			na, nb := leadingNumber(ka), leadingNumber(kb)
+			switch {
+			case na < nb:
+				return -1
+			case na > nb:
+				return 1
+			}
+			return 0
+		}
+		return strings.Compare(ka, kb)
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		order := compareKeys(lines[i], lines[j])
+		if order == 0 && !flags['u'] {
+			// Like GNU sort, equal keys fall back to comparing whole lines
+			order = strings.Compare(lines[i], lines[j])
+		}
+		if flags['r'] {
+			return order > 0
+		}
+		return order < 0
+	})
+
+	if flags['u'] {
+		var unique []string
+		for i, line := range lines {
+			if i == 0 || compareKeys(lines[i-1], line) != 0 {
+				unique = append(unique, line)
+			}
+		}
+		lines = unique
+	}
+	result.stdout = strings.Join(lines, "\n")
+	return result
+}
+
+// uniqCommand implements uniq with -c, -d, -u and -i
+func uniqCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, operands, usage := parseFlags("uniq", args, "cdui", map[string]rune{
+		"count": 'c', "repeated": 'd', "unique": 'u', "ignore-case": 'i'})
+	if usage != nil {
+		return *usage
+	}
+	if len(operands) > 1 {
+		return errorResult("uniq: writing to a file isn't supported here; use a pipe", 1)
+	}
+
+	var result commandResult
+	inputs := readInputs(vfs, operands, stdin, "uniq: %s: %s", &result)
+	if len(inputs) == 0 {
+		return result
+	}
+
+	same := func(a, b string) bool {
+		if flags['i'] {
+			return strings.EqualFold(a, b)
+		}
+		return a == b
+	}
+
+	var output []string
+	lines := textLines(inputs[0].content)
+	for i := 0; i < len(lines); {
+		j := i + 1
+		for j < len(lines) && same(lines[i], lines[j]) {
+			j++
+		}
+		count := j - i
+		if (!flags['d'] || count > 1) && (!flags['u'] || count == 1) {
+			if flags['c'] {
+				output = append(output, fmt.Sprintf("%7d %s", count, lines[i]))
+			} else {
+				output = append(output, lines[i])
+			}
+		}
+		i = j
+	}
+	result.stdout = strings.Join(output, "\n")
+	return result
+}
+
+// cutRange is one N, N-M, N- or -M entry of a cut list
+type cutRange struct {
+	from, to int // 1-based and inclusive, to 0 for open-ended
+}
+
+func parseCutList(list, unit string) ([]cutRange, *commandResult) {
+	var ranges []cutRange
+	for _, item := range strings.Split(list, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		r := cutRange{from: 1}
+		var err error
+		if from != "" {
+			r.from, err = strconv.Atoi(from)
+		}
+		if err == nil && !isRange {
+			r.to = r.from
+		} else if err == nil && to != "" {
+			r.to, err = strconv.Atoi(to)
+		}
+		if err != nil || item == "-" {
+			usage := errorResult(fmt.Sprintf("cut: invalid %s value '%s'\nTry 'cut --help' for more information.", unit, item), 1)
+			return nil, &usage
+		}
+		if r.from < 1 || isRange && to != "" && r.to < 1 {
+			usage := errorResult("cut: fields and positions are numbered from 1\nTry 'cut --help' for more information.", 1)
+			return nil, &usage
+		}
+		if r.to != 0 && r.to < r.from {
+			usage := errorResult("cut: invalid decreasing range\nTry 'cut --help' for more information.", 1)
+			return nil, &usage
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func (r cutRange) contains(position int) bool {
+	return position >= r.from && (r.to == 0 || position <= r.to)
+}
+
+// cutSelect keeps the items at the listed positions, in input order
+func cutSelect[T any](items []T, ranges []cutRange) []T {
+	var kept []T
+	for i, item := range items {
+		for _, r := range ranges {
+			if r.contains(i + 1) {
+				kept = append(kept, item)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// cutCommand implements cut with -f/-d/-s, -c and -b
+func cutCommand(vfs *VirtualFileSystem, args []string, stdin *string) commandResult {
+	flags, values, operands, usage := parseOptions("cut", args, "s", "fcbd", map[string]rune{
+		"fields": 'f', "characters": 'c', "bytes": 'b', "delimiter": 'd', "only-delimited": 's'})
+	if usage != nil {
+		return *usage
+	}
+
+	var mode rune
+	for _, flag := range "fcb" {
+		if flags[flag] {
+			if mode != 0 {
+				return errorResult("cut: only one type of list may be specified\nTry 'cut --help' for more information.", 1)
+			}
+			mode = flag
+		}
+	}
+	if mode == 0 {
+		return errorResult("cut: you must specify a list of bytes, characters, or fields\nTry 'cut --help' for more information.", 1)
+	}
+	if mode != 'f' && (flags['d'] || flags['s']) {
+		return errorResult("cut: an input delimiter may be specified only when operating on fields\nTry 'cut --help' for more information.", 1)
+	}
+	delimiter := "\t"
+	if flags['d'] {
+		delimiter = values['d']
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return errorResult("cut: the delimiter must be a single character\nTry 'cut --help' for more information.", 1)
+		}
+	}
+	unit := "field"
+	if mode != 'f' {
+		unit = "byte/character position"
+	}
+	ranges, usage := parseCutList(values[mode], unit)
+	if usage != nil {
+		return *usage
+	}
+
+	var result commandResult
+	var output []string
+	for _, input := range readInputs(vfs, operands, stdin, "cut: %s: %s", &result) {
+		for _, line := range textLines(input.content) {
+			switch mode {
+			case 'f':
+				if !strings.Contains(line, delimiter) {
+					if !flags['s'] {
+						output = append(output, line)
+					}
+					continue
+				}
+				output = append(output, strings.Join(cutSelect(strings.Split(line, delimiter), ranges), delimiter))
+			case 'c':
+				output = append(output, string(cutSelect([]rune(line), ranges)))
+			case 'b':
+				output = append(output, string(cutSelect([]byte(line), ranges)))
+			}
+		}
+	}
+	result.stdout = strings.Join(output, "\n")
+	return result
+}
+
+// Character classes tr understands inside [: :]
+var trClasses = map[string]func(rune) bool{
+	"alnum":  func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) },
+	"alpha":  unicode.IsLetter,
+	"blank":  func(r rune) bool { return r == ' ' || r == '\t' },
+	"digit":  unicode.IsDigit,
+	"lower":  unicode.IsLower,
+	"print":  func(r rune) bool { return r >= ' ' && r <= '~' },
+	"punct":  unicode.IsPunct,
+	"space":  unicode.IsSpace,
+	"upper":  unicode.IsUpper,
+	"xdigit": func(r rune) bool { return strings.ContainsRune("0123456789abcdefABCDEF", r) },
+}
+
+// expandTrSet turns a tr set such as 'a-z', '\n' or '[:upper:]' into the
+// bytes it lists, in order
+func expandTrSet(set string) ([]byte, *commandResult) {
+	// Resolve backslash escapes first, remembering which bytes were escaped
+	var chars []byte
+	var escaped []bool
+	for i := 0; i < len(set); i++ {
+		c := set[i]
+		if c != '\\' || i+1 == len(set) {
+			chars = append(chars, c)
+			escaped = append(escaped, false)
+			continue
+		}
+		i++
+		switch set[i] {
+		case 'n':
+			c = '\n'
+		case 't':
+			c = '\t'
+		case 'r':
+			c = '\r'
+		case 'a':
+			c = '\a'
+		case 'b':
+			c = '\b'
+		case 'f':
+			c = '\f'
+		case 'v':
+			c = '\v'
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := i + 1
+			for end < len(set) && end < i+3 && set[end] >= '0' && set[end] <= '7' {
+				end++
+			}
+			value, _ := strconv.ParseUint(set[i:end], 8, 8)
+			c = byte(value)
+			i = end - 1
+		default:
+			c = set[i]
+		}
+		chars = append(chars, c)
+		escaped = append(escaped, true)
+	}
+
+	var expanded []byte
+	for i := 0; i < len(chars); i++ {
+		if chars[i] == '[' && !escaped[i] && i+1 < len(chars) && chars[i+1] == ':' {
+			if end := strings.Index(string(chars[i:]), ":]"); end > 1 {
+				name := string(chars[i+2 : i+end])
+				class, known := trClasses[name]
+				if !known {
+					usage := errorResult("tr: invalid character class '"+name+"'", 1)
+					return nil, &usage
+				}
+				for c := 0; c < 256; c++ {
+					if class(rune(c)) && c < utf8.RuneSelf {
+						expanded = append(expanded, byte(c))
+					}
+				}
+				i += end + 1
+				continue
+			}
+		}
+		if i+2 < len(chars) && chars[i+1] == '-' && !escaped[i+1] {
+			from, to := chars[i], chars[i+2]
+			if from > to {
+				usage := errorResult(fmt.Sprintf("tr: range-endpoints of '%c-%c' are in reverse collating sequence order", from, to), 1)
+				return nil, &usage
+			}
+			for c := int(from); c <= int(to); c++ {
+				expanded = append(expanded, byte(c))
+			}
+			i += 2
+			continue
+		}
+		expanded = append(expanded, chars[i])
+	}
+	return expanded, nil
+}
+
+// trCommand implements tr [-c] [-d] [-s] SET1 [SET2] over stdin
+func trCommand(args []string, stdin *string) commandResult {
+	flags, operands, usage := parseFlags("tr", args, "cCds", map[string]rune{
+		"complement": 'c', "delete": 'd', "squeeze-repeats": 's'})
+	if usage != nil {
+		return *usage
+	}
+	complement, remove, squeeze := flags['c'] || flags['C'], flags['d'], flags['s']
+
+	wanted := 2
+	if remove && !squeeze || !remove && squeeze && len(operands) == 1 {
+		wanted = 1
+	}
+	switch {
+	case len(operands) == 0:
+		return missingOperand("tr", "operand")
+	case len(operands) < wanted:
+		return missingOperand("tr", "operand after '"+operands[0]+"'")
+	case len(operands) > wanted:
+		return errorResult("tr: extra operand '"+operands[wanted]+"'\nTry 'tr --help' for more information.", 1)
+	}
+
+	set1, usage := expandTrSet(operands[0])
+	if usage != nil {
+		return *usage
+	}
+	var set2 []byte
+	if len(operands) == 2 {
+		if set2, usage = expandTrSet(operands[1]); usage != nil {
+			return *usage
+		}
+	}
+
+	var in1 [256]bool
+	for _, c := range set1 {
+		in1[c] = true
+	}
+	if complement {
+		set1 = nil
+		for c := 0; c < 256; c++ {
+			if in1[c] = !in1[c]; in1[c] {
+				set1 = append(set1, byte(c))
+			}
+		}
+	}
+
+	// Translation maps each byte of SET1 to the byte at the same position
+	// in SET2, whose last byte repeats if it's shorter
+	var mapping [256]int
+	for c := range mapping {
+		mapping[c] = c
+	}
+	translate := !remove && len(set2) > 0
+	if translate {
+		for i, c := range set1 {
+			mapping[c] = int(set2[min(i, len(set2)-1)])
+		}
+	}
+
+	// Squeezing applies to the last set given
+	var squeezed [256]bool
+	squeezeSet := set1
+	if len(set2) > 0 {
+		squeezeSet = set2
+	}
+	for _, c := range squeezeSet {
+		squeezed[c] = true
+	}
+
+	input := ""
+	if stdin != nil {
+		input = string(streamBytes(*stdin))
+	}
+	var output []byte
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		if remove && in1[c] {
+			continue
+		}
+		out := byte(mapping[c])
+		if squeeze && squeezed[out] && len(output) > 0 && output[len(output)-1] == out {
+			continue
+		}
+		output = append(output, out)
+	}
+	return stdoutResult(streamString(output))
+}
@@ -0,0 +1,56 @@
+package game
+
+import "testing"
+
+// Expected output was recorded from GNU coreutils on the same files
+func TestTextTools(t *testing.T) {
+	files := map[string]interface{}{
+		"fruits.txt": "banana\napple\ncherry\napple\nbanana\nbanana\ndate",
+		"nums.txt":   "10\n9\n100\n-3\n2.5\n9",
+		"table.csv":  "name,age,city\nalice,30,paris\nbob,25,berlin\ncarol,35,rome",
+		"lines.txt":  "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve",
+		"words.txt":  "the quick  brown fox\njumps over\tthe lazy dog",
+	}
+	runCommandTests(t, files, []commandTest{
+		{name: "head default", command: "head lines.txt", stdout: "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\nten"},
+		{name: "head -n 3", command: "head -n 3 lines.txt", stdout: "one\ntwo\nthree"},
+		{name: "head -3", command: "head -3 lines.txt", stdout: "one\ntwo\nthree"},
+		{name: "head -n -10", command: "head -n -10 lines.txt", stdout: "one\ntwo"},
+		{name: "head -c 5", command: "head -c 5 lines.txt", stdout: "one\nt"},
+		{name: "head two files", command: "head -n 1 fruits.txt nums.txt", stdout: "==> fruits.txt <==\nbanana\n\n==> nums.txt <==\n10"},
+		{name: "head missing", command: "head nope", stderr: "head: cannot open 'nope' for reading: No such file or directory", exitCode: 1},
+		{name: "tail default", command: "tail lines.txt", stdout: "three\nfour\nfive\nsix\nseven\neight\nnine\nten\neleven\ntwelve"},
+		{name: "tail -n 2", command: "tail -n 2 lines.txt", stdout: "eleven\ntwelve"},
+		{name: "tail -n +11", command: "tail -n +11 lines.txt", stdout: "eleven\ntwelve"},
+		{name: "tail -c 4", command: "tail -c 4 lines.txt", stdout: "lve"},
+		{name: "wc", command: "wc words.txt", stdout: " 2  9 45 words.txt"},
+		{name: "wc -l", command: "wc -l lines.txt", stdout: "12 lines.txt"},
+		{name: "wc -w", command: "wc -w words.txt", stdout: "9 words.txt"},
+		{name: "wc -c", command: "wc -c fruits.txt", stdout: "45 fruits.txt"},
+		{name: "wc -L", command: "wc -L words.txt", stdout: "28 words.txt"},
+		{name: "wc two files", command: "wc -l fruits.txt lines.txt", stdout: "  7 fruits.txt\n 12 lines.txt\n 19 total"},
+		{name: "wc stdin", command: "cat fruits.txt | wc -l", stdout: "7"},
+		{name: "sort", command: "sort fruits.txt", stdout: "apple\napple\nbanana\nbanana\nbanana\ncherry\ndate"},
+		{name: "sort -r", command: "sort -r fruits.txt", stdout: "date\ncherry\nbanana\nbanana\nbanana\napple\napple"},
+		{name: "sort -u", command: "sort -u fruits.txt", stdout: "apple\nbanana\ncherry\ndate"},
+		{name: "sort -n", command: "sort -n nums.txt", stdout: "-3\n2.5\n9\n9\n10\n100"},
+		{name: "sort -rn", command: "sort -rn nums.txt", stdout: "100\n10\n9\n9\n2.5\n-3"},
+		{name: "sort -t -k", command: "sort -t , -k 2 -n table.csv", stdout: "name,age,city\nbob,25,berlin\nalice,30,paris\ncarol,35,rome"},
+		{name: "sort | uniq -c", command: "sort fruits.txt | uniq -c", stdout: "      2 apple\n      3 banana\n      1 cherry\n      1 date"},
+		{name: "uniq", command: "uniq fruits.txt", stdout: "banana\napple\ncherry\napple\nbanana\ndate"},
+		{name: "uniq -d", command: "sort fruits.txt | uniq -d", stdout: "apple\nbanana"},
+		{name: "uniq -u", command: "sort fruits.txt | uniq -u", stdout: "cherry\ndate"},
+		{name: "uniq -c sort -rn", command: "sort fruits.txt | uniq -c | sort -rn", stdout: "      3 banana\n      2 apple\n      1 date\n      1 cherry"},
+		{name: "cut -d -f", command: "cut -d , -f 1,3 table.csv", stdout: "name,city\nalice,paris\nbob,berlin\ncarol,rome"},
+		{name: "cut -f range", command: "cut -d , -f 2- table.csv", stdout: "age,city\n30,paris\n25,berlin\n35,rome"},
+		{name: "cut -c", command: "cut -c 1-3 fruits.txt", stdout: "ban\napp\nche\napp\nban\nban\ndat"},
+		{name: "cut -s", command: "cut -d , -f 2 -s fruits.txt"},
+		{name: "cut no list", command: "cut fruits.txt", stderr: "cut: you must specify a list of bytes, characters, or fields\nTry 'cut --help' for more information.", exitCode: 1},
+		{name: "tr upper", command: "cat fruits.txt | tr a-z A-Z", stdout: "BANANA\nAPPLE\nCHERRY\nAPPLE\nBANANA\nBANANA\nDATE"},
+		{name: "tr -d", command: "cat fruits.txt | tr -d an", stdout: "b\npple\ncherry\npple\nb\nb\ndte"},
+		{name: "tr -s", command: "cat words.txt | tr -s ' '", stdout: "the quick brown fox\njumps over\tthe lazy dog"},
+		{name: "tr rot13", command: "cat fruits.txt | tr 'A-Za-z' 'N-ZA-Mn-za-m'", stdout: "onanan\nnccyr\npureel\nnccyr\nonanan\nonanan\nqngr"},
+		{name: "tr classes", command: "cat table.csv | tr '[:lower:]' '[:upper:]'", stdout: "NAME,AGE,CITY\nALICE,30,PARIS\nBOB,25,BERLIN\nCAROL,35,ROME"},
+		{name: "tr -c", command: `cat fruits.txt | tr -cd 'a\n'`, stdout: "aaa\na\n\na\naaa\naaa\na"},
+	})
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// What happens when a level's time limit runs out
+const (
+	ExpiryReset = "reset" // Restore the level's files and start the clock again
+	ExpiryFail  = "fail"  // Lock the level until the player types `retry`
+)
+
+// Remaining times at which players are warned
+var timerWarnings = []time.Duration{60 * time.Second, 30 * time.Second, 10 * time.Second}
+
+// levelTimer is the running clock of a level with a time limit
+type levelTimer struct {
+	level    *Level
+	deadline time.Time
+	timers   []*time.Timer
+}
+
+func (t *levelTimer) stop() {
+	for _, timer := range t.timers {
+		timer.Stop()
+	}
+}
+
+// startLevelTimer starts the clock for a level that declares a time
+// limit, replacing any clock already running; callers hold s.mu
+func (e *GameEngine) startLevelTimer(s *Session, level *Level) {
+	e.stopLevelTimer(s)
+	if level.TimeLimit <= 0 {
+		return
+	}
+
+	limit := time.Duration(level.TimeLimit) * time.Second
+	timer := &levelTimer{
+		level:    level,
+		deadline: time.Now().Add(limit),
+	}
+	for _, warning := range timerWarnings {
+		if warning < limit {
+			remaining := warning
+			timer.timers = append(timer.timers, time.AfterFunc(limit-remaining, func() {
+				e.timerWarning(s, timer, remaining)
+			}))
+		}
+	}
+	timer.timers = append(timer.timers, time.AfterFunc(limit, func() {
+		e.timerExpired(s, timer)
+	}))
+	s.timer = timer
+}
+
+// stopLevelTimer cancels the running clock; callers hold s.mu
+func (e *GameEngine) stopLevelTimer(s *Session) {
+	if s.timer != nil {
+		s.timer.stop()
+		s.timer = nil
+	}
+}
+
+func (e *GameEngine) timerWarning(s *Session, timer *levelTimer, remaining time.Duration) {
+	s.mu.Lock()
+	current := s.timer == timer
+	s.mu.Unlock()
+	if !current {
+		return
+	}
+
+	e.emit(Event{
+		Type:      EventTimerWarning,
+		SessionID: s.ID,
+		Level:     timer.level.ID,
+		Message:   fmt.Sprintf("⏳ %s left on level %d!", formatRemaining(remaining), timer.level.ID),
+		Time:      time.Now(),
+	})
+}
+
+func (e *GameEngine) timerExpired(s *Session, timer *levelTimer) {
+	s.mu.Lock()
+	if s.timer != timer {
+		s.mu.Unlock()
+		return
+	}
+
+	level := timer.level
+	s.timer = nil
+	s.Pager = nil
+	message := fmt.Sprintf("⌛ Time's up! Level %d has been reset, the clock starts again.", level.ID)
+	if level.Expiry == ExpiryFail {
+		s.failedLevel = level
+		message = fmt.Sprintf("⌛ Time's up! Level %d failed. Type 'retry' to try again.", level.ID)
+	} else {
+		e.loadLevelFiles(s, level)
+	}
+	s.Recording.add("m", fmt.Sprintf("Level %d timed out", level.ID))
+	s.mu.Unlock()
+
+	log.Printf("⌛ Session %s ran out of time on level %d", s.ID, level.ID)
+	e.emit(Event{
+		Type:      EventLevelExpired,
+		SessionID: s.ID,
+		Level:     level.ID,
+		Message:   message,
+		Time:      time.Now(),
+	})
+}
+
+// retryLevel restarts a failed level with a fresh clock
+func (e *GameEngine) retryLevel(s *Session) *CommandResponse {
+	level := s.failedLevel
+	if level == nil {
+		return &CommandResponse{Stderr: "retry: the current level hasn't failed", ExitCode: 1}
+	}
+	s.failedLevel = nil
+	e.loadLevelFiles(s, level)
+	return &CommandResponse{Stdout: fmt.Sprintf("🔁 Level %d restarted. %s", level.ID, timeLeft(s))}
+}
+
+// timeLeft describes the running clock for status output
+func timeLeft(s *Session) string {
+	if s.timer == nil {
+		return ""
+	}
+	return fmt.Sprintf("Time left: %s", time.Until(s.timer.deadline).Round(time.Second))
+}
+
+// formatRemaining renders a warning threshold, e.g. "1 minute" or "30 seconds"
+func formatRemaining(d time.Duration) string {
+	switch {
+	case d == time.Minute:
+		return "1 minute"
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d minutes", int(d/time.Minute))
+	}
+	return fmt.Sprintf("%d seconds", int(d/time.Second))
+}
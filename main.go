@@ -1,10 +1,14 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strings"
 
+	"codeheist/api"
 	"codeheist/game"
+	"codeheist/sshserver"
 	"codeheist/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -14,14 +18,51 @@ func main() {
 	// Initialize game engine
 	gameEngine := game.NewEngine()
 
+	// Optional extra level packs and CTF event configuration
+	if dir := os.Getenv("LEVEL_PACKS_DIR"); dir != "" {
+		if err := gameEngine.LoadLevelPacks(dir); err != nil {
+			log.Fatal("Failed to load level packs:", err)
+		}
+	}
+	if path := os.Getenv("EVENT_CONFIG"); path != "" {
+		if err := gameEngine.LoadCTFConfig(path); err != nil {
+			log.Fatal("Failed to load event config:", err)
+		}
+	}
+
 	// Start cleanup goroutine for expired sessions
 	go gameEngine.CleanupSessions()
 
 	// Initialize WebSocket handler
 	wsHandler := websocket.NewHandler(gameEngine)
+	wsHandler.InstructorToken = os.Getenv("INSTRUCTOR_TOKEN")
+
+	// Initialize REST API handler
+	apiHandler := api.NewHandler(gameEngine)
+	apiHandler.InstructorToken = os.Getenv("INSTRUCTOR_TOKEN")
+
+	// Optional SSH frontend sharing the same engine
+	if sshPort := os.Getenv("SSH_PORT"); sshPort != "" {
+		sshServer, err := sshserver.NewServer(gameEngine, wsHandler, os.Getenv("SSH_HOST_KEY_FILE"))
+		if err != nil {
+			log.Fatal("Failed to configure SSH server:", err)
+		}
+		go func() {
+			if err := sshServer.ListenAndServe(":" + sshPort); err != nil {
+				log.Fatal("Failed to start SSH server:", err)
+			}
+		}()
+	}
 
-	// Setup Gin router
-	router := gin.Default()
+	// Setup Gin router. The access log leaves out query strings, so a
+	// token a client put in the URL never reaches the logs.
+	router := gin.New()
+	router.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		path, _, _ := strings.Cut(param.Path, "?")
+		return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"), param.StatusCode, param.Latency,
+			param.ClientIP, param.Method, path, param.ErrorMessage)
+	}), gin.Recovery())
 
 	// CORS middleware
 	router.Use(func(c *gin.Context) {
@@ -39,6 +80,19 @@ func main() {
 
 	// Routes
 	router.GET("/ws", wsHandler.HandleWebSocket)
+	router.GET("/ws/spectate/:session_id", wsHandler.HandleSpectate)
+	router.GET("/sse", wsHandler.HandleSSE)
+	router.POST("/sse/:session_id/input", wsHandler.HandleSSEInput)
+	router.POST("/api/sessions", apiHandler.CreateSession)
+	router.GET("/api/sessions/:id", apiHandler.RequireViewer, apiHandler.GetSession)
+	router.POST("/api/sessions/:id/commands", apiHandler.RequireSession, apiHandler.ExecuteCommand)
+	router.GET("/api/sessions/:id/recording", apiHandler.RequireViewer, apiHandler.GetRecording)
+	router.GET("/api/event", apiHandler.GetEvent)
+	router.PUT("/api/event", apiHandler.RequireInstructor, apiHandler.ConfigureEvent)
+	router.DELETE("/api/event", apiHandler.RequireInstructor, apiHandler.StopEvent)
+	router.POST("/api/event/teams", apiHandler.RegisterTeam)
+	router.GET("/api/event/scoreboard", apiHandler.GetScoreboard)
+	router.GET("/sse/scoreboard", wsHandler.HandleScoreboard)
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok", "service": "codeheist"})
 	})
@@ -52,6 +106,9 @@ func main() {
 	log.Printf("🚀 CodeHeist server starting on port %s", port)
 	log.Printf("💻 Web terminal available at http://localhost:%s", port)
 	log.Printf("🔌 WebSocket endpoint: ws://localhost:%s/ws", port)
+	log.Printf("📺 SSE fallback: http://localhost:%s/sse", port)
+	log.Printf("📡 REST API: http://localhost:%s/api/sessions", port)
+	log.Printf("🏆 CTF scoreboard: http://localhost:%s/api/event/scoreboard", port)
 
 	if err := router.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)
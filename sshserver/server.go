@@ -0,0 +1,257 @@
+package sshserver
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"codeheist/game"
+	"codeheist/websocket"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Server lets players connect with a real terminal, e.g.
+// `ssh codeheist0@host -p 2222`, using the level password to log in.
+// It drives the same engine and line editor as the WebSocket handler.
+type Server struct {
+	engine  *game.GameEngine
+	handler *websocket.WebSocketHandler
+	config  *ssh.ServerConfig
+}
+
+// Payloads of the SSH channel requests we care about (RFC 4254)
+type ptyRequest struct {
+	Term    string
+	Columns uint32
+	Rows    uint32
+	Width   uint32
+	Height  uint32
+	Modes   string
+}
+
+type windowChange struct {
+	Columns uint32
+	Rows    uint32
+	Width   uint32
+	Height  uint32
+}
+
+type execRequest struct {
+	Command string
+}
+
+type exitStatus struct {
+	Status uint32
+}
+
+func NewServer(engine *game.GameEngine, handler *websocket.WebSocketHandler, hostKeyFile string) (*Server, error) {
+	server := &Server{
+		engine:  engine,
+		handler: handler,
+	}
+
+	server.config = &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			level, ok := engine.AuthenticateLevel(conn.User(), string(password))
+			if !ok {
+				log.Printf("🔒 SSH login failed for %s from %s", conn.User(), conn.RemoteAddr())
+				return nil, fmt.Errorf("invalid password for %s", conn.User())
+			}
+			return &ssh.Permissions{
+				Extensions: map[string]string{"level": strconv.Itoa(level)},
+			}, nil
+		},
+		BannerCallback: func(conn ssh.ConnMetadata) string {
+			return "Welcome to CodeHeist! Log in as codeheistN with the password for level N.\n"
+		},
+	}
+
+	hostKey, err := loadHostKey(hostKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	server.config.AddHostKey(hostKey)
+
+	log.Printf("🔑 SSH host key fingerprint: %s", ssh.FingerprintSHA256(hostKey.PublicKey()))
+	return server, nil
+}
+
+// loadHostKey reads a PEM private key, or generates an ephemeral one
+// when no file is configured
+func loadHostKey(path string) (ssh.Signer, error) {
+	if path != "" {
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading SSH host key: %w", err)
+		}
+		return ssh.ParsePrivateKey(pemBytes)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generating SSH host key: %w", err)
+	}
+	log.Printf("⚠️ No SSH host key configured, using an ephemeral key")
+	return ssh.NewSignerFromKey(key)
+}
+
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🔐 SSH server listening on %s", addr)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+func (s *Server) handleConn(netConn net.Conn) {
+	conn, chans, reqs, err := ssh.NewServerConn(netConn, s.config)
+	if err != nil {
+		log.Printf("SSH handshake error: %v", err)
+		netConn.Close()
+		return
+	}
+	defer conn.Close()
+
+	level, _ := strconv.Atoi(conn.Permissions.Extensions["level"])
+	ip, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	log.Printf("🔗 New SSH connection from %s as %s", ip, conn.User())
+
+	go ssh.DiscardRequests(reqs)
+
+	// One game session per SSH connection, shared by all its channels
+	session := s.engine.CreateSessionAtLevel(ip, level)
+
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only session channels are supported")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			log.Printf("SSH channel error: %v", err)
+			continue
+		}
+		go s.handleChannel(channel, requests, session.ID)
+	}
+}
+
+func (s *Server) handleChannel(channel ssh.Channel, requests <-chan *ssh.Request, sessionID string) {
+	var once sync.Once
+	hangUp := func(status uint32) {
+		once.Do(func() {
+			channel.SendRequest("exit-status", false, ssh.Marshal(exitStatus{Status: status}))
+			channel.Close()
+		})
+	}
+
+	for req := range requests {
+		switch req.Type {
+		case "pty-req":
+			var pty ptyRequest
+			if err := ssh.Unmarshal(req.Payload, &pty); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			s.engine.ResizeTerminal(sessionID, int(pty.Columns), int(pty.Rows))
+			req.Reply(true, nil)
+
+		case "window-change":
+			var size windowChange
+			if err := ssh.Unmarshal(req.Payload, &size); err == nil {
+				s.engine.ResizeTerminal(sessionID, int(size.Columns), int(size.Rows))
+			}
+
+		case "shell":
+			req.Reply(true, nil)
+			go s.runShell(channel, sessionID, func() { hangUp(0) })
+
+		case "exec":
+			// ssh codeheist0@host 'cat readme' runs a single command
+			var exec execRequest
+			if err := ssh.Unmarshal(req.Payload, &exec); err != nil {
+				req.Reply(false, nil)
+				continue
+			}
+			req.Reply(true, nil)
+			response := s.engine.ExecuteBatchCommand(sessionID, exec.Command)
+			if response.Stdout != "" {
+				channel.Write([]byte(response.Stdout + "\n"))
+			}
+			if response.Stderr != "" {
+				channel.Stderr().Write([]byte(response.Stderr + "\n"))
+			}
+			hangUp(uint32(response.ExitCode))
+
+		default:
+			if req.WantReply {
+				req.Reply(false, nil)
+			}
+		}
+	}
+	hangUp(0)
+}
+
+// runShell feeds raw terminal bytes to the shared line editor until the
+// client disconnects or logs out
+func (s *Server) runShell(channel ssh.Channel, sessionID string, hangUp func()) {
+	client := websocket.NewClient(sessionID, &terminalWriter{channel: channel})
+	client.OnExit = hangUp
+
+	detach := s.handler.Attach(client)
+	defer detach()
+
+	s.handler.SendWelcome(client)
+
+	buf := make([]byte, 1024)
+	for {
+		n, err := channel.Read(buf)
+		if err != nil {
+			hangUp()
+			return
+		}
+		s.handler.HandleInput(client, string(buf[:n]))
+	}
+}
+
+// terminalWriter renders handler messages as raw terminal output
+type terminalWriter struct {
+	channel ssh.Channel
+}
+
+func (w *terminalWriter) WriteJSON(v interface{}) error {
+	msg, ok := v.(websocket.WSMessage)
+	if !ok {
+		return nil
+	}
+
+	switch msg.Type {
+	case "session_created", "prompt", "output", "echo":
+		_, err := w.channel.Write([]byte(toCRLF(msg.Content)))
+		return err
+	case "spectators":
+		_, err := w.channel.Write([]byte("\r\n\x1b[33m" + msg.Content + "\x1b[0m\r\n"))
+		return err
+	}
+	return nil
+}
+
+// toCRLF converts bare newlines so output renders correctly in a raw PTY
+func toCRLF(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\n", "\r\n")
+}
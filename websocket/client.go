@@ -0,0 +1,197 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// MessageWriter receives the handler's messages. *websocket.Conn satisfies it,
+// and other frontends (SSH, SSE) adapt it to their own transport.
+type MessageWriter interface {
+	WriteJSON(v interface{}) error
+}
+
+// Client is one terminal attached to a session, whatever the transport
+type Client struct {
+	SessionID string
+	writer    MessageWriter
+	editor    lineEditor
+	Team      string          // Team name when sharing a session, empty when solo
+	Race      string          // Race the client's session belongs to, empty when solo
+	Member    string          // This client's name within the team or race
+	OnExit    func()          // Hangs up on exit/logout; nil if the transport can't
+	mirror    func(WSMessage) // Copies output to spectators once attached
+	mu        sync.Mutex      // Serializes input that may arrive concurrently (SSE posts)
+}
+
+func NewClient(sessionID string, writer MessageWriter) *Client {
+	return &Client{
+		SessionID: sessionID,
+		writer:    &lockedWriter{writer: writer},
+	}
+}
+
+func (c *Client) send(msg WSMessage) {
+	c.writer.WriteJSON(msg)
+	c.mirrorOnly(msg)
+}
+
+// mirrorOnly shows a message to spectators without sending it to the client
+func (c *Client) mirrorOnly(msg WSMessage) {
+	if c.mirror != nil {
+		c.mirror(msg)
+	}
+}
+
+// lockedWriter serializes writes, since spectator notices and mirrored
+// output arrive from other connections' goroutines
+type lockedWriter struct {
+	writer MessageWriter
+	mu     sync.Mutex
+}
+
+func (w *lockedWriter) WriteJSON(v interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writer.WriteJSON(v)
+}
+
+// Writers queue this many messages for a slow client before giving up
+// on it, and a websocket write may take wsWriteWait
+const (
+	writeQueueSize = 256
+	wsWriteWait    = 10 * time.Second
+)
+
+var errStreamOverflow = errors.New("client fell too far behind")
+
+// wsWriter queues messages for a websocket and writes them from its own
+// goroutine, so a slow client never stalls the engine's event delivery.
+// A client a whole queue behind is disconnected.
+type wsWriter struct {
+	conn     *websocket.Conn
+	messages chan interface{}
+	done     chan struct{}
+	once     sync.Once
+}
+
+func newWSWriter(conn *websocket.Conn) *wsWriter {
+	w := &wsWriter{
+		conn:     conn,
+		messages: make(chan interface{}, writeQueueSize),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *wsWriter) WriteJSON(v interface{}) error {
+	select {
+	case <-w.done:
+		return errStreamClosed
+	case w.messages <- v:
+		return nil
+	default:
+		log.Printf("🐌 WebSocket client %s fell behind, disconnecting", w.conn.RemoteAddr())
+		w.close()
+		return errStreamOverflow
+	}
+}
+
+func (w *wsWriter) run() {
+	for {
+		select {
+		case v := <-w.messages:
+			w.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := w.conn.WriteJSON(v); err != nil {
+				w.close()
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// close stops the writer and hangs up, which ends the read loop too
+func (w *wsWriter) close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.conn.Close()
+	})
+}
+
+// InputEmpty reports whether nothing has been typed on the current line
+func (c *Client) InputEmpty() bool {
+	return c.editor.pending() == ""
+}
+
+// lineEditor buffers keystrokes until Enter. Teammates' goroutines read
+// the pending line to redraw it, so the buffer has its own lock.
+type lineEditor struct {
+	buf []rune
+	mu  sync.Mutex
+}
+
+// Key events produced by the line editor
+const (
+	keyNone = iota
+	keyEnter
+	keyInterrupt
+	keyEOF
+)
+
+// feed applies one keystroke and returns the echo to draw plus any
+// key event the handler needs to act on
+func (l *lineEditor) feed(r rune) (string, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch r {
+	case '\r', '\n':
+		return "\r\n", keyEnter
+	case 0x7f, '\b': // Backspace
+		if len(l.buf) == 0 {
+			return "", keyNone
+		}
+		l.buf = l.buf[:len(l.buf)-1]
+		return "\b \b", keyNone
+	case 0x03: // Ctrl-C
+		l.buf = nil
+		return "^C\r\n", keyInterrupt
+	case 0x04: // Ctrl-D
+		return "", keyEOF
+	case 0x15: // Ctrl-U
+		echo := strings.Repeat("\b \b", len(l.buf))
+		l.buf = nil
+		return echo, keyNone
+	}
+
+	if r < 0x20 && r != '\t' {
+		return "", keyNone // Ignore other control characters
+	}
+	l.buf = append(l.buf, r)
+	return string(r), keyNone
+}
+
+// take returns the buffered line and clears it
+func (l *lineEditor) take() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	line := string(l.buf)
+	l.buf = nil
+	return line
+}
+
+// pending returns the line typed so far without consuming it
+func (l *lineEditor) pending() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return string(l.buf)
+}
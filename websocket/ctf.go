@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HandleScoreboard handles GET /sse/scoreboard, streaming the CTF
+// scoreboard to projectors and dashboards whenever a flag is solved
+func (h *WebSocketHandler) HandleScoreboard(c *gin.Context) {
+	if _, exists := h.engine.CTFInfo(); !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no CTF event is configured"})
+		return
+	}
+
+	updates := make(chan struct{}, 1)
+	h.mu.Lock()
+	h.scoreboards[updates] = true
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.scoreboards, updates)
+		h.mu.Unlock()
+	}()
+
+	log.Printf("🏆 Scoreboard stream opened from %s", c.ClientIP())
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+
+	c.SSEvent("scoreboard", h.engine.Scoreboard())
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-updates:
+			c.SSEvent("scoreboard", h.engine.Scoreboard())
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// scoreboardChanged wakes every scoreboard stream; a stream that is
+// already due for an update doesn't need a second one
+func (h *WebSocketHandler) scoreboardChanged() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for updates := range h.scoreboards {
+		select {
+		case updates <- struct{}{}:
+		default:
+		}
+	}
+}
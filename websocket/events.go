@@ -0,0 +1,42 @@
+package websocket
+
+import "codeheist/game"
+
+// onEvent pushes engine events that concern other players to their terminals
+func (h *WebSocketHandler) onEvent(event game.Event) {
+	switch event.Type {
+	case game.EventRaceCountdown:
+		go h.runCountdown(event.Group, event.Time)
+	case game.EventRaceProgress:
+		h.toRace(event.Group, event.SessionID, WSMessage{
+			Type:    "race_progress",
+			Content: "\r\n\x1b[33m🏁 " + event.Message + "\x1b[0m\r\n",
+			Level:   event.Level,
+		})
+	case game.EventRaceFinished:
+		h.toRace(event.Group, event.SessionID, WSMessage{
+			Type:    "race_results",
+			Content: "\r\n\x1b[33m" + h.engine.RaceResults(event.Group) + "\x1b[0m\r\n",
+		})
+	case game.EventFlagSolved:
+		h.notify(h.engine.CTFSessions(), event.SessionID, WSMessage{
+			Type:    "flag_solved",
+			User:    event.Group,
+			Content: "\r\n\x1b[32m🚩 " + event.Message + "\x1b[0m\r\n",
+		})
+		h.scoreboardChanged()
+	case game.EventTimerWarning, game.EventLevelExpired:
+		h.notify([]string{event.SessionID}, "", WSMessage{
+			Type:    "timer",
+			Level:   event.Level,
+			Content: "\r\n\x1b[33m" + event.Message + "\x1b[0m\r\n",
+		})
+	case game.EventFirstBlood:
+		// First blood is news for everyone on the server, not just the teams
+		h.notify(h.connectedSessions(), event.SessionID, WSMessage{
+			Type:    "first_blood",
+			User:    event.Group,
+			Content: "\r\n\x1b[31m🩸 " + event.Message + "\x1b[0m\r\n",
+		})
+	}
+}
@@ -3,7 +3,9 @@ package websocket
 import (
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"sync"
 
 	"codeheist/game"
 
@@ -15,28 +17,73 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true // Allow all origins in development
 	},
+	Subprotocols: []string{tokenProtocol},
+}
+
+// tokenProtocol carries a token from browsers, which can't set headers
+// on a websocket: new WebSocket(url, ["codeheist.token", token])
+const tokenProtocol = "codeheist.token"
+
+// requestToken reads a bearer token from the Authorization header or
+// the websocket subprotocols. Never from the URL, which ends up in
+// access logs and browser history.
+func requestToken(c *gin.Context) string {
+	if token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); found {
+		return token
+	}
+	protocols := websocket.Subprotocols(c.Request)
+	for i, protocol := range protocols {
+		if protocol == tokenProtocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
 }
 
 type WebSocketHandler struct {
-	engine *game.GameEngine
+	engine          *game.GameEngine
+	InstructorToken string                 // Required to spectate; spectating is off when empty
+	sseClients      map[string]*Client     // Event streams by session ID
+	hubs            map[string]*sessionHub // Attached terminals by session ID
+	scoreboards     map[chan struct{}]bool // Open /sse/scoreboard streams
+	mu              sync.Mutex
 }
 
 type WSMessage struct {
 	Type      string `json:"type"`
 	Content   string `json:"content,omitempty"`
+	Stream    string `json:"stream,omitempty"` // "stdout" or "stderr" for output messages
 	Data      string `json:"data,omitempty"`
 	Command   string `json:"command,omitempty"`
 	Level     int    `json:"level,omitempty"`
 	SessionID string `json:"session_id,omitempty"`
+	Token     string `json:"token,omitempty"`
+	ExitCode  int    `json:"exit_code,omitempty"`
+	Cols      int    `json:"cols,omitempty"`
+	Rows      int    `json:"rows,omitempty"`
+	User      string `json:"user,omitempty"`
+	Count     int    `json:"count,omitempty"` // Number of spectators
 }
 
 func NewHandler(engine *game.GameEngine) *WebSocketHandler {
-	return &WebSocketHandler{
-		engine: engine,
+	h := &WebSocketHandler{
+		engine:      engine,
+		sseClients:  make(map[string]*Client),
+		hubs:        make(map[string]*sessionHub),
+		scoreboards: make(map[chan struct{}]bool),
 	}
+	engine.Subscribe(h.onEvent)
+	return h
 }
 
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// Replays need the session's or an instructor's token, sent like a
+	// spectator's rather than in the URL
+	if replayID := c.Query("replay"); replayID != "" && !h.canReplay(replayID, requestToken(c)) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session or token"})
+		return
+	}
+
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Printf("WebSocket upgrade error: %v", err)
@@ -44,26 +91,51 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 	}
 	defer conn.Close()
 
-	// Create new session
-	ip := strings.Split(c.Request.RemoteAddr, ":")[0] // Get IP without port
-	session := h.engine.CreateSession(ip)
+	// Stream a recorded session instead of starting a new one
+	if replayID := c.Query("replay"); replayID != "" {
+		h.handleReplay(conn, replayID, c.Query("speed"))
+		return
+	}
 
-	log.Printf("🔗 New WebSocket connection from %s, session: %s", ip, session.ID)
+	ip := strings.Split(c.Request.RemoteAddr, ":")[0] // Get IP without port
+	writer := newWSWriter(conn)
+	defer writer.close()
 
-	// Send session created message
-	welcomeMsg := WSMessage{
-		Type:      "session_created",
-		Content:   "\r\n\x1b[32m● WELCOME TO CODEHEIST\x1b[0m\r\n" + getLevelWelcomeMessage(h.engine, session.CurrentLevel) + "\r\n",
-		SessionID: session.ID,
+	var client *Client
+	if teamName := c.Query("team"); teamName != "" {
+		// Join a team's shared session
+		team, member, err := h.engine.JoinTeam(teamName, c.Query("name"), ip)
+		if err != nil {
+			conn.WriteJSON(WSMessage{Type: "error", Content: err.Error()})
+			return
+		}
+		client = NewClient(team.SessionID, writer)
+		client.Team = team.Name
+		client.Member = member
+		defer h.leaveTeam(client)
+	} else if raceName := c.Query("race"); raceName != "" {
+		// Join a race lobby with a session of our own
+		size, _ := strconv.Atoi(c.Query("size"))
+		race, session, player, err := h.engine.JoinRace(raceName, c.Query("name"), ip, size)
+		if err != nil {
+			conn.WriteJSON(WSMessage{Type: "error", Content: err.Error()})
+			return
+		}
+		client = NewClient(session.ID, writer)
+		client.Race = race.Name
+		client.Member = player
+		defer h.engine.LeaveRace(race.Name, player)
+	} else {
+		// Create new session
+		session := h.engine.CreateSession(ip)
+		client = NewClient(session.ID, writer)
 	}
-	conn.WriteJSON(welcomeMsg)
+	detach := h.Attach(client)
+	defer detach()
 
-	// Send initial prompt
-	promptMsg := WSMessage{
-		Type:    "prompt",
-		Content: "$ ",
-	}
-	conn.WriteJSON(promptMsg)
+	log.Printf("🔗 New WebSocket connection from %s, session: %s", ip, client.SessionID)
+
+	h.SendWelcome(client)
 
 	// Handle messages from client
 	for {
@@ -74,49 +146,248 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 			break
 		}
 
-		log.Printf("📨 Received message type: %s", msg.Type)
+		h.dispatch(client, msg)
+	}
+}
+
+// dispatch handles one client message, whichever transport it arrived on
+func (h *WebSocketHandler) dispatch(client *Client, msg WSMessage) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
 
-		switch msg.Type {
-		case "command":
-			h.handleCommand(conn, session.ID, msg.Command)
-		case "command_input":
-			// Handle direct input from terminal
-			h.handleCommandInput(conn, session.ID, msg.Data)
-		default:
-			log.Printf("❌ Unknown message type: %s", msg.Type)
+	log.Printf("📨 Received message type: %s", msg.Type)
+
+	switch msg.Type {
+	case "command":
+		// The player's terminal already shows the line; spectators need it echoed
+		h.engine.RecordInput(client.SessionID, msg.Command+"\r")
+		client.mirrorOnly(WSMessage{Type: "echo", Content: msg.Command + "\r\n"})
+		h.handleCommand(client, msg.Command)
+	case "command_input":
+		// Handle direct input from terminal
+		h.HandleInput(client, msg.Data)
+	case "resize":
+		// Terminal window size changed on the client
+		if !h.engine.ResizeTerminal(client.SessionID, msg.Cols, msg.Rows) {
+			log.Printf("❌ Invalid resize: %dx%d", msg.Cols, msg.Rows)
 		}
+	case "login":
+		// Continue from a level unlocked on another frontend (e.g. SSH)
+		h.handleLogin(client, msg.User, msg.Data)
+	case "player":
+		// Keep achievements and speedrun bests under a player name
+		h.handlePlayer(client, msg.User, msg.Token)
+	case "chat":
+		h.handleChat(client, msg.Content)
+	case "race_start":
+		h.handleRaceStart(client)
+	default:
+		log.Printf("❌ Unknown message type: %s", msg.Type)
 	}
 }
 
-// Handle command input (character by character)
-func (h *WebSocketHandler) handleCommandInput(conn *websocket.Conn, sessionID string, input string) {
-	// For now, we'll handle complete commands only
-	// This can be extended for real-time input handling
-	if input == "\r" || input == "\n" { // Enter key
-		session, exists := h.engine.GetSession(sessionID)
-		if exists && session.CurrentInput != "" {
-			h.handleCommand(conn, sessionID, session.CurrentInput)
-			session.CurrentInput = ""
-		} else {
-			// Send empty prompt
-			promptMsg := WSMessage{
-				Type:    "prompt",
-				Content: "$ ",
-			}
-			conn.WriteJSON(promptMsg)
+// SendWelcome greets a newly attached client and shows the first prompt
+func (h *WebSocketHandler) SendWelcome(client *Client) {
+	session, exists := h.engine.GetSession(client.SessionID)
+	if !exists {
+		return
+	}
+	// The level changes under the session lock, so read it through one
+	info, exists := h.engine.SessionInfo(client.SessionID)
+	if !exists {
+		return
+	}
+
+	// Send session created message
+	client.send(WSMessage{
+		Type:      "session_created",
+		Content:   "\r\n\x1b[32m● WELCOME TO CODEHEIST\x1b[0m\r\n" + info.Welcome + "\r\n",
+		SessionID: session.ID,
+		Token:     session.Token,
+		Level:     info.Level,
+	})
+
+	if client.Team != "" {
+		h.announceToTeam(client, client.Member+" joined the heist")
+	}
+	if client.Race != "" {
+		h.announceRaceJoin(client)
+	}
+
+	// Send initial prompt
+	h.sendPrompt(client, 0)
+}
+
+// HandleInput processes raw terminal input (character by character),
+// echoing it back and running the line when Enter is pressed
+func (h *WebSocketHandler) HandleInput(client *Client, input string) {
+	h.engine.RecordInput(client.SessionID, input)
+
+	// Keystrokes belong to less/more while a pager is open
+	if h.engine.PagerActive(client.SessionID) {
+		h.handlePagerInput(client, input)
+		return
+	}
+
+	// Escape sequences (arrow keys etc.) aren't supported by the line editor
+	if strings.HasPrefix(input, "\x1b") {
+		return
+	}
+
+	// ssh password prompts don't echo what's typed
+	hidden := h.engine.PasswordPending(client.SessionID)
+	var echo strings.Builder
+	flushEcho := func() {
+		if echo.Len() > 0 {
+			client.send(WSMessage{Type: "echo", Content: echo.String()})
+			echo.Reset()
+		}
+	}
+
+	previous := rune(0)
+	for _, r := range input {
+		// Treat a pasted CRLF as a single Enter
+		if r == '\n' && previous == '\r' {
+			continue
 		}
-	} else if input == "\x7f" { // Backspace
-		session, exists := h.engine.GetSession(sessionID)
-		if exists && len(session.CurrentInput) > 0 {
-			session.CurrentInput = session.CurrentInput[:len(session.CurrentInput)-1]
+		previous = r
+
+		output, key := client.editor.feed(r)
+		if !hidden || key != keyNone {
+			echo.WriteString(output)
 		}
-	} else {
-		// Append to current input
-		session, exists := h.engine.GetSession(sessionID)
-		if exists {
-			session.CurrentInput += input
+
+		switch key {
+		case keyEnter:
+			flushEcho()
+			if line := client.editor.take(); strings.TrimSpace(line) != "" || hidden {
+				h.handleCommand(client, line)
+			} else {
+				// Send empty prompt
+				h.sendPrompt(client, 0)
+			}
+			hidden = h.engine.PasswordPending(client.SessionID)
+		case keyInterrupt:
+			flushEcho()
+			h.engine.CancelLogin(client.SessionID)
+			hidden = false
+			h.sendPrompt(client, 130)
+		case keyEOF:
+			if client.InputEmpty() && h.engine.Remote(client.SessionID) {
+				flushEcho()
+				h.handleCommand(client, "logout")
+			} else if client.InputEmpty() && client.OnExit != nil {
+				flushEcho()
+				h.logout(client)
+				return
+			}
+		}
+
+		// Anything typed after opening a pager is for the pager, not the shell
+		if h.engine.PagerActive(client.SessionID) {
+			return
 		}
 	}
+	flushEcho()
+}
+
+// handlePagerInput forwards a keystroke to the open pager and
+// returns to the prompt once the pager exits
+func (h *WebSocketHandler) handlePagerInput(client *Client, input string) {
+	output, done := h.engine.HandlePagerKey(client.SessionID, input)
+	if output != "" {
+		client.send(WSMessage{
+			Type:    "output",
+			Stream:  "stdout",
+			Content: output,
+		})
+	}
+
+	if done {
+		h.sendPrompt(client, 0)
+	}
+}
+
+func (h *WebSocketHandler) handleLogin(client *Client, user, password string) {
+	if !h.engine.Login(client.SessionID, user, password) {
+		client.send(WSMessage{
+			Type:    "output",
+			Stream:  "stderr",
+			Content: "\x1b[31mPermission denied, please try again.\x1b[0m\r\n",
+		})
+		h.sendPrompt(client, 1)
+		return
+	}
+
+	info, exists := h.engine.SessionInfo(client.SessionID)
+	if !exists {
+		return
+	}
+	client.send(WSMessage{
+		Type:  "level_up",
+		Level: info.Level,
+	})
+	client.send(WSMessage{
+		Type:    "output",
+		Content: "\r\n\x1b[36m" + info.Welcome + "\x1b[0m\r\n",
+	})
+	h.sendPrompt(client, 0)
+}
+
+// handlePlayer signs the session in as a player, answering with the
+// token the client needs to sign in as that player again
+func (h *WebSocketHandler) handlePlayer(client *Client, name, token string) {
+	token, err := h.engine.SignIn(client.SessionID, name, token)
+	if err != nil {
+		client.send(WSMessage{
+			Type:    "output",
+			Stream:  "stderr",
+			Content: "\x1b[31m" + err.Error() + "\x1b[0m\r\n",
+		})
+		h.sendPrompt(client, 1)
+		return
+	}
+
+	client.send(WSMessage{
+		Type:  "player",
+		User:  name,
+		Token: token,
+	})
+	client.send(WSMessage{
+		Type:    "output",
+		Content: "\x1b[36m👤 Signed in as " + name + "\x1b[0m\r\n",
+	})
+	h.sendPrompt(client, 0)
+}
+
+// logout ends the client's connection on exit, logout or Ctrl-D
+func (h *WebSocketHandler) logout(client *Client) {
+	client.send(WSMessage{
+		Type:    "output",
+		Stream:  "stdout",
+		Content: "logout\r\n",
+	})
+	client.OnExit()
+}
+
+func (h *WebSocketHandler) sendPrompt(client *Client, exitCode int) {
+	client.send(WSMessage{
+		Type:     "prompt",
+		Content:  h.promptFor(client),
+		ExitCode: exitCode,
+	})
+}
+
+// promptFor shows where a multi-host level has the player, e.g.
+// "alice@web01:~$ ", and team members who they are, e.g. "alice@crew$ "
+func (h *WebSocketHandler) promptFor(client *Client) string {
+	if prompt := h.engine.Prompt(client.SessionID); prompt != "" {
+		return prompt
+	}
+	if client.Team == "" {
+		return "$ "
+	}
+	return client.Member + "@" + client.Team + "$ "
 }
 
 // Helper function to get level welcome message
@@ -127,19 +398,111 @@ func getLevelWelcomeMessage(engine *game.GameEngine, level int) string {
 	return "Welcome to CodeHeist! Your mission awaits..."
 }
 
-func (h *WebSocketHandler) handleCommand(conn *websocket.Conn, sessionID string, command string) {
+func (h *WebSocketHandler) handleCommand(client *Client, command string) {
+	sessionID := client.SessionID
+	if h.engine.PasswordPending(sessionID) {
+		h.handlePassword(client, command)
+		return
+	}
 	log.Printf("🔧 Executing command: '%s' for session: %s", command, sessionID)
 
-	// Execute command
-	response := h.engine.ExecuteCommand(sessionID, command)
+	// Hang up transports that support it, like a real login shell, unless
+	// exit only leaves a machine reached with ssh
+	switch strings.TrimSpace(command) {
+	case "exit", "logout":
+		if client.OnExit != nil && !h.engine.Remote(sessionID) {
+			h.logout(client)
+			return
+		}
+	}
+
+	// Execute command, crediting the team member who ran it
+	var response *game.CommandResponse
+	if client.Team != "" {
+		response = h.engine.ExecuteTeamCommand(client.Team, client.Member, command)
+	} else {
+		response = h.engine.ExecuteCommand(sessionID, command)
+	}
 
-	// Send command output
-	if response.Output != "" {
+	// Pager output is already laid out for the terminal and
+	// waits for keystrokes instead of returning to the prompt
+	if response.Paging {
+		client.send(WSMessage{
+			Type:    "output",
+			Stream:  "stdout",
+			Content: response.Stdout,
+		})
+		return
+	}
+
+	if response.Password {
+		h.askPassword(client, response)
+		return
+	}
+
+	messages := h.responseMessages(response)
+	for _, msg := range messages {
+		client.send(msg)
+	}
+
+	// Teammates see who ran what and the shared result
+	if client.Team != "" {
+		h.shareWithTeam(client, command, messages)
+	}
+
+	// Always send new prompt after command execution
+	h.sendPrompt(client, response.ExitCode)
+}
+
+// handlePassword passes a line typed at an ssh password prompt to the
+// engine without logging it or showing it to teammates
+func (h *WebSocketHandler) handlePassword(client *Client, password string) {
+	var response *game.CommandResponse
+	if client.Team != "" {
+		response = h.engine.ExecuteTeamCommand(client.Team, client.Member, password)
+	} else {
+		response = h.engine.ExecuteCommand(client.SessionID, password)
+	}
+	if response.Password {
+		h.askPassword(client, response)
+		return
+	}
+	for _, msg := range h.responseMessages(response) {
+		client.send(msg)
+	}
+	h.sendPrompt(client, response.ExitCode)
+}
+
+// askPassword shows ssh's warnings and password prompt, which waits on
+// the same line for the password instead of returning to the shell
+func (h *WebSocketHandler) askPassword(client *Client, response *game.CommandResponse) {
+	if response.Stderr != "" {
+		client.send(WSMessage{Type: "output", Stream: "stderr", Content: response.Stderr + "\r\n"})
+	}
+	client.send(WSMessage{Type: "output", Stream: "stdout", Content: response.Stdout})
+}
+
+// responseMessages turns a command response into the messages the
+// terminal shows: stdout and stderr as separate streams, then any level-up
+func (h *WebSocketHandler) responseMessages(response *game.CommandResponse) []WSMessage {
+	var messages []WSMessage
+
+	// Command output, stdout and stderr as separate streams
+	if response.Stdout != "" {
 		outputMsg := WSMessage{
 			Type:    "output",
-			Content: response.Output + "\r\n",
+			Stream:  "stdout",
+			Content: response.Stdout + "\r\n",
 		}
-		conn.WriteJSON(outputMsg)
+		messages = append(messages, outputMsg)
+	}
+	if response.Stderr != "" {
+		errorMsg := WSMessage{
+			Type:    "output",
+			Stream:  "stderr",
+			Content: "\x1b[31m" + response.Stderr + "\x1b[0m\r\n",
+		}
+		messages = append(messages, errorMsg)
 	}
 
 	// Handle level completion
@@ -148,23 +511,23 @@ func (h *WebSocketHandler) handleCommand(conn *websocket.Conn, sessionID string,
 			Type:  "level_up",
 			Level: response.NewLevel,
 		}
-		conn.WriteJSON(levelUpMsg)
-
-		// Send welcome message for new level
-		session, exists := h.engine.GetSession(sessionID)
-		if exists {
-			welcomeMsg := WSMessage{
-				Type:    "output",
-				Content: "\r\n\x1b[36m" + getLevelWelcomeMessage(h.engine, session.CurrentLevel) + "\x1b[0m\r\n",
-			}
-			conn.WriteJSON(welcomeMsg)
+		messages = append(messages, levelUpMsg)
+
+		// Welcome message for new level
+		welcomeMsg := WSMessage{
+			Type:    "output",
+			Content: "\r\n\x1b[36m" + getLevelWelcomeMessage(h.engine, response.NewLevel) + "\x1b[0m\r\n",
 		}
+		messages = append(messages, welcomeMsg)
 	}
 
-	// Always send new prompt after command execution
-	promptMsg := WSMessage{
-		Type:    "prompt",
-		Content: "$ ",
+	for _, achievement := range response.Achievements {
+		messages = append(messages, WSMessage{
+			Type:    "achievement",
+			Data:    achievement.ID,
+			Content: "\r\n\x1b[33m" + achievement.Icon + " Achievement unlocked: " + achievement.Title + " - " + achievement.Description + "\x1b[0m\r\n",
+		})
 	}
-	conn.WriteJSON(promptMsg)
+
+	return messages
 }
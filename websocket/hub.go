@@ -0,0 +1,233 @@
+package websocket
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionHub tracks every terminal attached to one session: the player's
+// own clients (any transport) and read-only spectators
+type sessionHub struct {
+	players    map[*Client]bool
+	spectators map[*Client]bool
+}
+
+func (h *WebSocketHandler) hubFor(sessionID string) *sessionHub {
+	hub, exists := h.hubs[sessionID]
+	if !exists {
+		hub = &sessionHub{
+			players:    make(map[*Client]bool),
+			spectators: make(map[*Client]bool),
+		}
+		h.hubs[sessionID] = hub
+	}
+	return hub
+}
+
+// Attach registers a player's client so spectators see its terminal
+// and its output is recorded.
+// The returned function detaches it when the connection ends.
+func (h *WebSocketHandler) Attach(client *Client) func() {
+	h.mu.Lock()
+	h.hubFor(client.SessionID).players[client] = true
+	spectators := len(h.hubs[client.SessionID].spectators)
+	h.mu.Unlock()
+
+	client.mirror = func(msg WSMessage) {
+		h.toSpectators(client.SessionID, msg)
+		h.record(client.SessionID, msg)
+	}
+
+	// Someone may already be watching this session
+	if spectators > 0 {
+		client.writer.WriteJSON(spectatorNotice(spectators))
+	}
+
+	return func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if hub, exists := h.hubs[client.SessionID]; exists {
+			delete(hub.players, client)
+			h.dropEmptyHub(client.SessionID)
+		}
+	}
+}
+
+func (h *WebSocketHandler) dropEmptyHub(sessionID string) {
+	hub := h.hubs[sessionID]
+	if len(hub.players) == 0 && len(hub.spectators) == 0 {
+		delete(h.hubs, sessionID)
+	}
+}
+
+// toSpectators copies a player's message to everyone watching the session
+func (h *WebSocketHandler) toSpectators(sessionID string, msg WSMessage) {
+	h.mu.Lock()
+	var spectators []*Client
+	if hub, exists := h.hubs[sessionID]; exists {
+		for spectator := range hub.spectators {
+			spectators = append(spectators, spectator)
+		}
+	}
+	h.mu.Unlock()
+
+	// Never leak the player's session token
+	msg.Token = ""
+	for _, spectator := range spectators {
+		spectator.writer.WriteJSON(msg)
+	}
+}
+
+// record keeps what the player's terminal displayed for later replay
+func (h *WebSocketHandler) record(sessionID string, msg WSMessage) {
+	switch msg.Type {
+	case "session_created", "prompt", "output", "echo":
+		h.engine.RecordOutput(sessionID, msg.Content)
+	}
+}
+
+// notifyPlayers tells the player how many people are watching
+func (h *WebSocketHandler) notifyPlayers(sessionID string) {
+	h.mu.Lock()
+	var players []*Client
+	count := 0
+	if hub, exists := h.hubs[sessionID]; exists {
+		for player := range hub.players {
+			players = append(players, player)
+		}
+		count = len(hub.spectators)
+	}
+	h.mu.Unlock()
+
+	for _, player := range players {
+		player.writer.WriteJSON(spectatorNotice(count))
+	}
+}
+
+// notify shows an asynchronous notice on the terminals of several
+// sessions. The session whose command caused it gets its prompt back from
+// handleCommand; everyone else gets their prompt and half-typed line
+// redrawn below the notice.
+func (h *WebSocketHandler) notify(sessionIDs []string, causedBy string, msg WSMessage) {
+	for _, sessionID := range sessionIDs {
+		for _, player := range h.sessionPlayers(sessionID) {
+			player.send(msg)
+			if sessionID == causedBy || h.engine.PagerActive(sessionID) || h.engine.PasswordPending(sessionID) {
+				continue
+			}
+			player.send(WSMessage{Type: "prompt", Content: h.promptFor(player)})
+			if pending := player.editor.pending(); pending != "" {
+				player.send(WSMessage{Type: "echo", Content: pending})
+			}
+		}
+	}
+}
+
+// sessionPlayers returns the clients attached to a session
+func (h *WebSocketHandler) sessionPlayers(sessionID string) []*Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var players []*Client
+	if hub, exists := h.hubs[sessionID]; exists {
+		for player := range hub.players {
+			players = append(players, player)
+		}
+	}
+	return players
+}
+
+// connectedSessions returns every session with a terminal attached
+func (h *WebSocketHandler) connectedSessions() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var sessionIDs []string
+	for sessionID, hub := range h.hubs {
+		if len(hub.players) > 0 {
+			sessionIDs = append(sessionIDs, sessionID)
+		}
+	}
+	return sessionIDs
+}
+
+func spectatorNotice(count int) WSMessage {
+	content := "👀 Nobody is watching your terminal anymore"
+	switch {
+	case count == 1:
+		content = "👀 An instructor is watching your terminal"
+	case count > 1:
+		content = fmt.Sprintf("👀 %d instructors are watching your terminal", count)
+	}
+	return WSMessage{
+		Type:    "spectators",
+		Content: content,
+		Count:   count,
+	}
+}
+
+// HandleSpectate handles GET /ws/spectate/:session_id, streaming the
+// target session's terminal read-only to an instructor authorized by
+// header or subprotocol
+func (h *WebSocketHandler) HandleSpectate(c *gin.Context) {
+	if !h.isInstructor(requestToken(c)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "instructor token required"})
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	info, exists := h.engine.SessionInfo(sessionID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	writer := newWSWriter(conn)
+	defer writer.close()
+	spectator := NewClient(sessionID, writer)
+	spectator.send(WSMessage{
+		Type:      "spectate_started",
+		Content:   fmt.Sprintf("\r\n\x1b[33m👀 Watching %s (level %d: %s)\x1b[0m\r\n", info.User, info.Level, info.Title),
+		SessionID: sessionID,
+		Level:     info.Level,
+	})
+
+	h.mu.Lock()
+	h.hubFor(sessionID).spectators[spectator] = true
+	h.mu.Unlock()
+	h.notifyPlayers(sessionID)
+
+	log.Printf("👀 Spectator from %s watching session: %s", c.ClientIP(), sessionID)
+
+	// Spectators are read-only: drain and ignore anything they send
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+
+	h.mu.Lock()
+	if hub, exists := h.hubs[sessionID]; exists {
+		delete(hub.spectators, spectator)
+		h.dropEmptyHub(sessionID)
+	}
+	h.mu.Unlock()
+	h.notifyPlayers(sessionID)
+
+	log.Printf("👋 Spectator left session: %s", sessionID)
+}
+
+func (h *WebSocketHandler) isInstructor(token string) bool {
+	return h.InstructorToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.InstructorToken)) == 1
+}
@@ -0,0 +1,59 @@
+package websocket
+
+import (
+	"fmt"
+	"time"
+)
+
+// runCountdown ticks down to the race start; the engine keeps input
+// locked until then, so every player unlocks at the same moment
+func (h *WebSocketHandler) runCountdown(raceName string, startsAt time.Time) {
+	for remaining := time.Until(startsAt); remaining > 0; remaining = time.Until(startsAt) {
+		seconds := int((remaining + time.Second - 1) / time.Second)
+		h.toRace(raceName, "", WSMessage{
+			Type:    "race_countdown",
+			Content: fmt.Sprintf("\r\n\x1b[33m🏁 Race starts in %d...\x1b[0m\r\n", seconds),
+			Count:   seconds,
+		})
+		time.Sleep(remaining - time.Duration(seconds-1)*time.Second)
+	}
+
+	h.toRace(raceName, "", WSMessage{
+		Type:    "race_start",
+		Content: "\r\n\x1b[32m🏁 GO! Clear every level before your rivals!\x1b[0m\r\n",
+	})
+}
+
+// toRace shows a notice on every racer's terminal
+func (h *WebSocketHandler) toRace(raceName, causedBy string, msg WSMessage) {
+	h.notify(h.engine.RacePlayers(raceName), causedBy, msg)
+}
+
+// announceRaceJoin tells the lobby who joined and how many spots are left
+func (h *WebSocketHandler) announceRaceJoin(client *Client) {
+	race, exists := h.engine.GetRace(client.Race)
+	if !exists {
+		return
+	}
+
+	text := fmt.Sprintf("%s joined race %s (%d/%d players)",
+		client.Member, race.Name, len(h.engine.RacePlayers(race.Name)), race.Size)
+	if client.Member == race.Host {
+		text += " - send race_start to begin early"
+	}
+	h.toRace(race.Name, client.SessionID, WSMessage{
+		Type:    "race_lobby",
+		User:    client.Member,
+		Content: "\r\n\x1b[33m🏁 " + text + "\x1b[0m\r\n",
+	})
+}
+
+func (h *WebSocketHandler) handleRaceStart(client *Client) {
+	if client.Race == "" {
+		client.send(WSMessage{Type: "error", Content: "not in a race"})
+		return
+	}
+	if err := h.engine.StartRace(client.Race, client.Member); err != nil {
+		client.send(WSMessage{Type: "error", Content: err.Error()})
+	}
+}
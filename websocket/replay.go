@@ -0,0 +1,88 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Replay speeds accepted from ?speed=, e.g. 2 for twice as fast
+const (
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 50
+)
+
+// canReplay allows the session's own token or an instructor token
+func (h *WebSocketHandler) canReplay(sessionID, token string) bool {
+	if h.isInstructor(token) {
+		return true
+	}
+	_, ok := h.engine.AuthenticateSession(sessionID, token)
+	return ok
+}
+
+// handleReplay streams a session's recording over /ws?replay=<id>&speed=N
+// with the original timing, scaled by speed
+func (h *WebSocketHandler) handleReplay(conn *websocket.Conn, sessionID, speedParam string) {
+	recording, exists := h.engine.GetRecording(sessionID)
+	if !exists {
+		conn.WriteJSON(WSMessage{Type: "error", Content: "session not found"})
+		return
+	}
+
+	speed, err := strconv.ParseFloat(speedParam, 64)
+	if err != nil || speed < minReplaySpeed {
+		speed = 1
+	}
+	if speed > maxReplaySpeed {
+		speed = maxReplaySpeed
+	}
+
+	// Stop replaying as soon as the viewer disconnects
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	log.Printf("📼 Replaying session %s at %.2gx", sessionID, speed)
+
+	conn.WriteJSON(WSMessage{
+		Type:      "replay_started",
+		Content:   fmt.Sprintf("\r\n\x1b[33m📼 Replaying session at %.2gx\x1b[0m\r\n", speed),
+		SessionID: sessionID,
+		Cols:      recording.Cols,
+		Rows:      recording.Rows,
+	})
+
+	previous := 0.0
+	for _, frame := range recording.Frames {
+		delay := time.Duration((frame.Time - previous) / speed * float64(time.Second))
+		previous = frame.Time
+
+		select {
+		case <-time.After(delay):
+		case <-closed:
+			return
+		}
+
+		switch frame.Type {
+		case "o":
+			conn.WriteJSON(WSMessage{Type: "output", Content: frame.Data})
+		case "r":
+			var cols, rows int
+			if _, err := fmt.Sscanf(frame.Data, "%dx%d", &cols, &rows); err == nil {
+				conn.WriteJSON(WSMessage{Type: "resize", Cols: cols, Rows: rows})
+			}
+		}
+	}
+
+	conn.WriteJSON(WSMessage{Type: "replay_finished", SessionID: sessionID})
+}
@@ -0,0 +1,164 @@
+package websocket
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server-Sent Events transport for networks that block WebSocket upgrades.
+// GET /sse streams the same messages as /ws (one SSE event per WSMessage,
+// named after its type) and POST /sse/:session_id/input accepts the same
+// client messages the WebSocket reads, so a client can switch transports
+// without changing how it renders or sends anything.
+
+const sseHeartbeat = 15 * time.Second
+
+var errStreamClosed = errors.New("event stream closed")
+
+// sseWriter queues messages for the streaming response. It never blocks:
+// a client a whole queue behind gets its stream closed and has to resume
+// its session.
+type sseWriter struct {
+	messages chan WSMessage
+	done     chan struct{}
+	overflow chan struct{} // Closed when the queue fills up
+	once     sync.Once
+}
+
+func newSSEWriter() *sseWriter {
+	return &sseWriter{
+		messages: make(chan WSMessage, writeQueueSize),
+		done:     make(chan struct{}),
+		overflow: make(chan struct{}),
+	}
+}
+
+func (w *sseWriter) WriteJSON(v interface{}) error {
+	msg, ok := v.(WSMessage)
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-w.done:
+		return errStreamClosed
+	case w.messages <- msg:
+		return nil
+	default:
+		w.once.Do(func() { close(w.overflow) })
+		return errStreamOverflow
+	}
+}
+
+// HandleSSE handles GET /sse. It creates a new session, or resumes the
+// one in session_id with its token as a bearer token. EventSource can't
+// send headers, so browsers may put it in a token query parameter
+// instead; the access log leaves query strings out.
+func (h *WebSocketHandler) HandleSSE(c *gin.Context) {
+	ip := c.ClientIP()
+	sessionID := c.Query("session_id")
+
+	if sessionID != "" {
+		token := requestToken(c)
+		if token == "" {
+			token = c.Query("token")
+		}
+		if _, ok := h.engine.AuthenticateSession(sessionID, token); !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session or token"})
+			return
+		}
+	} else {
+		sessionID = h.engine.CreateSession(ip).ID
+	}
+
+	writer := newSSEWriter()
+	defer close(writer.done)
+
+	client := NewClient(sessionID, writer)
+	h.attachSSE(client)
+	defer h.detachSSE(client)
+	detach := h.Attach(client)
+	defer detach()
+
+	log.Printf("🔗 New SSE connection from %s, session: %s", ip, sessionID)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	h.SendWelcome(client)
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case msg := <-writer.messages:
+			c.SSEvent(msg.Type, msg)
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			// Comment line keeps proxies from closing an idle stream
+			c.Writer.WriteString(": ping\n\n")
+			c.Writer.Flush()
+		case <-writer.overflow:
+			log.Printf("🐌 SSE client fell behind, closing stream for session: %s", sessionID)
+			return
+		case <-c.Request.Context().Done():
+			log.Printf("SSE stream closed for session: %s", sessionID)
+			return
+		}
+	}
+}
+
+// HandleSSEInput handles POST /sse/:session_id/input with a WSMessage body,
+// authenticated by the session token as a bearer token
+func (h *WebSocketHandler) HandleSSEInput(c *gin.Context) {
+	sessionID := c.Param("session_id")
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if _, ok := h.engine.AuthenticateSession(sessionID, token); !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session or token"})
+		return
+	}
+
+	var msg WSMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.mu.Lock()
+	client, exists := h.sseClients[sessionID]
+	h.mu.Unlock()
+	if !exists {
+		c.JSON(http.StatusConflict, gin.H{"error": "no event stream open for this session"})
+		return
+	}
+
+	h.dispatch(client, msg)
+	c.Status(http.StatusNoContent)
+}
+
+// attachSSE makes client the input target for its session, replacing
+// any older stream (e.g. after the browser reconnects)
+func (h *WebSocketHandler) attachSSE(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.sseClients[client.SessionID] = client
+}
+
+func (h *WebSocketHandler) detachSSE(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.sseClients[client.SessionID] == client {
+		delete(h.sseClients, client.SessionID)
+	}
+}
@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"strings"
+)
+
+// teammates returns the other team clients attached to the same session
+func (h *WebSocketHandler) teammates(client *Client) []*Client {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var others []*Client
+	if hub, exists := h.hubs[client.SessionID]; exists {
+		for player := range hub.players {
+			if player != client && player.Team != "" {
+				others = append(others, player)
+			}
+		}
+	}
+	return others
+}
+
+// shareWithTeam shows a command and its result to the rest of the team,
+// then restores each teammate's prompt and whatever they were typing
+func (h *WebSocketHandler) shareWithTeam(client *Client, command string, messages []WSMessage) {
+	header := WSMessage{
+		Type:    "output",
+		Stream:  "stdout",
+		User:    client.Member,
+		Content: "\r\n\x1b[35m" + h.promptFor(client) + "\x1b[0m" + command + "\r\n",
+	}
+
+	for _, teammate := range h.teammates(client) {
+		teammate.writer.WriteJSON(header)
+		for _, msg := range messages {
+			teammate.writer.WriteJSON(msg)
+		}
+		teammate.writer.WriteJSON(WSMessage{Type: "prompt", Content: h.promptFor(teammate)})
+		if pending := teammate.editor.pending(); pending != "" {
+			teammate.writer.WriteJSON(WSMessage{Type: "echo", Content: pending})
+		}
+	}
+}
+
+// announceToTeam tells every member, including the client, about a team event
+func (h *WebSocketHandler) announceToTeam(client *Client, text string) {
+	notice := WSMessage{
+		Type:    "team",
+		User:    client.Member,
+		Content: "\r\n\x1b[35m👥 " + text + "\x1b[0m\r\n",
+	}
+	client.writer.WriteJSON(notice)
+	for _, teammate := range h.teammates(client) {
+		teammate.writer.WriteJSON(notice)
+	}
+}
+
+// handleChat relays a team chat message to every member and spectators
+func (h *WebSocketHandler) handleChat(client *Client, text string) {
+	text = strings.TrimSpace(text)
+	if client.Team == "" || text == "" {
+		return
+	}
+
+	msg := WSMessage{
+		Type:    "chat",
+		User:    client.Member,
+		Content: text,
+	}
+	client.send(msg)
+	for _, teammate := range h.teammates(client) {
+		teammate.writer.WriteJSON(msg)
+	}
+}
+
+func (h *WebSocketHandler) leaveTeam(client *Client) {
+	h.engine.LeaveTeam(client.Team, client.Member)
+	h.announceToTeam(client, client.Member+" left the heist")
+}